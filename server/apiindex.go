@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// apiIndexEndpoint is one registered route in the SERVE_UI=false index. An
+// empty Methods list means the route accepts any method.
+type apiIndexEndpoint struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods,omitempty"`
+}
+
+type apiIndexResponse struct {
+	Service   string             `json:"service"`
+	Version   string             `json:"version"`
+	Endpoints []apiIndexEndpoint `json:"endpoints"`
+}
+
+// handleAPIIndex replaces the file server on / when the UI is disabled,
+// describing the registered endpoints so a separately hosted frontend (or a
+// person with curl) can discover them. Other unmatched paths are 404s.
+func handleAPIIndex(routes []route) http.HandlerFunc {
+	index := apiIndexResponse{Service: "home-pager", Version: version, Endpoints: []apiIndexEndpoint{}}
+	for _, rt := range routes {
+		if rt.pattern == "/" || rt.pattern == "/api/" {
+			continue
+		}
+		index.Endpoints = append(index.Endpoints, apiIndexEndpoint{Path: rt.pattern, Methods: rt.methods})
+	}
+	sort.Slice(index.Endpoints, func(i, j int) bool {
+		return index.Endpoints[i].Path < index.Endpoints[j].Path
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			handleNotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		_ = json.NewEncoder(w).Encode(index)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleAPIIndex(t *testing.T) {
+	handler := handleAPIIndex([]route{
+		{pattern: "/api/ingresses", methods: methodsGet},
+		{pattern: "/api/", handler: http.HandlerFunc(handleNotFound)},
+		{pattern: "/healthz", methods: methodsRead},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var body apiIndexResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Endpoints) != 2 || body.Endpoints[0].Path != "/api/ingresses" || body.Endpoints[1].Path != "/healthz" {
+		t.Fatalf("unexpected endpoints %#v", body.Endpoints)
+	}
+	if body.Service != "home-pager" || body.Version != version {
+		t.Fatalf("unexpected service info %#v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for other paths, got %d", rec.Code)
+	}
+}
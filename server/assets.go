@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	assetManifestFile = "/asset-manifest.json"
+
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	indexCacheControl     = "no-cache"
+
+	// maxAssetManifestBytes bounds how much of the manifest is parsed.
+	maxAssetManifestBytes = 4 << 20
+)
+
+// fingerprintPattern matches file names carrying a content hash, such as
+// main.3f9a1c2b.js or index-B4x7kQ9z.css.
+var fingerprintPattern = regexp.MustCompile(`[.-]([0-9A-Za-z_]{8,})\.[0-9A-Za-z]+$`)
+
+// immutableAssets holds the set of fingerprinted paths listed in the asset
+// manifest, or nil when the build has no manifest.
+var immutableAssets atomic.Pointer[map[string]bool]
+
+// loadAssetManifest reads asset-manifest.json from the static roots. A
+// missing manifest is not an error and disables manifest caching.
+func loadAssetManifest(root http.FileSystem) error {
+	f, err := root.Open(assetManifestFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		immutableAssets.Store(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var manifest interface{}
+	if err := json.NewDecoder(io.LimitReader(f, maxAssetManifestBytes)).Decode(&manifest); err != nil {
+		return err
+	}
+	assets := make(map[string]bool)
+	collectManifestAssets(manifest, assets)
+	immutableAssets.Store(&assets)
+	return nil
+}
+
+// reloadAssetManifest re-reads the asset manifest from root on SIGHUP.
+func reloadAssetManifest(root http.FileSystem) error {
+	err := loadAssetManifest(root)
+	recordConfigReload(err)
+	return err
+}
+
+// collectManifestAssets walks any manifest layout (Create React App's
+// "files" map, webpack-manifest-plugin's flat map, Vite's per-entry "file"
+// and "css" lists) and records every fingerprinted local path it lists.
+func collectManifestAssets(value interface{}, assets map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			collectManifestAssets(child, assets)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectManifestAssets(child, assets)
+		}
+	case string:
+		if strings.Contains(v, "://") || strings.HasPrefix(v, "//") {
+			return
+		}
+		name := path.Clean("/" + strings.TrimPrefix(v, "./"))
+		if isFingerprinted(name) {
+			assets[name] = true
+		}
+	}
+}
+
+func isFingerprinted(name string) bool {
+	match := fingerprintPattern.FindStringSubmatch(path.Base(name))
+	return match != nil && strings.ContainsAny(match[1], "0123456789")
+}
+
+// withAssetCaching marks fingerprinted files from the asset manifest as
+// immutable and keeps index.html, which references them, revalidated on
+// every load. Without a manifest, responses are left alone.
+func withAssetCaching(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assets := immutableAssets.Load()
+		if assets == nil || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := path.Clean("/" + r.URL.Path)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/") || name == staticIndexFile:
+			w.Header().Set("Cache-Control", indexCacheControl)
+		case (*assets)[name]:
+			w.Header().Set("Cache-Control", immutableCacheControl)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
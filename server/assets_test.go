@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWithAssetCaching(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "index.html", "<html></html>")
+	writeTestFile(t, dir, "static/js/main.3f9a1c2b.js", "js")
+	writeTestFile(t, dir, "assets/index-B4x7kQ9z.css", "css")
+	writeTestFile(t, dir, "favicon.svg", "<svg/>")
+	writeTestFile(t, dir, "asset-manifest.json", `{
+  "files": {"main.js": "/static/js/main.3f9a1c2b.js", "favicon.svg": "/favicon.svg", "cdn": "https://cdn.example.com/lib.1234abcd.js"},
+  "src/main.ts": {"file": "assets/main.js", "css": ["./assets/index-B4x7kQ9z.css"]}
+}`)
+	root := newStaticFS([]string{dir})
+	defer immutableAssets.Store(nil)
+
+	handler := withAssetCaching(http.FileServer(root))
+	cacheControl := func(target string) string {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr.Header().Get("Cache-Control")
+	}
+
+	if got := cacheControl("/static/js/main.3f9a1c2b.js"); got != "" {
+		t.Fatalf("expected no caching headers before the manifest is loaded, got %q", got)
+	}
+
+	if err := loadAssetManifest(root); err != nil {
+		t.Fatal(err)
+	}
+	for target, want := range map[string]string{
+		"/static/js/main.3f9a1c2b.js": immutableCacheControl,
+		"/assets/index-B4x7kQ9z.css":  immutableCacheControl,
+		"/favicon.svg":                "",
+		"/":                           indexCacheControl,
+		"/index.html":                 indexCacheControl,
+	} {
+		if got := cacheControl(target); got != want {
+			t.Fatalf("%s: expected Cache-Control %q, got %q", target, want, got)
+		}
+	}
+
+	if err := loadAssetManifest(newStaticFS([]string{t.TempDir()})); err != nil || immutableAssets.Load() != nil {
+		t.Fatalf("expected a missing manifest to disable manifest caching, got %v", err)
+	}
+}
+
+func TestReloadAssetManifestRecordsFailures(t *testing.T) {
+	resetMetrics()
+	defer resetMetrics()
+	defer immutableAssets.Store(nil)
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "asset-manifest.json", "{not json")
+	if err := reloadAssetManifest(newStaticFS([]string{dir})); err == nil {
+		t.Fatal("expected an invalid manifest to fail the reload")
+	}
+	if _, lastErr := configReloadStatus(); atomic.LoadUint64(&configReloadFailures) != 1 || lastErr == "" {
+		t.Fatalf("expected the failed reload to be recorded, got %d failures (%q)", atomic.LoadUint64(&configReloadFailures), lastErr)
+	}
+}
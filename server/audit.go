@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	auditQueueSize      = 256
+	auditWebhookTimeout = 5 * time.Second
+	auditRedacted       = "REDACTED"
+)
+
+// auditSensitiveParams are substrings of query parameter names whose values
+// are never written to the audit trail.
+var auditSensitiveParams = []string{"token", "secret", "password", "passwd", "key", "auth", "signature", "credential", "session", "code"}
+
+// auditEvent is one API access. It deliberately carries no headers or
+// bodies, which is where credentials travel.
+type auditEvent struct {
+	Time       time.Time           `json:"time"`
+	Identity   string              `json:"identity,omitempty"`
+	ClientCert string              `json:"clientCert,omitempty"`
+	RemoteAddr string              `json:"remoteAddr"`
+	Method     string              `json:"method"`
+	Path       string              `json:"path"`
+	Query      map[string][]string `json:"query,omitempty"`
+	Status     int                 `json:"status"`
+	DurationMS int64               `json:"durationMs"`
+}
+
+// auditor writes audit events to the log and, when a webhook is configured,
+// queues them for delivery by a single sender so a slow receiver cannot
+// block requests. Events that do not fit in the queue are dropped and
+// counted.
+type auditor struct {
+	logEvents bool
+	webhook   string
+	client    *http.Client
+	queue     chan auditEvent
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// audit is nil unless AUDIT_LOG or AUDIT_WEBHOOK is set.
+var audit *auditor
+
+func loadAuditor() *auditor {
+	logEvents := getEnvBool("AUDIT_LOG", false)
+	webhook := strings.TrimSpace(os.Getenv("AUDIT_WEBHOOK"))
+	if webhook != "" && !isValidLinkURL(webhook) {
+		log.Printf("Warning: ignoring AUDIT_WEBHOOK %q: must be an absolute http(s) URL", webhook)
+		webhook = ""
+	}
+	if !logEvents && webhook == "" {
+		return nil
+	}
+	if authProxyHeader == "" {
+		log.Printf("Warning: auditing is enabled without AUTH_PROXY_HEADER; events will have no identity")
+	}
+
+	a := &auditor{logEvents: logEvents, webhook: webhook}
+	if webhook != "" {
+		a.client = &http.Client{Timeout: auditWebhookTimeout}
+		a.queue = make(chan auditEvent, auditQueueSize)
+	}
+	return a
+}
+
+// withAudit records every /api/ request once it has been served. It must run
+// inside withProxyIdentity to see the caller's identity.
+func withAudit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if audit == nil || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		cw := &countingResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r)
+		audit.record(auditEvent{
+			Time:       start.UTC(),
+			Identity:   requestIdentity(r.Context()),
+			ClientCert: clientCertCN(r.Context()),
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      redactQuery(r.URL.Query()),
+			Status:     cw.statusCode(),
+			DurationMS: time.Since(start).Milliseconds(),
+		})
+	})
+}
+
+// redactQuery copies query, replacing the values of sensitive parameters.
+func redactQuery(query url.Values) map[string][]string {
+	if len(query) == 0 {
+		return nil
+	}
+	redacted := make(map[string][]string, len(query))
+	for name, values := range query {
+		if isSensitiveParam(name) {
+			redacted[name] = []string{auditRedacted}
+			continue
+		}
+		redacted[name] = values
+	}
+	return redacted
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range auditSensitiveParams {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *auditor) record(event auditEvent) {
+	if a.logEvents {
+		if line, err := json.Marshal(event); err == nil {
+			log.Printf("audit %s", line)
+		}
+	}
+	if a.queue == nil {
+		return
+	}
+	select {
+	case a.queue <- event:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+// run delivers queued events to the webhook until ctx is cancelled. Failed
+// deliveries are counted and logged, not retried.
+func (a *auditor) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-a.queue:
+			if err := a.send(ctx, event); err != nil && ctx.Err() == nil {
+				a.failed.Add(1)
+				log.Printf("Error sending audit event: %v", err)
+			}
+		}
+	}
+}
+
+func (a *auditor) send(ctx context.Context, event auditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", kubeUserAgent)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("audit webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (a *auditor) write(w io.Writer) {
+	_, _ = io.WriteString(w, "# HELP home_pager_audit_events_dropped_total Audit events dropped because the webhook queue was full.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_audit_events_dropped_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_audit_events_dropped_total "+strconv.FormatUint(a.dropped.Load(), 10)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_audit_webhook_failures_total Audit events the webhook did not accept.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_audit_webhook_failures_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_audit_webhook_failures_total "+strconv.FormatUint(a.failed.Load(), 10)+"\n")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithAuditSendsRedactedEventsToWebhook(t *testing.T) {
+	received := make(chan auditEvent, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event auditEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("invalid audit event: %v", err)
+		}
+		received <- event
+	}))
+	defer webhook.Close()
+
+	t.Setenv("AUDIT_WEBHOOK", webhook.URL)
+	prev := audit
+	audit = loadAuditor()
+	defer func() { audit = prev }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go audit.run(ctx)
+
+	handler := withAudit(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ingresses?format=summary&access_token=s3cret&apiKey=k", nil)
+	req = req.WithContext(context.WithValue(req.Context(), identityContextKey{}, "alice"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/index.html", nil))
+
+	select {
+	case event := <-received:
+		if event.Identity != "alice" || event.Path != "/api/ingresses" || event.Method != http.MethodGet || event.Status != http.StatusTeapot {
+			t.Fatalf("unexpected audit event %+v", event)
+		}
+		if got := event.Query["format"]; len(got) != 1 || got[0] != "summary" {
+			t.Fatalf("expected format to be kept, got %v", event.Query)
+		}
+		for _, name := range []string{"access_token", "apiKey"} {
+			if got := event.Query[name]; len(got) != 1 || got[0] != auditRedacted {
+				t.Fatalf("expected %s to be redacted, got %v", name, event.Query)
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected an audit event")
+	}
+
+	select {
+	case event := <-received:
+		t.Fatalf("expected only API requests to be audited, got %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestLoadAuditorIsOptIn(t *testing.T) {
+	t.Setenv("AUDIT_LOG", "")
+	t.Setenv("AUDIT_WEBHOOK", "")
+	if a := loadAuditor(); a != nil {
+		t.Fatalf("expected auditing to be off by default, got %+v", a)
+	}
+
+	t.Setenv("AUDIT_WEBHOOK", "not a url")
+	if a := loadAuditor(); a != nil {
+		t.Fatalf("expected an invalid webhook to be ignored, got %+v", a)
+	}
+
+	t.Setenv("AUDIT_LOG", "true")
+	if a := loadAuditor(); a == nil || !a.logEvents || a.queue != nil {
+		t.Fatalf("expected log-only auditing, got %+v", a)
+	}
+}
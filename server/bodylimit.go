@@ -0,0 +1,49 @@
+package main
+
+import "net/http"
+
+const (
+	defaultMaxRequestBody = 1 << 20
+	defaultMaxHeaderBytes = 1 << 20
+)
+
+// withMaxRequestBody rejects requests whose declared body exceeds limit and
+// caps the readable body of the rest, so handlers reading past the limit get
+// an *http.MaxBytesError.
+func withMaxRequestBody(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			localizedError(w, r, msgRequestTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestHeaderBytes approximates the wire size of the request line and
+// headers the same way net/http counts them against MaxHeaderBytes.
+func requestHeaderBytes(r *http.Request) int64 {
+	size := int64(len(r.Method) + len(r.RequestURI) + len(r.Proto) + 4)
+	size += int64(len(r.Host) + len("Host: \r\n"))
+	for name, values := range r.Header {
+		for _, value := range values {
+			size += int64(len(name) + len(value) + 4)
+		}
+	}
+	return size
+}
+
+// withMaxHeaderBytes rejects requests whose headers exceed limit with a 431.
+// It tightens the server-wide MaxHeaderBytes for individual routes.
+func withMaxHeaderBytes(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestHeaderBytes(r) > limit {
+			localizedError(w, r, msgHeaderTooLarge, http.StatusRequestHeaderFieldsTooLarge)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithMaxRequestBody(t *testing.T) {
+	handler := withMaxRequestBody(8, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("small")))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for small body, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("much too large")))
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for declared oversized body, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("much too large")))
+	req.ContentLength = -1
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for streamed oversized body, got %d", rr.Code)
+	}
+}
+
+func TestWithMaxHeaderBytes(t *testing.T) {
+	handler := withMaxHeaderBytes(256, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for small headers, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ingresses", nil)
+	req.Header.Set("Cookie", strings.Repeat("a", 512))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("expected 431 for oversized headers, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	bookmarksFilename = "home-pager-bookmarks.html"
+
+	// bookmarkCategoryTag groups tiles in the export; tiles without it are
+	// grouped by namespace.
+	bookmarkCategoryTag   = "category"
+	bookmarkOtherCategory = "Other"
+)
+
+// writeBookmarks renders summaries as a Netscape bookmark file for
+// /api/ingresses?format=bookmarks, which every major browser can import. The
+// tiles go in a home-pager folder with one subfolder per category.
+func writeBookmarks(w io.Writer, response summaryResponse) error {
+	groups := make(map[string][]ingressSummary)
+	for _, summary := range response.Ingresses {
+		if !isValidLinkURL(summary.URL) {
+			continue
+		}
+		category := strings.TrimSpace(summary.Tags[bookmarkCategoryTag])
+		if category == "" {
+			category = summary.Namespace
+		}
+		if category == "" {
+			category = bookmarkOtherCategory
+		}
+		groups[category] = append(groups[category], summary)
+	}
+	categories := make([]string, 0, len(groups))
+	for category := range groups {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE NETSCAPE-Bookmark-file-1>\n")
+	b.WriteString("<META HTTP-EQUIV=\"Content-Type\" CONTENT=\"text/html; charset=UTF-8\">\n")
+	b.WriteString("<TITLE>Bookmarks</TITLE>\n<H1>Bookmarks</H1>\n<DL><p>\n")
+	b.WriteString("    <DT><H3>home-pager</H3>\n    <DL><p>\n")
+	for _, category := range categories {
+		b.WriteString("        <DT><H3>" + html.EscapeString(category) + "</H3>\n        <DL><p>\n")
+		for _, summary := range groups[category] {
+			b.WriteString("            <DT><A HREF=\"" + html.EscapeString(summary.URL) + "\">" + html.EscapeString(summary.Title) + "</A>\n")
+			if summary.Description != "" {
+				b.WriteString("            <DD>" + html.EscapeString(summary.Description) + "\n")
+			}
+		}
+		b.WriteString("        </DL><p>\n")
+	}
+	b.WriteString("    </DL><p>\n</DL><p>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteBookmarks(t *testing.T) {
+	var b strings.Builder
+	err := writeBookmarks(&b, summaryResponse{Ingresses: []ingressSummary{
+		{Namespace: "media", Title: "Jellyfin", URL: "https://jellyfin.example.com", Description: "Films & TV"},
+		{Namespace: "infra", Title: "Grafana <ops>", URL: "https://grafana.example.com", Tags: map[string]string{"category": "Monitoring"}},
+		{Namespace: "media", Title: "No link"},
+		{Title: "Router", URL: "http://192.168.1.1"},
+		{Namespace: "media", Title: "Bad", URL: "javascript:alert(1)"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := b.String()
+	if !strings.HasPrefix(out, "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n") {
+		t.Fatalf("expected a Netscape bookmark header, got %q", out)
+	}
+	for _, want := range []string{
+		`<DT><H3>Monitoring</H3>`,
+		`<DT><A HREF="https://grafana.example.com">Grafana &lt;ops&gt;</A>`,
+		`<DD>Films &amp; TV`,
+		`<DT><H3>Other</H3>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "No link") || strings.Contains(out, "javascript:") {
+		t.Errorf("expected tiles without a valid link to be skipped:\n%s", out)
+	}
+	if strings.Index(out, ">media<") < strings.Index(out, ">Monitoring<") {
+		t.Errorf("expected categories in sorted order:\n%s", out)
+	}
+}
+
+func TestHandleIngressesBookmarksFormat(t *testing.T) {
+	ingressesCache.reset()
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{testIngress("default", "app", "app.example.com")},
+		})
+	}))
+
+	rr := httptest.NewRecorder()
+	handleIngresses(time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses?format=bookmarks", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Fatalf("expected text/html, got %q", got)
+	}
+	if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename="home-pager-bookmarks.html"` {
+		t.Fatalf("unexpected Content-Disposition %q", got)
+	}
+	if !strings.Contains(rr.Body.String(), `<A HREF="http://app.example.com">app</A>`) {
+		t.Fatalf("expected the tile in the export, got %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// ingressCache holds the most recent list fetched from the Kubernetes API so
+// that requests within the TTL do not hit the apiserver. It lists ingresses
+// unless fetcher is set. For staleWhileRevalidate past the TTL, the expired
+// list is still served while a background refresh replaces it.
+//
+// Entries are keyed by cacheKey. Without impersonation every request shares
+// the "" entry; with it, each identity has its own entry so one user's list
+// is never served to another.
+type ingressCache struct {
+	mu                   sync.Mutex
+	ttl                  time.Duration
+	staleWhileRevalidate time.Duration
+	fetcher              func(context.Context) (map[string]interface{}, error)
+	entries              map[string]cacheEntry
+	revalidating         map[string]bool
+	flight               flightGroup
+}
+
+type cacheEntry struct {
+	result    map[string]interface{}
+	fetchedAt time.Time
+}
+
+// cacheFlightKey identifies the upstream list query; concurrent misses for
+// the same cache key share one upstream request.
+const cacheFlightKey = "list"
+
+// staleHeader marks responses built from an expired cache entry.
+const staleHeader = "X-Cache-Stale"
+
+var ingressesCache = &ingressCache{}
+
+// cacheKey returns the cache entry a request may use. The apiserver filters
+// impersonated lists by the identity's permissions, so with impersonation the
+// identity is part of the key.
+func cacheKey(ctx context.Context) string {
+	if !impersonateUsers {
+		return ""
+	}
+	return requestIdentity(ctx)
+}
+
+func (c *ingressCache) lookup(key string, now time.Time) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.Sub(entry.fetchedAt) >= c.ttl {
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// lookupStale returns an expired entry that is still within the
+// stale-while-revalidate window.
+func (c *ingressCache) lookupStale(key string, now time.Time) (map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.Sub(entry.fetchedAt) >= c.ttl+c.staleWhileRevalidate {
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// store saves result under key and drops other entries that are too old to
+// be served, so per-identity entries do not accumulate.
+func (c *ingressCache) store(key string, result map[string]interface{}, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+	for existing, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl+c.staleWhileRevalidate {
+			delete(c.entries, existing)
+		}
+	}
+	c.entries[key] = cacheEntry{result: result, fetchedAt: now}
+}
+
+func (c *ingressCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+}
+
+// fetch returns the cached ingress list when it is still fresh and otherwise
+// refreshes it from the Kubernetes API. Caching is disabled when the TTL is
+// not positive, but concurrent fetches are still coalesced. Callers must
+// treat the returned map as read-only.
+func (c *ingressCache) fetch(ctx context.Context) (map[string]interface{}, error) {
+	result, _, err := c.fetchWithStaleness(ctx)
+	return result, err
+}
+
+// fetchWithStaleness is fetch, additionally reporting whether the result is
+// an expired entry served while a background refresh runs.
+func (c *ingressCache) fetchWithStaleness(ctx context.Context) (map[string]interface{}, bool, error) {
+	key := cacheKey(ctx)
+	if c.ttl <= 0 {
+		result, err := c.flight.do(ctx, cacheFlightKey+"/"+key, c.fetchUpstream)
+		return result, false, err
+	}
+
+	now := time.Now()
+	if result, ok := c.lookup(key, now); ok {
+		return result, false, nil
+	}
+	if result, ok := c.lookupStale(key, now); ok {
+		c.revalidate(ctx, key)
+		return result, true, nil
+	}
+
+	result, err := c.refresh(ctx)
+	return result, false, err
+}
+
+// revalidate starts a background refresh of key unless one is already
+// running. The refresh outlives the request that triggered it; flightTimeout
+// still bounds it.
+func (c *ingressCache) revalidate(ctx context.Context, key string) {
+	c.mu.Lock()
+	if c.revalidating[key] {
+		c.mu.Unlock()
+		return
+	}
+	if c.revalidating == nil {
+		c.revalidating = make(map[string]bool)
+	}
+	c.revalidating[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.revalidating, key)
+			c.mu.Unlock()
+		}()
+		if _, err := c.backgroundRefresh(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Error revalidating stale ingress cache: %v", err)
+		}
+	}()
+}
+
+func (c *ingressCache) isRevalidating(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.revalidating[key]
+}
+
+func (c *ingressCache) fetchUpstream(ctx context.Context) (map[string]interface{}, error) {
+	if c.fetcher != nil {
+		return c.fetcher(ctx)
+	}
+	return fetchIngresses(ctx)
+}
+
+// refresh fetches the list from upstream and stores it under the request's
+// cache key. Concurrent refreshes for the same key share a single upstream
+// request. Partial lists, where some WATCH_NAMESPACES namespaces failed, are
+// returned but not cached, so the next request retries the failed ones.
+func (c *ingressCache) refresh(ctx context.Context) (map[string]interface{}, error) {
+	key := cacheKey(ctx)
+	return c.flight.do(ctx, cacheFlightKey+"/"+key, func(ctx context.Context) (map[string]interface{}, error) {
+		result, err := c.fetchUpstream(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(resultWarnings(result)) == 0 {
+			c.store(key, result, time.Now())
+		}
+		return result, nil
+	})
+}
+
+// backgroundRefresh is refresh through the background worker pool, for
+// refreshes no request is waiting on. Request-driven refreshes skip the pool
+// so they never queue behind background work.
+func (c *ingressCache) backgroundRefresh(ctx context.Context) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	var err error
+	if poolErr := backgroundPool.do(ctx, func() { result, err = c.refresh(ctx) }); poolErr != nil {
+		return nil, poolErr
+	}
+	return result, err
+}
+
+// prewarmDelay returns how long to wait before proactively refreshing the
+// cache: shortly before the TTL expires, with up to 10% jitter so replicas do
+// not refresh in lockstep.
+func prewarmDelay(ttl time.Duration) time.Duration {
+	lead := ttl / 10
+	jitter := time.Duration(rand.Int64N(int64(ttl/10) + 1))
+	return ttl - lead - jitter
+}
+
+// prewarm refreshes the cache in the background until ctx is cancelled, so
+// that requests rarely observe an expired entry.
+func (c *ingressCache) prewarm(ctx context.Context, timeout time.Duration) {
+	if c.ttl <= 0 {
+		log.Printf("Warning: CACHE_PREWARM requires a positive CACHE_TTL; prewarming disabled")
+		return
+	}
+
+	h := registerHeartbeat("cachePrewarm", c.ttl+timeout)
+	defer h.stop()
+
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+		if _, err := c.backgroundRefresh(fetchCtx); err != nil && ctx.Err() == nil {
+			log.Printf("Error prewarming ingress cache: %v", err)
+		}
+		cancel()
+		h.beat()
+
+		timer := time.NewTimer(prewarmDelay(c.ttl))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
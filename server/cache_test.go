@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIngressCacheServesFreshEntries(t *testing.T) {
+	c := &ingressCache{ttl: time.Minute}
+	cached := map[string]interface{}{"items": []interface{}{"cached"}}
+	c.store("", cached, time.Now())
+
+	got, err := c.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items := got["items"].([]interface{}); len(items) != 1 {
+		t.Fatalf("expected cached result, got %v", got)
+	}
+
+	c.store("", cached, time.Now().Add(-2*time.Minute))
+	if _, ok := c.lookup("", time.Now()); ok {
+		t.Fatal("expected expired entry to miss")
+	}
+}
+
+func TestIngressCacheDisabled(t *testing.T) {
+	kubernetesServiceHost = ""
+	kubernetesServicePort = ""
+
+	c := &ingressCache{}
+	c.store("", map[string]interface{}{"items": []interface{}{"stale"}}, time.Now())
+
+	got, err := c.fetch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items := got["items"].([]interface{}); len(items) != 0 {
+		t.Fatalf("expected cache bypass with zero TTL, got %v", got)
+	}
+}
+
+func TestPrewarmDelay(t *testing.T) {
+	ttl := 10 * time.Second
+	for i := 0; i < 100; i++ {
+		delay := prewarmDelay(ttl)
+		if delay < 8*time.Second || delay > 9*time.Second {
+			t.Fatalf("expected delay between 8s and 9s, got %v", delay)
+		}
+	}
+}
+
+func TestIngressCachePrewarm(t *testing.T) {
+	kubernetesServiceHost = ""
+	kubernetesServicePort = ""
+
+	c := &ingressCache{ttl: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.prewarm(ctx, time.Second)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := c.lookup("", time.Now()); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected prewarm to populate the cache")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected prewarm to stop after cancellation")
+	}
+}
+
+func TestIngressCacheCoalescesConcurrentMisses(t *testing.T) {
+	for _, ttl := range []time.Duration{0, time.Minute} {
+		var calls int32
+		started := make(chan struct{})
+		release := make(chan struct{})
+		c := &ingressCache{
+			ttl: ttl,
+			fetcher: func(ctx context.Context) (map[string]interface{}, error) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					close(started)
+				}
+				<-release
+				return map[string]interface{}{"items": []interface{}{"shared"}}, nil
+			},
+		}
+
+		const n = 20
+		results := make([]map[string]interface{}, n)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[0], _ = c.fetch(context.Background())
+		}()
+		<-started
+		for i := 1; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _ = c.fetch(context.Background())
+			}(i)
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if got := atomic.LoadInt32(&calls); got != 1 {
+			t.Fatalf("ttl=%v: expected exactly one upstream fetch, got %d", ttl, got)
+		}
+		for i, result := range results {
+			if result == nil || len(result["items"].([]interface{})) != 1 {
+				t.Fatalf("ttl=%v: caller %d did not receive the shared result: %v", ttl, i, result)
+			}
+		}
+	}
+}
+
+func TestFlightGroupWaiterCancellation(t *testing.T) {
+	var g flightGroup
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := g.do(ctx, "k", func(context.Context) (map[string]interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected cancelled waiter to return context.Canceled, got %v", err)
+	}
+}
+
+func TestFlightGroupBoundsSharedCall(t *testing.T) {
+	prevTimeout := flightTimeout
+	flightTimeout = 50 * time.Millisecond
+	defer func() { flightTimeout = prevTimeout }()
+
+	var g flightGroup
+	_, err := g.do(context.Background(), "k", func(ctx context.Context) (map[string]interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the shared call to hit its own deadline, got %v", err)
+	}
+}
+
+func TestIngressCacheServesStaleWhileRevalidating(t *testing.T) {
+	refreshed := make(chan struct{})
+	c := &ingressCache{
+		ttl:                  time.Minute,
+		staleWhileRevalidate: time.Minute,
+		fetcher: func(ctx context.Context) (map[string]interface{}, error) {
+			defer close(refreshed)
+			return map[string]interface{}{"items": []interface{}{"fresh"}}, nil
+		},
+	}
+	c.store("", map[string]interface{}{"items": []interface{}{"stale"}}, time.Now().Add(-90*time.Second))
+
+	got, stale, err := c.fetchWithStaleness(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !stale || got["items"].([]interface{})[0] != "stale" {
+		t.Fatalf("expected the stale entry, got stale=%v %v", stale, got)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("expected a background refresh")
+	}
+	deadline := time.Now().Add(time.Second)
+	for c.isRevalidating("") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the background refresh to finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	got, stale, err = c.fetchWithStaleness(context.Background())
+	if err != nil || stale || got["items"].([]interface{})[0] != "fresh" {
+		t.Fatalf("expected the refreshed entry, got stale=%v err=%v %v", stale, err, got)
+	}
+
+	c.store("", got, time.Now().Add(-3*time.Minute))
+	if _, ok := c.lookupStale("", time.Now()); ok {
+		t.Fatal("expected an entry past the stale window to miss")
+	}
+}
+
+func TestIngressCacheSeparatesImpersonatedIdentities(t *testing.T) {
+	impersonateUsers = true
+	defer func() { impersonateUsers = false }()
+
+	var calls int32
+	c := &ingressCache{
+		ttl: time.Minute,
+		fetcher: func(ctx context.Context) (map[string]interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return map[string]interface{}{"items": []interface{}{requestIdentity(ctx)}}, nil
+		},
+	}
+	as := func(identity string) context.Context {
+		return context.WithValue(context.Background(), identityContextKey{}, identity)
+	}
+
+	for _, identity := range []string{"alice", "bob", "alice", "bob"} {
+		got, err := c.fetch(as(identity))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if owner := got["items"].([]interface{})[0]; owner != identity {
+			t.Fatalf("%s was served %s's cached list", identity, owner)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expected one upstream fetch per identity, got %d", got)
+	}
+
+	impersonateUsers = false
+	_, _ = c.fetch(as("alice"))
+	_, _ = c.fetch(as("bob"))
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("expected identities to share one entry without impersonation, got %d fetches", got)
+	}
+}
+
+func TestIngressCacheRequestFetchesSkipBusyPool(t *testing.T) {
+	prevPool := backgroundPool
+	backgroundPool = newWorkerPool(1)
+	defer func() { backgroundPool = prevPool }()
+	release := make(chan struct{})
+	go func() {
+		_ = backgroundPool.do(context.Background(), func() { <-release })
+	}()
+	defer close(release)
+	for backgroundPool.busy.Load() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	c := &ingressCache{ttl: time.Minute, fetcher: func(context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"items": []interface{}{}}, nil
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.fetch(ctx); err != nil {
+		t.Fatalf("expected a request fetch not to wait for the busy pool, got %v", err)
+	}
+	if _, err := c.backgroundRefresh(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a background refresh to wait for the pool, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chaosConfig injects artificial latency and failures into fetchIngresses so
+// the UI's loading and error states can be exercised. It is inert unless
+// ENABLE_CHAOS=true.
+type chaosConfig struct {
+	enabled   bool
+	latency   time.Duration
+	errorRate float64
+}
+
+var chaos chaosConfig
+
+var errChaosInjected = errors.New("chaos: injected failure")
+
+func loadChaosConfig() chaosConfig {
+	if !getEnvBool("ENABLE_CHAOS", false) {
+		return chaosConfig{}
+	}
+
+	cfg := chaosConfig{
+		enabled: true,
+		latency: getEnvDuration("CHAOS_LATENCY", 0),
+	}
+	if raw := strings.TrimSpace(os.Getenv("CHAOS_ERROR_RATE")); raw != "" {
+		rate, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			log.Printf("Warning: invalid CHAOS_ERROR_RATE %q; expected a value between 0 and 1", raw)
+		} else {
+			cfg.errorRate = rate
+		}
+	}
+
+	log.Printf("WARNING: chaos mode enabled (latency=%s, error rate=%.2f); do not use in production", cfg.latency, cfg.errorRate)
+	return cfg
+}
+
+// inject delays for the configured latency and then fails with the configured
+// probability.
+func (c chaosConfig) inject(ctx context.Context) error {
+	if !c.enabled {
+		return nil
+	}
+
+	if c.latency > 0 {
+		timer := time.NewTimer(c.latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if c.errorRate > 0 && rand.Float64() < c.errorRate {
+		return errChaosInjected
+	}
+	return nil
+}
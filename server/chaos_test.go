@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoadChaosConfig(t *testing.T) {
+	t.Setenv("ENABLE_CHAOS", "")
+	t.Setenv("CHAOS_LATENCY", "2s")
+	t.Setenv("CHAOS_ERROR_RATE", "0.5")
+	if cfg := loadChaosConfig(); cfg.enabled || cfg.latency != 0 || cfg.errorRate != 0 {
+		t.Fatalf("expected chaos to stay off without ENABLE_CHAOS, got %+v", cfg)
+	}
+
+	t.Setenv("ENABLE_CHAOS", "true")
+	if cfg := loadChaosConfig(); !cfg.enabled || cfg.latency != 2*time.Second || cfg.errorRate != 0.5 {
+		t.Fatalf("unexpected chaos config: %+v", cfg)
+	}
+
+	t.Setenv("CHAOS_ERROR_RATE", "1.5")
+	if cfg := loadChaosConfig(); cfg.errorRate != 0 {
+		t.Fatalf("expected out-of-range error rate to be ignored, got %v", cfg.errorRate)
+	}
+}
+
+func TestFetchIngressesChaos(t *testing.T) {
+	kubernetesServiceHost = ""
+	kubernetesServicePort = ""
+	defer func() { chaos = chaosConfig{} }()
+
+	chaos = chaosConfig{enabled: true, errorRate: 1}
+	if _, err := fetchIngresses(context.Background()); !errors.Is(err, errChaosInjected) {
+		t.Fatalf("expected injected failure, got %v", err)
+	}
+
+	chaos = chaosConfig{enabled: true, latency: 50 * time.Millisecond}
+	start := time.Now()
+	if _, err := fetchIngresses(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected injected latency, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	chaos = chaosConfig{enabled: true, latency: time.Second}
+	if _, err := fetchIngresses(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected latency to respect context deadline, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"compress/gzip"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultGzipLevel = 5
+
+// defaultCompressionExclusions lists already-compressed formats that gzip
+// cannot shrink: media types (with type/* wildcards) and file extensions.
+const defaultCompressionExclusions = "image/png,image/jpeg,image/gif,image/webp,image/avif,font/woff,font/woff2,application/font-woff,video/*,audio/*,application/zip,application/gzip,application/x-gzip,application/zstd,application/x-7z-compressed,application/x-bzip2," +
+	".png,.jpg,.jpeg,.gif,.webp,.avif,.woff,.woff2,.zip,.gz,.br,.zst,.mp4,.webm,.mp3"
+
+// compressionExclusions are responses withCompression passes through as is.
+var compressionExclusions = parseCompressionExclusions(defaultCompressionExclusions)
+
+type compressionExclusionList struct {
+	mediaTypes []string
+	extensions map[string]bool
+}
+
+// parseCompressionExclusions splits a comma-separated list of media types
+// (e.g. image/png or video/*) and extensions (e.g. .woff2).
+func parseCompressionExclusions(raw string) compressionExclusionList {
+	list := compressionExclusionList{extensions: make(map[string]bool)}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case part == "":
+		case strings.HasPrefix(part, "."):
+			list.extensions[part] = true
+		case strings.Contains(part, "/"):
+			list.mediaTypes = append(list.mediaTypes, part)
+		default:
+			log.Printf("Warning: ignoring compression exclusion %q; expected a media type or .extension", part)
+		}
+	}
+	return list
+}
+
+// loadCompressionExclusions reads COMPRESSION_EXCLUDE, keeping the defaults
+// when it is unset; an empty value compresses everything.
+func loadCompressionExclusions() compressionExclusionList {
+	raw, ok := os.LookupEnv("COMPRESSION_EXCLUDE")
+	if !ok {
+		raw = defaultCompressionExclusions
+	}
+	return parseCompressionExclusions(raw)
+}
+
+func (l compressionExclusionList) excludes(contentType, requestPath string) bool {
+	if l.extensions[strings.ToLower(path.Ext(requestPath))] {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" {
+		return false
+	}
+	for _, excluded := range l.mediaTypes {
+		if prefix, ok := strings.CutSuffix(excluded, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipLevel parses GZIP_LEVEL, falling back to the default for values outside
+// 1–9.
+func gzipLevel(raw string) int {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return defaultGzipLevel
+	}
+	level, err := strconv.Atoi(raw)
+	if err != nil || level < gzip.BestSpeed || level > gzip.BestCompression {
+		log.Printf("Warning: invalid GZIP_LEVEL %q; expected 1-9, using %d", raw, defaultGzipLevel)
+		return defaultGzipLevel
+	}
+	return level
+}
+
+func loadGzipLevel() int {
+	return gzipLevel(os.Getenv("GZIP_LEVEL"))
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows coding,
+// honouring q=0 exclusions.
+func acceptsEncoding(header, coding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), coding) {
+			continue
+		}
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(value, 64); err == nil && q <= 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// withCompression gzips responses for clients that accept it, except for the
+// already-compressed formats in compressionExclusions.
+func withCompression(level int, next http.Handler) http.Handler {
+	pool := &sync.Pool{
+		New: func() interface{} {
+			gz, _ := gzip.NewWriterLevel(nil, level)
+			return gz
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || r.Header.Get("Range") != "" || !acceptsEncoding(r.Header.Get("Accept-Encoding"), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, pool: pool, path: r.URL.Path}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// gzipResponseWriter compresses the body once the status is known, leaving
+// bodiless, already-encoded and excluded responses untouched.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	pool        *sync.Pool
+	path        string
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	if code < http.StatusOK {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	if code != http.StatusNoContent && code != http.StatusNotModified && h.Get("Content-Encoding") == "" &&
+		!compressionExclusions.excludes(h.Get("Content-Type"), w.path) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush pushes buffered compressed data to the client, which keeps
+// server-sent event streams live.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *gzipResponseWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	_ = w.gz.Close()
+	w.gz.Reset(nil)
+	w.pool.Put(w.gz)
+	w.gz = nil
+}
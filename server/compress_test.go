@@ -0,0 +1,131 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipLevel(t *testing.T) {
+	cases := map[string]int{"": defaultGzipLevel, "1": 1, "9": 9, "0": defaultGzipLevel, "10": defaultGzipLevel, "fast": defaultGzipLevel}
+	for raw, want := range cases {
+		if got := gzipLevel(raw); got != want {
+			t.Errorf("gzipLevel(%q) = %d, want %d", raw, got, want)
+		}
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	cases := map[string]bool{
+		"":                   false,
+		"gzip":               true,
+		"deflate, GZIP;q=.5": true,
+		"gzip;q=0":           false,
+		"br":                 false,
+	}
+	for header, want := range cases {
+		if got := acceptsEncoding(header, "gzip"); got != want {
+			t.Errorf("acceptsEncoding(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestWithCompression(t *testing.T) {
+	body := strings.Repeat(`{"items":[]}`, 100)
+	handler := withCompression(gzipLevel("9"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ingresses", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", rr.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(rr.Header().Get("Vary"), "Accept-Encoding") {
+		t.Fatalf("expected Vary: Accept-Encoding, got %q", rr.Header().Get("Vary"))
+	}
+	gz, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(gz)
+	if string(decoded) != body {
+		t.Fatal("decompressed body does not match original")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/ingresses", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Header().Get("Content-Encoding") != "" || rr.Body.String() != body {
+		t.Fatal("expected identity response without Accept-Encoding")
+	}
+}
+
+func TestWithCompressionSkipsBodilessResponses(t *testing.T) {
+	handler := withCompression(defaultGzipLevel, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Header().Get("Content-Encoding") != "" || rr.Body.Len() != 0 {
+		t.Fatalf("expected no encoding for 304, got %q with %d bytes", rr.Header().Get("Content-Encoding"), rr.Body.Len())
+	}
+}
+
+func TestWithCompressionSkipsPNG(t *testing.T) {
+	dir := t.TempDir()
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 2048)
+	writeTestFile(t, dir, "logo.png", png)
+	writeTestFile(t, dir, "app.js", strings.Repeat("console.log(1);", 100))
+	handler := withCompression(defaultGzipLevel, http.FileServer(http.Dir(dir)))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/logo.png")
+	if rr.Header().Get("Content-Encoding") != "" || rr.Body.String() != png {
+		t.Fatalf("expected the png to pass through uncompressed, got encoding %q", rr.Header().Get("Content-Encoding"))
+	}
+	if rr := get("/app.js"); rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected javascript to be compressed, got %q", rr.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCompressionExclusions(t *testing.T) {
+	list := parseCompressionExclusions("video/*, .woff2, image/png, bogus")
+	for _, tc := range []struct {
+		contentType, path string
+		want              bool
+	}{
+		{"video/mp4", "/clip", true},
+		{"image/png; charset=binary", "/x", true},
+		{"application/octet-stream", "/fonts/a.WOFF2", true},
+		{"image/svg+xml", "/icon.svg", false},
+		{"text/html; charset=utf-8", "/", false},
+		{"", "/api/ingresses", false},
+	} {
+		if got := list.excludes(tc.contentType, tc.path); got != tc.want {
+			t.Fatalf("excludes(%q, %q) = %v, want %v", tc.contentType, tc.path, got, tc.want)
+		}
+	}
+
+	t.Setenv("COMPRESSION_EXCLUDE", "")
+	if loadCompressionExclusions().excludes("image/png", "/logo.png") {
+		t.Fatal("expected an empty COMPRESSION_EXCLUDE to compress everything")
+	}
+}
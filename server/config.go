@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	configReloadSuccesses uint64
+	configReloadFailures  uint64
+
+	configReloadMu        sync.Mutex
+	configLastReloadTime  time.Time
+	configLastReloadError string
+)
+
+// recordConfigReload tracks the outcome of reloading a config source so it can
+// be surfaced through /metrics and /api/config.
+func recordConfigReload(err error) {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+
+	configLastReloadTime = time.Now()
+	if err != nil {
+		atomic.AddUint64(&configReloadFailures, 1)
+		configLastReloadError = err.Error()
+		return
+	}
+
+	atomic.AddUint64(&configReloadSuccesses, 1)
+	configLastReloadError = ""
+}
+
+// configReloader re-reads one file-based config source. A failed reload must
+// leave the previous config in place.
+type configReloader struct {
+	name   string
+	reload func() error
+}
+
+var (
+	configReloadersMu sync.Mutex
+	configReloaders   []configReloader
+)
+
+// registerConfigReloader adds a config source to reload on SIGHUP.
+func registerConfigReloader(name string, reload func() error) {
+	configReloadersMu.Lock()
+	defer configReloadersMu.Unlock()
+	configReloaders = append(configReloaders, configReloader{name: name, reload: reload})
+}
+
+// reloadConfig runs every registered reloader, logging each outcome, and
+// returns the combined errors.
+func reloadConfig() error {
+	configReloadersMu.Lock()
+	reloaders := append([]configReloader(nil), configReloaders...)
+	configReloadersMu.Unlock()
+
+	var errs []error
+	for _, r := range reloaders {
+		if err := r.reload(); err != nil {
+			log.Printf("Error reloading %s: %v; keeping previous config", r.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", r.name, err))
+			continue
+		}
+		log.Printf("Reloaded %s", r.name)
+	}
+	return errors.Join(errs...)
+}
+
+// handleReloadSignals reloads config for every signal received until ctx is
+// cancelled.
+func handleReloadSignals(ctx context.Context, signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			log.Printf("Reloading config")
+			_ = reloadConfig()
+		}
+	}
+}
+
+func configReloadStatus() (time.Time, string) {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+	return configLastReloadTime, configLastReloadError
+}
+
+func resetConfigReloadStatus() {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+
+	atomic.StoreUint64(&configReloadSuccesses, 0)
+	atomic.StoreUint64(&configReloadFailures, 0)
+	configLastReloadTime = time.Time{}
+	configLastReloadError = ""
+}
+
+type configReloadResponse struct {
+	LastReloadTime  *time.Time `json:"lastReloadTime,omitempty"`
+	LastReloadError string     `json:"lastReloadError,omitempty"`
+	Successes       uint64     `json:"successes"`
+	Failures        uint64     `json:"failures"`
+}
+
+type configResponse struct {
+	HiddenHosts        []string             `json:"hiddenHosts"`
+	APICacheControl    string               `json:"apiCacheControl"`
+	CSRFTrustedOrigins []string             `json:"csrfTrustedOrigins"`
+	DisplayTimezone    string               `json:"displayTimezone"`
+	Reload             configReloadResponse `json:"reload"`
+}
+
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	lastReload, lastErr := configReloadStatus()
+	reload := configReloadResponse{
+		LastReloadError: lastErr,
+		Successes:       atomic.LoadUint64(&configReloadSuccesses),
+		Failures:        atomic.LoadUint64(&configReloadFailures),
+	}
+	if !lastReload.IsZero() {
+		reload.LastReloadTime = &lastReload
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(configResponse{
+		HiddenHosts:        nonNilStrings(currentFlags().hiddenHostPatterns),
+		APICacheControl:    apiCacheControl,
+		CSRFTrustedOrigins: nonNilStrings(csrfTrustedOrigins),
+		DisplayTimezone:    displayLocation.String(),
+		Reload:             reload,
+	})
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestHandleConfigReportsReloadStatus(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+	defer resetMetrics()
+
+	recordConfigReload(nil)
+	recordConfigReload(errors.New("invalid redirects file"))
+
+	rr := httptest.NewRecorder()
+	handleConfig(rr, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var payload configResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json from /api/config: %v", err)
+	}
+	if payload.Reload.Successes != 1 || payload.Reload.Failures != 1 {
+		t.Fatalf("expected 1 success and 1 failure, got %+v", payload.Reload)
+	}
+	if payload.Reload.LastReloadError != "invalid redirects file" {
+		t.Fatalf("expected last reload error, got %q", payload.Reload.LastReloadError)
+	}
+	if payload.Reload.LastReloadTime == nil {
+		t.Fatal("expected last reload time to be set")
+	}
+
+	rr = httptest.NewRecorder()
+	metrics.handleMetrics(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`home_pager_config_reloads_total{result="success"} 1`,
+		`home_pager_config_reloads_total{result="failure"} 1`,
+		"home_pager_config_last_reload_timestamp_seconds ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics output", want)
+		}
+	}
+}
+
+func TestHandleReloadSignalsReloadsRedirects(t *testing.T) {
+	resetMetrics()
+	defer resetMetrics()
+	prevReloaders := configReloaders
+	defer func() {
+		configReloaders = prevReloaders
+		redirectTargets = nil
+	}()
+
+	dir := t.TempDir()
+	path := dir + "/redirects.json"
+	writeTestFile(t, dir, "redirects.json", `{"nas": "https://nas.example.com"}`)
+	if err := reloadRedirects(path); err != nil {
+		t.Fatal(err)
+	}
+	configReloaders = nil
+	registerConfigReloader("REDIRECTS_FILE", func() error { return reloadRedirects(path) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal)
+	done := make(chan struct{})
+	go func() {
+		handleReloadSignals(ctx, signals)
+		close(done)
+	}()
+
+	writeTestFile(t, dir, "redirects.json", `{"nas": "https://storage.example.com"}`)
+	signals <- syscall.SIGHUP
+	cancel()
+	<-done // the reload has finished once the handler returns
+	if target, _ := lookupRedirect("nas"); target != "https://storage.example.com" {
+		t.Fatalf("expected reloaded target, got %q", target)
+	}
+
+	writeTestFile(t, dir, "redirects.json", `{"nas": "not a url"}`)
+	if err := reloadConfig(); err == nil || !strings.Contains(err.Error(), "REDIRECTS_FILE") {
+		t.Fatalf("expected a named reload error, got %v", err)
+	}
+	if target, _ := lookupRedirect("nas"); target != "https://storage.example.com" {
+		t.Fatalf("expected the previous target after a failed reload, got %q", target)
+	}
+}
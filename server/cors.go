@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const corsPreflightMaxAge = 10 * time.Minute
+
+// corsAllowedOrigins lists the origins allowed to read responses from another
+// site. CORS is disabled when it is empty; "*" allows any origin.
+var corsAllowedOrigins []string
+
+// parseCORSOrigins splits a comma-separated list of origins such as
+// "https://dashboard.example.com", dropping trailing slashes.
+func parseCORSOrigins(raw string) []string {
+	var origins []string
+	for _, part := range strings.Split(raw, ",") {
+		origin := strings.ToLower(strings.TrimRight(strings.TrimSpace(part), "/"))
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+func corsOriginAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range corsAllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// withCORS adds CORS headers for allowed origins and answers preflight
+// requests. It must wrap every other middleware so error responses written
+// further down the chain, including http.Error and timeouts, still carry
+// Access-Control-Allow-Origin and browsers can read their status and body.
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(corsAllowedOrigins) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		if origin == "" || !corsOriginAllowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Accept-Language, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsPreflightMaxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithCORSOnErrorResponses(t *testing.T) {
+	corsAllowedOrigins = parseCORSOrigins("https://dashboard.example.com/")
+	defer func() { corsAllowedOrigins = nil }()
+	ingressesCache.reset()
+	defer ingressesCache.reset()
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+
+	handler := withCORS(withSecurityHeaders(handleIngresses(time.Second)))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ingresses", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://dashboard.example.com" {
+		t.Fatalf("expected the origin to be allowed on the error response, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/ingresses", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected no CORS header for an unknown origin, got %q", got)
+	}
+}
+
+func TestWithCORSPreflight(t *testing.T) {
+	corsAllowedOrigins = parseCORSOrigins("*")
+	defer func() { corsAllowedOrigins = nil }()
+
+	handler := withCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("preflight requests should not reach the handler")
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/favorites", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Methods"); got == "" {
+		t.Fatal("expected allowed methods on the preflight response")
+	}
+}
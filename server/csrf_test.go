@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithCSRFProtection(t *testing.T) {
+	csrfTrustedOrigins = parseTrustedOrigins("https://admin.example.com")
+	defer func() { csrfTrustedOrigins = nil }()
+
+	handler := withCSRFProtection(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		name    string
+		method  string
+		origin  string
+		referer string
+		code    int
+	}{
+		{"safe method cross-origin", http.MethodGet, "https://evil.example", "", http.StatusOK},
+		{"no origin headers", http.MethodPost, "", "", http.StatusOK},
+		{"same origin", http.MethodPost, "http://home.local", "", http.StatusOK},
+		{"same origin referer", http.MethodPost, "", "http://home.local/page", http.StatusOK},
+		{"trusted origin", http.MethodPost, "https://admin.example.com", "", http.StatusOK},
+		{"cross origin", http.MethodPost, "https://evil.example", "", http.StatusForbidden},
+		{"cross origin referer", http.MethodDelete, "", "https://evil.example/x", http.StatusForbidden},
+		{"opaque origin", http.MethodPost, "null", "", http.StatusForbidden},
+		{"opaque origin with same origin referer", http.MethodPost, "null", "http://home.local/page", http.StatusForbidden},
+		{"referer without host", http.MethodPost, "", "file:///tmp/attack.html", http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "http://home.local/api/cache", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.referer != "" {
+				req.Header.Set("Referer", tc.referer)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.code {
+				t.Fatalf("expected %d, got %d", tc.code, rr.Code)
+			}
+		})
+	}
+}
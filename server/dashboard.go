@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// dashboardSource reports how one tile source contributed to /api/dashboard.
+// Disabled sources are listed so the UI can tell "off" from "empty".
+type dashboardSource struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Count   int    `json:"count"`
+	Stale   bool   `json:"stale,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// dashboardResponse is the summary response plus a per-source breakdown.
+type dashboardResponse struct {
+	summaryResponse
+	Sources []dashboardSource `json:"sources"`
+}
+
+// handleDashboard returns every enabled tile source in one summary-format
+// response, served from the caches, so the UI needs a single request on
+// load. A failing source is reported under sources rather than failing the
+// response; only when every enabled source fails is the request an error.
+func handleDashboard(timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		response, ok := buildDashboard(ctx)
+		if !ok {
+			http.Error(w, "all dashboard sources failed", http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", apiCacheControl)
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// buildDashboard aggregates the tile sources, returning false when none of
+// the enabled sources could be fetched.
+func buildDashboard(ctx context.Context) (dashboardResponse, bool) {
+	ingressSource := dashboardSource{Name: sourceIngress, Enabled: true}
+	ingresses, stale, err := ingressesCache.fetchWithStaleness(ctx)
+	if err != nil {
+		log.Printf("Error fetching ingresses: %v", err)
+		ingressSource.Error = err.Error()
+		ingresses = map[string]interface{}{"items": []interface{}{}}
+	}
+	ingressSource.Stale = stale
+
+	entrySource := dashboardSource{Name: sourceHomepageEntry, Enabled: homepageEntries.enabled}
+	var extra []ingressSummary
+	if entrySource.Enabled {
+		entries, stale, err := entriesCache.fetchWithStaleness(ctx)
+		if err != nil {
+			log.Printf("Error fetching homepage entries: %v", err)
+			entrySource.Error = err.Error()
+		} else {
+			extra = summarizeHomepageEntries(entries)
+		}
+		entrySource.Stale = stale
+	}
+
+	tilesSource := dashboardSource{Name: sourceTilesFile, Enabled: tilesFile != ""}
+	extra = append(extra, currentFileTiles()...)
+
+	summary := summarizeIngresses(ctx, filterIngresses(ingresses), extra)
+	if summary.Ingresses == nil {
+		summary.Ingresses = []ingressSummary{}
+	}
+	sources := []dashboardSource{ingressSource, entrySource, tilesSource}
+	for _, tile := range summary.Ingresses {
+		for i := range sources {
+			if sources[i].Name == tile.Source {
+				sources[i].Count++
+			}
+		}
+	}
+
+	ok := false
+	for _, source := range sources {
+		if source.Enabled && source.Error == "" {
+			ok = true
+		}
+	}
+	return dashboardResponse{summaryResponse: summary, Sources: sources}, ok
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandleDashboard(t *testing.T) {
+	homepageEntries.enabled = true
+	ingressesCache.reset()
+	entriesCache.reset()
+	defer func() {
+		homepageEntries.enabled = false
+		ingressesCache.reset()
+		entriesCache.reset()
+	}()
+
+	failIngresses := false
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveHomepageEntryDiscovery(w, r) {
+			return
+		}
+		if r.URL.Path == homepageEntries.groupVersionPath()+"/homepageentries" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"items": []interface{}{map[string]interface{}{
+					"metadata": map[string]interface{}{"namespace": "home", "name": "nas"},
+					"spec":     map[string]interface{}{"url": "https://nas.example.com"},
+				}},
+			})
+			return
+		}
+		if failIngresses {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{testIngress("apps", "web", "web.example.com"), testIngress("apps", "api", "api.example.com")},
+		})
+	}))
+
+	get := func() (*httptest.ResponseRecorder, dashboardResponse) {
+		rr := httptest.NewRecorder()
+		handleDashboard(time.Second)(rr, httptest.NewRequest(http.MethodGet, "/api/dashboard", nil))
+		var body dashboardResponse
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr, body
+	}
+
+	rr, body := get()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if body.Count != 3 || len(body.Ingresses) != 3 {
+		t.Fatalf("expected three tiles, got %+v", body.summaryResponse)
+	}
+	want := []dashboardSource{
+		{Name: sourceIngress, Enabled: true, Count: 2},
+		{Name: sourceHomepageEntry, Enabled: true, Count: 1},
+		{Name: sourceTilesFile},
+	}
+	if !reflect.DeepEqual(body.Sources, want) {
+		t.Fatalf("expected sources %+v, got %+v", want, body.Sources)
+	}
+
+	ingressesCache.reset()
+	entriesCache.reset()
+	failIngresses = true
+	rr, body = get()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected a partial 200 when one source fails, got %d", rr.Code)
+	}
+	if body.Count != 1 || body.Sources[0].Error == "" || body.Sources[1].Count != 1 {
+		t.Fatalf("expected the entry tile and an ingress error, got %+v", body)
+	}
+
+	ingressesCache.reset()
+	homepageEntries.enabled = false
+	rr, _ = get()
+	if rr.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 when every enabled source fails, got %d", rr.Code)
+	}
+}
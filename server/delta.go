@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// deltaWatchSeconds bounds how long the apiserver streams events for a delta
+// request before closing the watch.
+const deltaWatchSeconds = "1"
+
+// maxListHistory bounds how many recent ingress lists are kept for deltas.
+const maxListHistory = 8
+
+// listHistory keeps the most recent complete ingress lists by cache key and
+// resourceVersion. A delta against a version this server listed is a diff
+// against a fresh list, which returns at once, rather than a watch that only
+// ends when the apiserver closes it.
+type listHistory struct {
+	mu    sync.Mutex
+	lists map[[2]string]map[string]interface{}
+	order [][2]string
+}
+
+var ingressListHistory = &listHistory{}
+
+// record keeps result unless it is truncated or has no resourceVersion,
+// dropping the oldest list beyond maxListHistory.
+func (h *listHistory) record(key string, result map[string]interface{}) {
+	metadata, _ := result["metadata"].(map[string]interface{})
+	resourceVersion := stringField(metadata, "resourceVersion")
+	if resourceVersion == "" || result["truncated"] == true {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.lists == nil {
+		h.lists = make(map[[2]string]map[string]interface{})
+	}
+	id := [2]string{key, resourceVersion}
+	if _, ok := h.lists[id]; !ok {
+		h.order = append(h.order, id)
+	}
+	h.lists[id] = result
+	for len(h.order) > maxListHistory {
+		delete(h.lists, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+func (h *listHistory) lookup(key, resourceVersion string) (map[string]interface{}, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result, ok := h.lists[[2]string{key, resourceVersion}]
+	return result, ok
+}
+
+func (h *listHistory) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lists = nil
+	h.order = nil
+}
+
+// errResourceVersionGone is returned when the requested resourceVersion is
+// older than the apiserver's watch cache and the client must relist.
+var errResourceVersionGone = errors.New("resourceVersion too old")
+
+type ingressDelta struct {
+	ResourceVersion string        `json:"resourceVersion"`
+	Added           []interface{} `json:"added"`
+	Modified        []interface{} `json:"modified"`
+	Deleted         []interface{} `json:"deleted"`
+}
+
+type watchEvent struct {
+	Type   string                 `json:"type"`
+	Object map[string]interface{} `json:"object"`
+}
+
+func objectKey(object map[string]interface{}) string {
+	metadata, _ := object["metadata"].(map[string]interface{})
+	return stringField(metadata, "namespace") + "/" + stringField(metadata, "name")
+}
+
+func objectResourceVersion(object map[string]interface{}) string {
+	metadata, _ := object["metadata"].(map[string]interface{})
+	return stringField(metadata, "resourceVersion")
+}
+
+// watchStatusCode extracts the HTTP status code from a watch ERROR event.
+func watchStatusCode(object map[string]interface{}) int {
+	code, _ := jsonInt(object["code"])
+	return code
+}
+
+// fetchIngressDelta returns the net set of added, modified and deleted
+// ingresses since resourceVersion. When that version is in the list history,
+// it diffs the old list against a fresh one; otherwise it lists the
+// ingresses as they were at that version and collapses the watch events
+// since then against that list.
+func fetchIngressDelta(ctx context.Context, resourceVersion string) (ingressDelta, error) {
+	delta := ingressDelta{
+		ResourceVersion: resourceVersion,
+		Added:           []interface{}{},
+		Modified:        []interface{}{},
+		Deleted:         []interface{}{},
+	}
+	if previous, ok := ingressListHistory.lookup(cacheKey(ctx), resourceVersion); ok {
+		current, err := ingressesCache.refresh(ctx)
+		if err != nil {
+			return delta, err
+		}
+		return diffIngressLists(delta, previous, current), nil
+	}
+	before, err := visibleIngressesAt(ctx, resourceVersion)
+	if err != nil {
+		return delta, err
+	}
+	events, err := watchIngressEvents(ctx, resourceVersion)
+	if err != nil {
+		return delta, err
+	}
+	return collapseWatchEvents(delta, before, events), nil
+}
+
+// visibleIngressesAt returns the keys of the ingresses that passed the
+// dashboard filters at exactly resourceVersion, which a watch cannot tell,
+// or errResourceVersionGone when the apiserver has compacted past it.
+func visibleIngressesAt(ctx context.Context, resourceVersion string) (map[string]bool, error) {
+	if !kubeAPIConfigured() {
+		return nil, nil
+	}
+	result, err := listKubernetesPagesAt(ctx, listedResource.path(), resourceVersion)
+	var apiErr *kubernetesAPIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusGone {
+		return nil, errResourceVersionGone
+	}
+	if err != nil {
+		return nil, err
+	}
+	return visibleIngressKeys(result), nil
+}
+
+// visibleIngressKeys returns the keys of the items of an ingress list that
+// pass the dashboard filters.
+func visibleIngressKeys(result map[string]interface{}) map[string]bool {
+	items, _ := result["items"].([]interface{})
+	keys := make(map[string]bool, len(items))
+	for _, item := range items {
+		if object, ok := item.(map[string]interface{}); ok && isVisibleIngress(object) {
+			keys[objectKey(object)] = true
+		}
+	}
+	return keys
+}
+
+// visibilityEventType names the change to an object for a client that holds
+// the visible ingresses: one that becomes visible is added, one that stops
+// being visible or is deleted is deleted, and one that was never visible is
+// skipped with "".
+func visibilityEventType(wasVisible, visible bool) string {
+	switch {
+	case visible && !wasVisible:
+		return "added"
+	case visible:
+		return "modified"
+	case wasVisible:
+		return "deleted"
+	default:
+		return ""
+	}
+}
+
+// watchIngressEvents returns the watch events the apiserver still holds after
+// resourceVersion, or errResourceVersionGone when it has compacted past it.
+func watchIngressEvents(ctx context.Context, resourceVersion string) ([]watchEvent, error) {
+	if !kubeAPIConfigured() {
+		return nil, nil
+	}
+
+	req, err := newKubernetesRequest(ctx, listedResource.path(), url.Values{
+		"watch":               {"1"},
+		"resourceVersion":     {resourceVersion},
+		"allowWatchBookmarks": {"true"},
+		"timeoutSeconds":      {deltaWatchSeconds},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return nil, errResourceVersionGone
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxIngressesBodyBytes))
+		return nil, &kubernetesAPIError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+	}
+
+	var events []watchEvent
+	decoder := json.NewDecoder(io.LimitReader(resp.Body, maxIngressesBodyBytes))
+	decoder.UseNumber()
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				break
+			}
+			return nil, err
+		}
+		if event.Type == "ERROR" {
+			if watchStatusCode(event.Object) == http.StatusGone {
+				return nil, errResourceVersionGone
+			}
+			return nil, errors.New("kubernetes watch error: " + stringField(event.Object, "message"))
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// collapseWatchEvents folds a sequence of watch events into a delta holding
+// only the latest state of each object, given the keys of the objects that
+// were visible before the first event. Objects that become visible are
+// reported as added and objects that stop being visible as deleted.
+func collapseWatchEvents(delta ingressDelta, before map[string]bool, events []watchEvent) ingressDelta {
+	type change struct {
+		lastType string
+		object   map[string]interface{}
+	}
+
+	changes := make(map[string]*change)
+	var order []string
+	for _, event := range events {
+		if rv := objectResourceVersion(event.Object); rv != "" {
+			delta.ResourceVersion = rv
+		}
+		if event.Type == "BOOKMARK" {
+			continue
+		}
+
+		key := objectKey(event.Object)
+		c, ok := changes[key]
+		if !ok {
+			c = &change{}
+			changes[key] = c
+			order = append(order, key)
+		}
+		c.lastType = event.Type
+		c.object = event.Object
+	}
+
+	for _, key := range order {
+		c := changes[key]
+		switch visibilityEventType(before[key], c.lastType != "DELETED" && isVisibleIngress(c.object)) {
+		case "added":
+			delta.Added = append(delta.Added, c.object)
+		case "modified":
+			delta.Modified = append(delta.Modified, c.object)
+		case "deleted":
+			delta.Deleted = append(delta.Deleted, c.object)
+		}
+	}
+	return delta
+}
+
+// diffIngressLists compares the visible ingresses of two lists. Ingresses
+// that disappear or stop passing the dashboard filters are reported as
+// deleted.
+func diffIngressLists(delta ingressDelta, previous, current map[string]interface{}) ingressDelta {
+	visible := func(result map[string]interface{}) (map[string]map[string]interface{}, []string) {
+		items, _ := result["items"].([]interface{})
+		objects := make(map[string]map[string]interface{}, len(items))
+		var order []string
+		for _, item := range items {
+			object, ok := item.(map[string]interface{})
+			if !ok || !isVisibleIngress(object) {
+				continue
+			}
+			key := objectKey(object)
+			objects[key] = object
+			order = append(order, key)
+		}
+		return objects, order
+	}
+	before, beforeOrder := visible(previous)
+	after, afterOrder := visible(current)
+
+	for _, key := range afterOrder {
+		old, ok := before[key]
+		switch {
+		case !ok:
+			delta.Added = append(delta.Added, after[key])
+		case objectResourceVersion(old) != objectResourceVersion(after[key]):
+			delta.Modified = append(delta.Modified, after[key])
+		}
+	}
+	for _, key := range beforeOrder {
+		if _, ok := after[key]; !ok {
+			delta.Deleted = append(delta.Deleted, before[key])
+		}
+	}
+
+	metadata, _ := current["metadata"].(map[string]interface{})
+	if resourceVersion := stringField(metadata, "resourceVersion"); resourceVersion != "" {
+		delta.ResourceVersion = resourceVersion
+	}
+	return delta
+}
+
+func writeIngressDelta(ctx context.Context, w http.ResponseWriter, resourceVersion string) {
+	delta, err := fetchIngressDelta(ctx, resourceVersion)
+	w.Header().Set("Cache-Control", "no-cache")
+	if errors.Is(err, errResourceVersionGone) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGone)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"resync": true, "error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching ingress delta: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(delta)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serveExactList answers a list at an exact resourceVersion with the given
+// items, or 410 Gone for the expired version.
+func serveExactList(t *testing.T, w http.ResponseWriter, r *http.Request, expired string, items ...interface{}) {
+	t.Helper()
+	query := r.URL.Query()
+	if query.Get("resourceVersionMatch") != "Exact" {
+		t.Errorf("expected a list at an exact resourceVersion, got %s", r.URL.RawQuery)
+	}
+	if query.Get("resourceVersion") == expired {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"metadata": map[string]interface{}{"resourceVersion": query.Get("resourceVersion")},
+		"items":    append([]interface{}{}, items...),
+	})
+}
+
+func watchObject(name, resourceVersion string) map[string]interface{} {
+	item := testIngress("default", name, name+".example.com")
+	item["metadata"].(map[string]interface{})["resourceVersion"] = resourceVersion
+	return item
+}
+
+func TestCollapseWatchEvents(t *testing.T) {
+	events := []watchEvent{
+		{Type: "ADDED", Object: watchObject("new", "11")},
+		{Type: "MODIFIED", Object: watchObject("new", "12")},
+		{Type: "MODIFIED", Object: watchObject("existing", "13")},
+		{Type: "ADDED", Object: watchObject("transient", "14")},
+		{Type: "DELETED", Object: watchObject("transient", "15")},
+		{Type: "DELETED", Object: watchObject("gone", "16")},
+		{Type: "BOOKMARK", Object: map[string]interface{}{"metadata": map[string]interface{}{"resourceVersion": "20"}}},
+	}
+
+	before := map[string]bool{"default/existing": true, "default/gone": true}
+	delta := collapseWatchEvents(ingressDelta{ResourceVersion: "10"}, before, events)
+	if delta.ResourceVersion != "20" {
+		t.Fatalf("expected resourceVersion 20, got %q", delta.ResourceVersion)
+	}
+	if len(delta.Added) != 1 || objectResourceVersion(delta.Added[0].(map[string]interface{})) != "12" {
+		t.Fatalf("expected latest state of new ingress in added, got %v", delta.Added)
+	}
+	if len(delta.Modified) != 1 || objectKey(delta.Modified[0].(map[string]interface{})) != "default/existing" {
+		t.Fatalf("expected existing ingress in modified, got %v", delta.Modified)
+	}
+	if len(delta.Deleted) != 1 || objectKey(delta.Deleted[0].(map[string]interface{})) != "default/gone" {
+		t.Fatalf("expected gone ingress in deleted, got %v", delta.Deleted)
+	}
+}
+
+func TestCollapseWatchEventsHiddenBecomesDeleted(t *testing.T) {
+	setFlags(t, func(f *featureFlags) { f.hiddenHostPatterns = parseHostPatterns("*.example.com") })
+
+	events := []watchEvent{{Type: "MODIFIED", Object: watchObject("app", "5")}}
+	delta := collapseWatchEvents(ingressDelta{}, map[string]bool{"default/app": true}, events)
+	if len(delta.Modified) != 0 || len(delta.Deleted) != 1 {
+		t.Fatalf("expected hidden ingress to be reported deleted, got %+v", delta)
+	}
+
+	delta = collapseWatchEvents(ingressDelta{}, map[string]bool{}, events)
+	if len(delta.Modified) != 0 || len(delta.Deleted) != 0 {
+		t.Fatalf("expected an ingress that was never visible to be skipped, got %+v", delta)
+	}
+}
+
+func TestCollapseWatchEventsNewlyVisibleBecomesAdded(t *testing.T) {
+	delta := collapseWatchEvents(ingressDelta{}, map[string]bool{}, []watchEvent{{Type: "MODIFIED", Object: watchObject("app", "5")}})
+	if len(delta.Added) != 1 || len(delta.Modified) != 0 {
+		t.Fatalf("expected a newly visible ingress to be reported added, got %+v", delta)
+	}
+}
+
+func TestHandleIngressesDelta(t *testing.T) {
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") != "1" {
+			serveExactList(t, w, r, "1")
+			return
+		}
+		if r.URL.Query().Get("resourceVersion") == "1" {
+			enc := json.NewEncoder(w)
+			_ = enc.Encode(watchEvent{Type: "ERROR", Object: map[string]interface{}{"code": 410, "message": "too old"}})
+			return
+		}
+		enc := json.NewEncoder(w)
+		_ = enc.Encode(watchEvent{Type: "ADDED", Object: watchObject("app", "101")})
+	}))
+
+	h := handleIngresses(time.Second)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses?resourceVersion=100", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var delta ingressDelta
+	if err := json.Unmarshal(rr.Body.Bytes(), &delta); err != nil {
+		t.Fatalf("invalid delta json: %v", err)
+	}
+	if delta.ResourceVersion != "101" || len(delta.Added) != 1 {
+		t.Fatalf("unexpected delta: %+v", delta)
+	}
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses?resourceVersion=1", nil))
+	if rr.Code != http.StatusGone {
+		body, _ := io.ReadAll(rr.Body)
+		t.Fatalf("expected 410 for expired resourceVersion, got %d: %s", rr.Code, body)
+	}
+}
+
+func TestHandleIngressesDeltaDiffsListedVersions(t *testing.T) {
+	ingressesCache.reset()
+	defer ingressesCache.reset()
+
+	var listVersion atomic.Int32
+	listVersion.Store(10)
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "1" {
+			t.Errorf("expected a listed resourceVersion to be diffed without a watch")
+			return
+		}
+		items := []interface{}{watchObject("kept", "5"), watchObject("changed", "6"), watchObject("removed", "7")}
+		if listVersion.Load() == 12 {
+			items = []interface{}{watchObject("kept", "5"), watchObject("changed", "11"), watchObject("added", "12")}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"metadata": map[string]interface{}{"resourceVersion": strconv.Itoa(int(listVersion.Load()))},
+			"items":    items,
+		})
+	}))
+
+	h := handleIngresses(time.Second)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	listVersion.Store(12)
+	start := time.Now()
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses?resourceVersion=10", nil))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the delta to return at once, took %s", elapsed)
+	}
+	var delta ingressDelta
+	if err := json.Unmarshal(rr.Body.Bytes(), &delta); err != nil {
+		t.Fatalf("invalid delta json: %v", err)
+	}
+	name := func(objects []interface{}) string {
+		if len(objects) != 1 {
+			return ""
+		}
+		return objectKey(objects[0].(map[string]interface{}))
+	}
+	if delta.ResourceVersion != "12" || name(delta.Added) != "default/added" || name(delta.Modified) != "default/changed" || name(delta.Deleted) != "default/removed" {
+		t.Fatalf("unexpected delta: %+v", delta)
+	}
+}
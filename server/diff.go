@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ingressDiff is the net change to the visible ingresses between two
+// resource versions.
+type ingressDiff struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Added    []interface{} `json:"added"`
+	Modified []interface{} `json:"modified"`
+	Deleted  []interface{} `json:"deleted"`
+}
+
+// parseResourceVersion accepts the decimal resource versions the apiserver
+// hands out. Kubernetes calls them opaque, but they are etcd revisions in
+// practice and ordering them is the only way to bound a diff.
+func parseResourceVersion(raw string) (uint64, bool) {
+	value, err := strconv.ParseUint(raw, 10, 64)
+	return value, err == nil && value > 0
+}
+
+// eventsThrough keeps the events up to and including resource version to.
+// Events without a numeric resource version are kept.
+func eventsThrough(events []watchEvent, to uint64) []watchEvent {
+	var kept []watchEvent
+	for _, event := range events {
+		if rv, ok := parseResourceVersion(objectResourceVersion(event.Object)); ok && rv > to {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	return kept
+}
+
+// fetchIngressDiff replays the watch from from and collapses the events up to
+// to, or all of them when to is zero, against the list at from.
+func fetchIngressDiff(ctx context.Context, from string, to uint64) (ingressDiff, error) {
+	before, err := visibleIngressesAt(ctx, from)
+	if err != nil {
+		return ingressDiff{}, err
+	}
+	events, err := watchIngressEvents(ctx, from)
+	if err != nil {
+		return ingressDiff{}, err
+	}
+	if to > 0 {
+		events = eventsThrough(events, to)
+	}
+
+	delta := collapseWatchEvents(ingressDelta{
+		ResourceVersion: from,
+		Added:           []interface{}{},
+		Modified:        []interface{}{},
+		Deleted:         []interface{}{},
+	}, before, events)
+	diff := ingressDiff{
+		From:     from,
+		To:       delta.ResourceVersion,
+		Added:    delta.Added,
+		Modified: delta.Modified,
+		Deleted:  delta.Deleted,
+	}
+	if to > 0 {
+		diff.To = strconv.FormatUint(to, 10)
+	}
+	return diff, nil
+}
+
+// handleIngressDiff serves /api/ingresses/diff?from=<rv>[&to=<rv>], the
+// added, modified and deleted ingresses between two resource versions. A
+// from version the apiserver has already compacted away answers 410 with
+// {"resync": true}, telling the client to take a fresh snapshot instead.
+func handleIngressDiff(timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if watchUnavailable(w, r) {
+			return
+		}
+		from := r.URL.Query().Get("from")
+		fromVersion, ok := parseResourceVersion(from)
+		if !ok {
+			localizedError(w, r, msgInvalidResourceVersion, http.StatusBadRequest)
+			return
+		}
+		var toVersion uint64
+		if to := r.URL.Query().Get("to"); to != "" {
+			toVersion, ok = parseResourceVersion(to)
+			if !ok || toVersion < fromVersion {
+				localizedError(w, r, msgInvalidResourceVersion, http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		diff, err := fetchIngressDiff(ctx, from, toVersion)
+		w.Header().Set("Cache-Control", "no-cache")
+		if errors.Is(err, errResourceVersionGone) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusGone)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"resync": true, "error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("Error fetching ingress diff: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(diff)
+	}
+}
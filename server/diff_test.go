@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleIngressDiff(t *testing.T) {
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") != "1" {
+			serveExactList(t, w, r, "5", watchObject("old", "90"), watchObject("gone", "91"))
+			return
+		}
+		enc := json.NewEncoder(w)
+		if r.URL.Query().Get("resourceVersion") == "5" {
+			_ = enc.Encode(watchEvent{Type: "ERROR", Object: map[string]interface{}{"code": 410, "message": "too old"}})
+			return
+		}
+		_ = enc.Encode(watchEvent{Type: "ADDED", Object: watchObject("app", "101")})
+		_ = enc.Encode(watchEvent{Type: "MODIFIED", Object: watchObject("old", "102")})
+		_ = enc.Encode(watchEvent{Type: "DELETED", Object: watchObject("gone", "103")})
+	}))
+
+	get := func(query string) (*httptest.ResponseRecorder, ingressDiff) {
+		rr := httptest.NewRecorder()
+		handleIngressDiff(time.Second)(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses/diff?"+query, nil))
+		var diff ingressDiff
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &diff); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr, diff
+	}
+
+	rr, diff := get("from=100&to=102")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if diff.From != "100" || diff.To != "102" || len(diff.Added) != 1 || len(diff.Modified) != 1 || len(diff.Deleted) != 0 {
+		t.Fatalf("expected changes through 102 only, got %+v", diff)
+	}
+
+	_, diff = get("from=100")
+	if diff.To != "103" || len(diff.Deleted) != 1 {
+		t.Fatalf("expected every change without to, got %+v", diff)
+	}
+
+	rr, _ = get("from=5")
+	var body map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || rr.Code != http.StatusGone || body["resync"] != true {
+		t.Fatalf("expected 410 with resync for an expired from, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for _, query := range []string{"", "from=abc", "from=100&to=99", "from=100&to=x"} {
+		if rr, _ := get(query); rr.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Apiserver discovery methods for KUBE_API_DISCOVERY.
+const (
+	discoveryEnv        = "env"
+	discoveryKubeconfig = "kubeconfig"
+	discoveryDNS        = "dns"
+
+	// kubernetesServiceDNSName is the in-cluster name of the default
+	// "kubernetes" service, which the service account CA certificate covers.
+	kubernetesServiceDNSName = "kubernetes.default.svc"
+	defaultKubernetesPort    = "443"
+)
+
+var defaultDiscoveryMethods = []string{discoveryEnv, discoveryKubeconfig, discoveryDNS}
+
+// lookupHost resolves names for the dns discovery method; tests replace it.
+var lookupHost = net.DefaultResolver.LookupHost
+
+// parseDiscoveryMethods splits KUBE_API_DISCOVERY, skipping unknown methods.
+// An empty value tries every method in the default order.
+func parseDiscoveryMethods(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return defaultDiscoveryMethods
+	}
+	var methods []string
+	for _, part := range strings.Split(raw, ",") {
+		switch method := strings.ToLower(strings.TrimSpace(part)); method {
+		case "":
+		case discoveryEnv, discoveryKubeconfig, discoveryDNS:
+			methods = append(methods, method)
+		default:
+			log.Printf("Warning: ignoring unknown KUBE_API_DISCOVERY method %q", method)
+		}
+	}
+	return methods
+}
+
+// probeKubernetesAPI checks that addr accepts connections; tests replace it.
+var probeKubernetesAPI = func(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// discoveryCandidate is an apiserver one discovery method found.
+type discoveryCandidate struct {
+	method string
+	host   string
+	port   string
+	target *kubeconfigTarget
+}
+
+// addr returns the host:port to probe, or "" when it cannot be determined.
+func (c discoveryCandidate) addr() string {
+	if c.target == nil {
+		return net.JoinHostPort(c.host, c.port)
+	}
+	u, err := url.Parse(c.target.server)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return urlHostPort(u)
+}
+
+func (c discoveryCandidate) validate() error {
+	if c.target != nil {
+		return nil
+	}
+	return validateKubernetesService(c.host, c.port)
+}
+
+// findCandidate runs one discovery method.
+func findCandidate(method string, dialTimeout time.Duration) (discoveryCandidate, bool) {
+	switch method {
+	case discoveryEnv:
+		host := strings.TrimSpace(os.Getenv("KUBERNETES_SERVICE_HOST"))
+		port := strings.TrimSpace(os.Getenv("KUBERNETES_SERVICE_PORT"))
+		if host != "" && port != "" {
+			return discoveryCandidate{method: method, host: host, port: port}, true
+		}
+
+	case discoveryKubeconfig:
+		path := findKubeconfig()
+		if path == "" {
+			return discoveryCandidate{}, false
+		}
+		target, err := loadKubeconfig(path)
+		if err != nil {
+			kubeconfigErr = fmt.Errorf("could not load kubeconfig %s: %w", path, err)
+			log.Printf("Warning: %v", kubeconfigErr)
+			return discoveryCandidate{}, false
+		}
+		log.Printf("Using kubeconfig %s", path)
+		return discoveryCandidate{method: method, target: target}, true
+
+	case discoveryDNS:
+		if _, err := os.Stat(serviceAccountTokenPath); err != nil {
+			return discoveryCandidate{}, false
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		addrs, err := lookupHost(ctx, kubernetesServiceDNSName)
+		cancel()
+		if err != nil || len(addrs) == 0 {
+			log.Printf("Warning: Could not resolve %s: %v", kubernetesServiceDNSName, err)
+			return discoveryCandidate{}, false
+		}
+		port := strings.TrimSpace(os.Getenv("KUBERNETES_SERVICE_PORT"))
+		if port == "" {
+			port = defaultKubernetesPort
+		}
+		return discoveryCandidate{method: method, host: kubernetesServiceDNSName, port: port}, true
+	}
+	return discoveryCandidate{}, false
+}
+
+// discoverKubernetesAPI tries methods in order and returns the first whose
+// apiserver is valid and accepts connections, with the kubeconfig target when
+// that method won.
+//
+//   - env uses KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT.
+//   - kubeconfig uses the current context of KUBECONFIG or ~/.kube/config.
+//   - dns resolves kubernetes.default.svc, for pods whose service
+//     environment variables are missing or point at an unreachable proxy. It
+//     only runs when a service account token is mounted.
+//
+// A candidate that is malformed or cannot be dialed within dialTimeout falls
+// through to the next method. When none can be reached, the first candidate
+// found is used anyway, so an apiserver that is briefly down at startup, or a
+// malformed value that readiness should report, is not mistaken for running
+// outside a cluster. The in-cluster host and port are set for env and dns.
+// It returns "" when nothing was found.
+func discoverKubernetesAPI(methods []string, dialTimeout time.Duration) (string, *kubeconfigTarget) {
+	var fallback *discoveryCandidate
+	for _, method := range methods {
+		candidate, ok := findCandidate(method, dialTimeout)
+		if !ok {
+			continue
+		}
+		if fallback == nil {
+			fallback = &candidate
+		}
+		if err := candidate.validate(); err != nil {
+			log.Printf("Warning: Kubernetes API found via %s is invalid: %v; trying the next method", method, err)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		err := probeKubernetesAPI(ctx, candidate.addr())
+		cancel()
+		if err != nil {
+			log.Printf("Warning: Kubernetes API %s found via %s is unreachable: %v; trying the next method", candidate.addr(), method, err)
+			continue
+		}
+		return useCandidate(candidate)
+	}
+	if fallback == nil {
+		return "", nil
+	}
+	log.Printf("Warning: No reachable Kubernetes API found; using the one found via %s", fallback.method)
+	return useCandidate(*fallback)
+}
+
+func useCandidate(c discoveryCandidate) (string, *kubeconfigTarget) {
+	if c.target == nil {
+		kubernetesServiceHost, kubernetesServicePort = c.host, c.port
+	}
+	return c.method, c.target
+}
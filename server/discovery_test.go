@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDiscoveryMethods(t *testing.T) {
+	if got := parseDiscoveryMethods(""); !reflect.DeepEqual(got, defaultDiscoveryMethods) {
+		t.Errorf("expected the default order, got %v", got)
+	}
+	if got := parseDiscoveryMethods(" DNS, bogus ,env"); !reflect.DeepEqual(got, []string{discoveryDNS, discoveryEnv}) {
+		t.Errorf("expected dns then env, got %v", got)
+	}
+}
+
+func TestDiscoverKubernetesAPI(t *testing.T) {
+	prevLookup, prevTokenPath, prevProbe := lookupHost, serviceAccountTokenPath, probeKubernetesAPI
+	defer func() {
+		lookupHost, serviceAccountTokenPath, probeKubernetesAPI = prevLookup, prevTokenPath, prevProbe
+		kubernetesServiceHost, kubernetesServicePort = "", ""
+	}()
+	dir := t.TempDir()
+	writeTestFile(t, dir, "token", "sa-token")
+	serviceAccountTokenPath = filepath.Join(dir, "token")
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "missing"))
+
+	var lookups int
+	lookupHost = func(ctx context.Context, host string) ([]string, error) {
+		lookups++
+		if host != kubernetesServiceDNSName {
+			return nil, errors.New("unexpected host " + host)
+		}
+		return []string{"10.96.0.1"}, nil
+	}
+	unreachable := map[string]bool{}
+	probeKubernetesAPI = func(ctx context.Context, addr string) error {
+		if unreachable[addr] {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "6443")
+	if method, _ := discoverKubernetesAPI(defaultDiscoveryMethods, time.Second); method != discoveryEnv || kubernetesServiceHost != "10.0.0.1" {
+		t.Fatalf("expected the service environment to win, got %q (%s)", method, kubernetesServiceHost)
+	}
+	if lookups != 0 {
+		t.Fatal("expected no DNS lookup once env found the apiserver")
+	}
+
+	unreachable["10.0.0.1:6443"] = true
+	if method, _ := discoverKubernetesAPI(defaultDiscoveryMethods, time.Second); method != discoveryDNS || kubernetesServiceHost != kubernetesServiceDNSName {
+		t.Fatalf("expected an unreachable env apiserver to fall through to dns, got %q (%s)", method, kubernetesServiceHost)
+	}
+	unreachable["kubernetes.default.svc:6443"] = true
+	if method, _ := discoverKubernetesAPI(defaultDiscoveryMethods, time.Second); method != discoveryEnv || kubernetesServiceHost != "10.0.0.1" {
+		t.Fatalf("expected the first candidate when none is reachable, got %q (%s)", method, kubernetesServiceHost)
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+	method, target := discoverKubernetesAPI(defaultDiscoveryMethods, time.Second)
+	if method != discoveryDNS || target != nil {
+		t.Fatalf("expected the dns fallback, got %q", method)
+	}
+	if kubernetesServiceHost != kubernetesServiceDNSName || kubernetesServicePort != defaultKubernetesPort {
+		t.Fatalf("expected %s:%s, got %s:%s", kubernetesServiceDNSName, defaultKubernetesPort, kubernetesServiceHost, kubernetesServicePort)
+	}
+
+	kubernetesServiceHost, kubernetesServicePort = "", ""
+	serviceAccountTokenPath = filepath.Join(dir, "missing-token")
+	if method, _ := discoverKubernetesAPI(defaultDiscoveryMethods, time.Second); method != "" {
+		t.Fatalf("expected dns to be skipped without a service account token, got %q", method)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	sourceIngress       = "ingress"
+	sourceHomepageEntry = "homepageEntry"
+)
+
+// homepageEntrySource describes the optional HomepageEntry custom resource
+// whose objects declare dashboard tiles that are not backed by an ingress.
+// It is identified by group, version and kind; the resource to list is
+// looked up in the group version's discovery document.
+type homepageEntrySource struct {
+	enabled bool
+	group   string
+	version string
+	kind    string
+}
+
+var homepageEntries = homepageEntrySource{
+	group:   "home-pager.io",
+	version: "v1alpha1",
+	kind:    "HomepageEntry",
+}
+
+var entriesCache = &ingressCache{fetcher: fetchHomepageEntries}
+
+func loadHomepageEntrySource() homepageEntrySource {
+	source := homepageEntries
+	source.enabled = getEnvBool("HOMEPAGE_ENTRIES", false)
+	if value := strings.TrimSpace(os.Getenv("HOMEPAGE_ENTRY_GROUP")); value != "" {
+		source.group = value
+	}
+	if value := strings.TrimSpace(os.Getenv("HOMEPAGE_ENTRY_VERSION")); value != "" {
+		source.version = value
+	}
+	if value := strings.TrimSpace(os.Getenv("HOMEPAGE_ENTRY_KIND")); value != "" {
+		source.kind = value
+	}
+	return source
+}
+
+func (s homepageEntrySource) groupVersionPath() string {
+	return "/apis/" + s.group + "/" + s.version
+}
+
+// resource finds the resource serving kind in the group version's discovery
+// document. It reports false when the group version is not served or has no
+// such kind, as when the CRD is not installed.
+func (s homepageEntrySource) resource(ctx context.Context) (string, bool, error) {
+	result, err := getKubernetesJSON(ctx, s.groupVersionPath(), nil)
+	if isNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	resources, _ := result["resources"].([]interface{})
+	for _, resource := range resources {
+		resourceMap, _ := resource.(map[string]interface{})
+		name := stringField(resourceMap, "name")
+		// Subresources such as homepageentries/status share the kind.
+		if stringField(resourceMap, "kind") == s.kind && name != "" && !strings.Contains(name, "/") {
+			return name, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func isNotFound(err error) bool {
+	var apiErr *kubernetesAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// fetchHomepageEntries lists HomepageEntry objects, treating a missing CRD as
+// an empty list.
+func fetchHomepageEntries(ctx context.Context) (map[string]interface{}, error) {
+	empty := map[string]interface{}{"items": []interface{}{}}
+	if !homepageEntries.enabled || !kubeAPIConfigured() {
+		return empty, nil
+	}
+
+	resource, ok, err := homepageEntries.resource(ctx)
+	if err != nil || !ok {
+		return empty, err
+	}
+	result, err := getKubernetesJSON(ctx, homepageEntries.groupVersionPath()+"/"+resource, nil)
+	if isNotFound(err) {
+		return empty, nil
+	}
+	return result, err
+}
+
+// summarizeHomepageEntries converts HomepageEntry objects into summaries,
+// skipping entries without a valid http(s) URL. Entries pass the same
+// filters as ingresses: EXCLUDE_NAMESPACES, OPT_IN_ONLY through the
+// home-pager.io/show annotation, and HIDDEN_HOSTS.
+func summarizeHomepageEntries(result map[string]interface{}) []ingressSummary {
+	items, _ := result["items"].([]interface{})
+	flags := currentFlags()
+
+	var summaries []ingressSummary
+	for _, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		metadata, _ := itemMap["metadata"].(map[string]interface{})
+		spec, _ := itemMap["spec"].(map[string]interface{})
+		if flags.excludedNamespaces[stringField(metadata, "namespace")] {
+			continue
+		}
+		if flags.optInOnly && !isOptedIn(itemMap) {
+			continue
+		}
+
+		rawURL := strings.TrimSpace(stringField(spec, "url"))
+		if !isValidLinkURL(rawURL) {
+			continue
+		}
+		parsed, _ := url.Parse(rawURL)
+		if hostsHidden([]string{parsed.Hostname()}, flags.hiddenHostPatterns) {
+			continue
+		}
+
+		summary := ingressSummary{
+			Namespace:   stringField(metadata, "namespace"),
+			Name:        stringField(metadata, "name"),
+			Title:       strings.TrimSpace(stringField(spec, "title")),
+			Description: strings.TrimSpace(stringField(spec, "description")),
+			Icon:        strings.TrimSpace(stringField(spec, "icon")),
+			Hosts:       []string{parsed.Hostname()},
+			URL:         rawURL,
+			URLs:        []string{rawURL},
+			TLS:         parsed.Scheme == "https",
+			Visibility:  hostsVisibility([]string{parsed.Hostname()}),
+			Tags:        ingressTags(itemMap),
+			Source:      sourceHomepageEntry,
+		}
+		if summary.Title == "" {
+			summary.Title = summary.Name
+		}
+		if value, ok := jsonInt(spec["order"]); ok {
+			summary.Order = &value
+		} else if raw, ok := spec["order"].(string); ok {
+			if value, err := strconv.Atoi(raw); err == nil {
+				summary.Order = &value
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// fetchExtraSummaries returns summaries from sources other than ingresses.
+// Failures are logged rather than failing the whole response.
+func fetchExtraSummaries(ctx context.Context) []ingressSummary {
+	summaries := currentFileTiles()
+	if !homepageEntries.enabled {
+		return summaries
+	}
+
+	result, err := entriesCache.fetch(ctx)
+	if err != nil {
+		log.Printf("Error fetching homepage entries: %v", err)
+		return summaries
+	}
+	return append(summaries, summarizeHomepageEntries(result)...)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestSummarizeHomepageEntries(t *testing.T) {
+	result := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"namespace":   "links",
+					"name":        "github",
+					"annotations": map[string]interface{}{tagAnnotationPrefix + "team": " dev "},
+				},
+				"spec": map[string]interface{}{
+					"title": "GitHub",
+					"url":   "https://github.com/damacus",
+					"icon":  "🐙",
+					"order": float64(5),
+				},
+			},
+			map[string]interface{}{
+				"metadata": map[string]interface{}{"namespace": "links", "name": "router"},
+				"spec":     map[string]interface{}{"url": "http://192.168.1.1"},
+			},
+			map[string]interface{}{
+				"metadata": map[string]interface{}{"namespace": "links", "name": "invalid"},
+				"spec":     map[string]interface{}{"url": "ftp://nas.local"},
+			},
+		},
+	}
+
+	got := summarizeHomepageEntries(result)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", len(got), got)
+	}
+	if got[0].Title != "GitHub" || !got[0].TLS || got[0].Hosts[0] != "github.com" || *got[0].Order != 5 || got[0].Tags["team"] != "dev" {
+		t.Fatalf("unexpected github entry: %+v", got[0])
+	}
+	if got[1].Title != "router" || got[1].TLS || got[1].Source != sourceHomepageEntry || got[1].Tags != nil {
+		t.Fatalf("unexpected router entry: %+v", got[1])
+	}
+}
+
+// serveHomepageEntryDiscovery answers the discovery request for the entry
+// group version, serving the HomepageEntry kind as homepageentries.
+func serveHomepageEntryDiscovery(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Path != homepageEntries.groupVersionPath() {
+		return false
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"resources": []interface{}{
+			map[string]interface{}{"name": "homepageentries/status", "kind": "HomepageEntry"},
+			map[string]interface{}{"name": "homepageentries", "kind": "HomepageEntry"},
+		},
+	})
+	return true
+}
+
+func TestFetchHomepageEntriesMissingCRD(t *testing.T) {
+	homepageEntries.enabled = true
+	defer func() { homepageEntries.enabled = false }()
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"group version not served": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != homepageEntries.groupVersionPath() {
+				t.Errorf("unexpected path %q", r.URL.Path)
+			}
+			http.NotFound(w, r)
+		},
+		"kind not served": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != homepageEntries.groupVersionPath() {
+				t.Errorf("unexpected path %q", r.URL.Path)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"resources": []interface{}{map[string]interface{}{"name": "widgets", "kind": "Widget"}},
+			})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			withTestKubernetesAPI(t, handler)
+
+			result, err := fetchHomepageEntries(context.Background())
+			if err != nil {
+				t.Fatalf("expected missing CRD to be skipped, got %v", err)
+			}
+			if items := result["items"].([]interface{}); len(items) != 0 {
+				t.Fatalf("expected no entries, got %v", items)
+			}
+		})
+	}
+}
+
+func TestFetchHomepageEntriesResolvesKind(t *testing.T) {
+	homepageEntries.enabled = true
+	homepageEntries.kind = "Bookmark"
+	defer func() { homepageEntries.enabled, homepageEntries.kind = false, "HomepageEntry" }()
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case homepageEntries.groupVersionPath():
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"resources": []interface{}{map[string]interface{}{"name": "bookmarks", "kind": "Bookmark"}},
+			})
+		case homepageEntries.groupVersionPath() + "/bookmarks":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": []interface{}{map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "nas"},
+				"spec":     map[string]interface{}{"url": "https://nas.example.com"},
+			}}})
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+
+	result, err := fetchHomepageEntries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := result["items"].([]interface{}); len(items) != 1 {
+		t.Fatalf("expected the entry listed through the resolved resource, got %v", items)
+	}
+}
+
+func TestSummarizeHomepageEntriesFilters(t *testing.T) {
+	entry := func(name, rawURL string, show bool) interface{} {
+		metadata := map[string]interface{}{"namespace": "links", "name": name}
+		if show {
+			metadata["annotations"] = map[string]interface{}{showAnnotation: "true"}
+		}
+		return map[string]interface{}{"metadata": metadata, "spec": map[string]interface{}{"url": rawURL}}
+	}
+	result := map[string]interface{}{"items": []interface{}{
+		entry("wiki", "https://wiki.example.com", true),
+		entry("nas", "https://nas.internal.local", true),
+		entry("blog", "https://blog.example.com", false),
+	}}
+
+	setFlags(t, func(f *featureFlags) {
+		f.optInOnly = true
+		f.hiddenHostPatterns = parseHostPatterns("*.internal.local")
+	})
+	got := summarizeHomepageEntries(result)
+	if len(got) != 1 || got[0].Name != "wiki" {
+		t.Fatalf("expected only the opted-in, unhidden entry, got %+v", got)
+	}
+}
+
+func TestFetchExtraSummaries(t *testing.T) {
+	homepageEntries.enabled = true
+	defer func() { homepageEntries.enabled = false }()
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveHomepageEntryDiscovery(w, r) {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "nas"},
+					"spec":     map[string]interface{}{"url": "https://nas.example.com"},
+				},
+			},
+		})
+	}))
+
+	extra := fetchExtraSummaries(context.Background())
+	if len(extra) != 1 || extra[0].URL != "https://nas.example.com" {
+		t.Fatalf("expected nas entry, got %+v", extra)
+	}
+
+	summary := summarizeIngresses(context.Background(), map[string]interface{}{"items": []interface{}{testIngress("default", "app", "app.example.com")}}, extra)
+	if summary.Count != 2 {
+		t.Fatalf("expected merged count of 2, got %d", summary.Count)
+	}
+}
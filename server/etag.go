@@ -0,0 +1,59 @@
+package main
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// weakETag returns a weak validator for an ingress list resourceVersion, or
+// "" when the list has none (such as one merged from several namespaces).
+// It is weak because the same list is served with different encodings and
+// key order.
+func weakETag(resourceVersion string) string {
+	if resourceVersion == "" {
+		return ""
+	}
+	return `W/"` + resourceVersion + `"`
+}
+
+// weakContentETag extends the resourceVersion validator with a hash of body,
+// for formats that also depend on favorites, health checks and other sources
+// that can change while the ingress list does not.
+func weakContentETag(resourceVersion string, body []byte) string {
+	if resourceVersion == "" {
+		return ""
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(body)
+	return `W/"` + resourceVersion + "-" + strconv.FormatUint(h.Sum64(), 36) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag using the
+// weak comparison RFC 9110 requires for it.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNotModified sets the ETag header and answers 304 when the request's
+// If-None-Match matches it. Headers set before the call, such as
+// Cache-Control, are sent with the 304.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	if header := r.Header.Get("If-None-Match"); header == "" || !etagMatches(header, etag) {
+		return false
+	}
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
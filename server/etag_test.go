@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleIngressesWeakETag(t *testing.T) {
+	ingressesCache.reset()
+	defer ingressesCache.reset()
+	resourceVersion := "100"
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"metadata": map[string]interface{}{"resourceVersion": resourceVersion},
+			"items":    []interface{}{testIngress("default", "app", "app.example.com")},
+		})
+	}))
+
+	get := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		handleIngresses(time.Second).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/ingresses", "")
+	if got := rr.Header().Get("ETag"); got != `W/"100"` {
+		t.Fatalf("expected a weak ETag from the resourceVersion, got %q", got)
+	}
+	rr = get("/api/ingresses", `"99", W/"100"`)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Fatalf("expected an empty 304, got %d %q", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Cache-Control") == "" {
+		t.Error("expected Cache-Control on the 304")
+	}
+
+	summaryETag := get("/api/ingresses?format=summary", "").Header().Get("ETag")
+	if summaryETag == "" || summaryETag == `W/"100"` {
+		t.Fatalf("expected the summary ETag to cover more than the resourceVersion, got %q", summaryETag)
+	}
+	if rr := get("/api/ingresses?format=summary", summaryETag); rr.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for an unchanged summary, got %d", rr.Code)
+	}
+
+	resourceVersion = "101"
+	ingressesCache.reset()
+	if rr := get("/api/ingresses", `W/"100"`); rr.Code != http.StatusOK || rr.Header().Get("ETag") != `W/"101"` {
+		t.Fatalf("expected a full response with the new ETag, got %d %q", rr.Code, rr.Header().Get("ETag"))
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   bool
+	}{
+		{`W/"7"`, true},
+		{`"7"`, true},
+		{`*`, true},
+		{`"6", "8"`, false},
+		{`W/"70"`, false},
+	} {
+		if got := etagMatches(tc.header, `W/"7"`); got != tc.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxFavorites = 1000
+
+var errTooManyFavorites = fmt.Errorf("at most %d favorites are allowed", maxFavorites)
+
+// favorites is the server-side favorites store; it is nil unless
+// FAVORITES_FILE is set.
+var favorites *favoriteStore
+
+// favoriteStore keeps favorited tile identifiers ("namespace/name") for each
+// identity in memory and persists every change to a JSON file. Anonymous
+// requests share the "" identity. The mutex serializes writers so concurrent
+// updates cannot lose each other's changes.
+type favoriteStore struct {
+	mu    sync.Mutex
+	path  string
+	users map[string]map[string]bool
+}
+
+// favoritesFile is the /api/favorites response body.
+type favoritesFile struct {
+	Favorites []string `json:"favorites"`
+}
+
+// favoritesStoreFile is the persisted store. The anonymous favorites stay
+// under "favorites", so files written before favorites were per user still
+// load.
+type favoritesStoreFile struct {
+	Favorites []string            `json:"favorites,omitempty"`
+	Users     map[string][]string `json:"users,omitempty"`
+}
+
+// loadFavoriteStore opens the store at path; a missing file is an empty
+// store.
+func loadFavoriteStore(path string) (*favoriteStore, error) {
+	store := &favoriteStore{path: path, users: make(map[string]map[string]bool)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file favoritesStoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	store.add("", file.Favorites)
+	for identity, ids := range file.Users {
+		if identity != "" {
+			store.add(identity, ids)
+		}
+	}
+	return store, nil
+}
+
+func (s *favoriteStore) add(identity string, ids []string) {
+	for _, id := range ids {
+		if !isValidFavoriteID(id) {
+			continue
+		}
+		if s.users[identity] == nil {
+			s.users[identity] = make(map[string]bool)
+		}
+		s.users[identity][id] = true
+	}
+}
+
+func isValidFavoriteID(id string) bool {
+	namespace, name, ok := strings.Cut(id, "/")
+	return ok && namespace != "" && name != "" && !strings.Contains(name, "/")
+}
+
+func favoriteID(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func (s *favoriteStore) has(identity, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[identity][id]
+}
+
+func (s *favoriteStore) list(identity string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedFavorites(s.users[identity])
+}
+
+func sortedFavorites(set map[string]bool) []string {
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// set marks or unmarks id for identity and persists the result. The
+// in-memory state only changes once the file has been written.
+func (s *favoriteStore) set(identity, id string, favorite bool) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.users[identity]
+	if current[id] == favorite {
+		return sortedFavorites(current), nil
+	}
+	if favorite && len(current) >= maxFavorites {
+		return nil, errTooManyFavorites
+	}
+
+	next := make(map[string]bool, len(current)+1)
+	for existing := range current {
+		next[existing] = true
+	}
+	if favorite {
+		next[id] = true
+	} else {
+		delete(next, id)
+	}
+
+	s.store(identity, next)
+	if err := writeFavoritesFile(s.path, s.fileLocked()); err != nil {
+		s.store(identity, current)
+		return nil, err
+	}
+	return sortedFavorites(next), nil
+}
+
+func (s *favoriteStore) store(identity string, ids map[string]bool) {
+	if len(ids) == 0 {
+		delete(s.users, identity)
+		return
+	}
+	s.users[identity] = ids
+}
+
+func (s *favoriteStore) fileLocked() favoritesStoreFile {
+	file := favoritesStoreFile{Favorites: sortedFavorites(s.users[""])}
+	for identity, ids := range s.users {
+		if identity == "" {
+			continue
+		}
+		if file.Users == nil {
+			file.Users = make(map[string][]string)
+		}
+		file.Users[identity] = sortedFavorites(ids)
+	}
+	return file
+}
+
+// writeFavoritesFile writes the favorites to a temporary file and renames it
+// over path, so a crash mid-write never leaves a truncated file behind.
+func writeFavoritesFile(path string, file favoritesStoreFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// markFavorites flags summaries whose identifier is among the favorites of
+// the request's identity.
+func markFavorites(ctx context.Context, summaries []ingressSummary) {
+	if favorites == nil {
+		return
+	}
+	identity := requestIdentity(ctx)
+	for i := range summaries {
+		summaries[i].IsFavorite = favorites.has(identity, favoriteID(summaries[i].Namespace, summaries[i].Name))
+	}
+}
+
+type favoriteRequest struct {
+	ID       string `json:"id"`
+	Favorite *bool  `json:"favorite"`
+}
+
+// handleFavorites lists the caller's favorites on GET and adds or removes one
+// on POST with a body of {"id": "namespace/name", "favorite": true|false}.
+// With AUTH_PROXY_HEADER set, anonymous callers cannot change favorites, so
+// they cannot edit the shared anonymous list from outside the proxy.
+func handleFavorites(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	identity := requestIdentity(r.Context())
+
+	if r.Method == http.MethodPost {
+		if identity == "" && authProxyHeader != "" {
+			localizedError(w, r, msgUnauthorized, http.StatusUnauthorized)
+			return
+		}
+		var req favoriteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Favorite == nil || !isValidFavoriteID(req.ID) {
+			localizedError(w, r, msgInvalidFavorite, http.StatusBadRequest)
+			return
+		}
+
+		ids, err := favorites.set(identity, req.ID, *req.Favorite)
+		if errors.Is(err, errTooManyFavorites) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Error saving favorites: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(favoritesFile{Favorites: ids})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(favoritesFile{Favorites: favorites.list(identity)})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFavoriteStorePersistsConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "favorites.json")
+	store, err := loadFavoriteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := store.set("", favoriteID("default", "app-"+string(rune('a'+i))), true); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	reloaded, err := loadFavoriteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reloaded.list("")); got != 20 {
+		t.Fatalf("expected 20 persisted favorites, got %d", got)
+	}
+
+	if _, err := store.set("", "default/app-a", false); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, _ = loadFavoriteStore(path)
+	if reloaded.has("", "default/app-a") || !reloaded.has("", "default/app-b") {
+		t.Fatalf("unexpected favorites after removal: %v", reloaded.list(""))
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	if len(matches) != 0 {
+		t.Fatalf("expected temporary files to be cleaned up, got %v", matches)
+	}
+}
+
+func TestHandleFavorites(t *testing.T) {
+	prev := favorites
+	defer func() { favorites = prev }()
+	store, err := loadFavoriteStore(filepath.Join(t.TempDir(), "favorites.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	favorites = store
+
+	rr := httptest.NewRecorder()
+	handleFavorites(rr, httptest.NewRequest(http.MethodPost, "/api/favorites", strings.NewReader(`{"id": "monitoring/grafana", "favorite": true}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for _, body := range []string{`{"id": "grafana", "favorite": true}`, `{"id": "monitoring/grafana"}`, `not json`} {
+		rr = httptest.NewRecorder()
+		handleFavorites(rr, httptest.NewRequest(http.MethodPost, "/api/favorites", strings.NewReader(body)))
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, rr.Code)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	handleFavorites(rr, httptest.NewRequest(http.MethodGet, "/api/favorites", nil))
+	var listed favoritesFile
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil || len(listed.Favorites) != 1 || listed.Favorites[0] != "monitoring/grafana" {
+		t.Fatalf("unexpected favorites %s (%v)", rr.Body.String(), err)
+	}
+
+	response := summarizeIngresses(context.Background(), map[string]interface{}{"items": []interface{}{
+		testIngress("default", "app"),
+		testIngress("monitoring", "grafana"),
+	}}, nil)
+	if first := response.Ingresses[0]; first.Name != "grafana" || !first.IsFavorite {
+		t.Fatalf("expected the favorite to sort first, got %+v", first)
+	}
+	if response.Ingresses[1].IsFavorite {
+		t.Fatal("expected non-favorites to be unflagged")
+	}
+}
+
+func TestFavoritesArePerIdentity(t *testing.T) {
+	prevStore, prevHeader, prevProxies := favorites, authProxyHeader, authTrustedProxies
+	defer func() { favorites, authProxyHeader, authTrustedProxies = prevStore, prevHeader, prevProxies }()
+	path := filepath.Join(t.TempDir(), "favorites.json")
+	if err := os.WriteFile(path, []byte(`{"favorites": ["default/shared"]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := loadFavoriteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	favorites = store
+	authProxyHeader = "X-Forwarded-User"
+	authTrustedProxies = parseTrustedProxies("192.0.2.1")
+	handler := withProxyIdentity(http.HandlerFunc(handleFavorites))
+
+	post := func(user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/favorites", strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:1234"
+		if user != "" {
+			req.Header.Set("X-Forwarded-User", user)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := post("alice", `{"id": "monitoring/grafana", "favorite": true}`); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := post("", `{"id": "monitoring/grafana", "favorite": true}`); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous writes to be refused, got %d", rr.Code)
+	}
+
+	if !store.has("alice", "monitoring/grafana") || store.has("bob", "monitoring/grafana") || store.has("", "monitoring/grafana") {
+		t.Fatalf("expected only alice to have the favorite")
+	}
+	ctx := context.WithValue(context.Background(), identityContextKey{}, "bob")
+	response := summarizeIngresses(ctx, map[string]interface{}{"items": []interface{}{testIngress("monitoring", "grafana")}}, nil)
+	if response.Ingresses[0].IsFavorite {
+		t.Fatal("expected bob not to see alice's favorite")
+	}
+
+	reloaded, err := loadFavoriteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.has("", "default/shared") || !reloaded.has("alice", "monitoring/grafana") {
+		t.Fatalf("expected both the legacy and per-user favorites to persist, got %+v", reloaded.users)
+	}
+}
+
+func TestLoadFavoriteStoreRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "favorites.json")
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFavoriteStore(path); err == nil {
+		t.Fatal("expected a corrupt file to fail")
+	}
+}
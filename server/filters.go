@@ -0,0 +1,137 @@
+package main
+
+import (
+	"log"
+	"path"
+	"strings"
+)
+
+const (
+	annotationPrefix = "home-pager.io/"
+	showAnnotation   = annotationPrefix + "show"
+)
+
+// defaultExcludedNamespaces are the namespaces Kubernetes itself manages;
+// nothing in them belongs on a home page.
+const defaultExcludedNamespaces = "kube-system,kube-public,kube-node-lease"
+
+// parseNameSet splits a comma-separated list of names, such as namespaces or
+// ingress classes, into a set.
+func parseNameSet(raw string) map[string]bool {
+	namespaces := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		if namespace := strings.TrimSpace(part); namespace != "" {
+			namespaces[namespace] = true
+		}
+	}
+	return namespaces
+}
+
+// parseHostPatterns splits a comma-separated list of host globs, lowercasing
+// each pattern and dropping any that path.Match rejects as malformed.
+func parseHostPatterns(raw string) []string {
+	var patterns []string
+	for _, part := range strings.Split(raw, ",") {
+		pattern := strings.ToLower(strings.TrimSpace(part))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Printf("Warning: ignoring invalid host pattern %q: %v", pattern, err)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+func matchesAnyHostPattern(host string, patterns []string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ingressHosts returns the hosts declared by a listed object; see specHosts.
+func ingressHosts(item map[string]interface{}) []string {
+	spec, _ := item["spec"].(map[string]interface{})
+	return specHosts(spec)
+}
+
+// ingressAnnotations returns the annotations of an ingress, or nil when absent.
+func ingressAnnotations(item map[string]interface{}) map[string]interface{} {
+	metadata, _ := item["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	return annotations
+}
+
+func isOptedIn(item map[string]interface{}) bool {
+	value, _ := ingressAnnotations(item)[showAnnotation].(string)
+	return strings.EqualFold(strings.TrimSpace(value), "true")
+}
+
+// isHiddenIngress reports whether every host of the ingress matches one of the
+// hidden host patterns. Ingresses without any host are never hidden.
+func isHiddenIngress(item map[string]interface{}, patterns []string) bool {
+	if len(patterns) == 0 {
+		return false
+	}
+	return hostsHidden(ingressHosts(item), patterns)
+}
+
+// hostsHidden reports whether every host matches one of the hidden host
+// patterns. An empty host list is never hidden.
+func hostsHidden(hosts []string, patterns []string) bool {
+	if len(patterns) == 0 || len(hosts) == 0 {
+		return false
+	}
+
+	for _, host := range hosts {
+		if !matchesAnyHostPattern(host, patterns) {
+			return false
+		}
+	}
+	return true
+}
+
+// isVisibleIngress reports whether an ingress passes the configured filters.
+// Excluded namespaces win over every other setting, including opt-in.
+func isVisibleIngress(item map[string]interface{}) bool {
+	flags := currentFlags()
+	metadata, _ := item["metadata"].(map[string]interface{})
+	if flags.excludedNamespaces[stringField(metadata, "namespace")] {
+		return false
+	}
+	if flags.optInOnly && !isOptedIn(item) {
+		return false
+	}
+	return !isHiddenIngress(item, flags.hiddenHostPatterns)
+}
+
+// filterIngresses returns a copy of an ingress list response without the items
+// that should not be shown on the dashboard. The input is left untouched so it
+// can be shared with the cache.
+func filterIngresses(result map[string]interface{}) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(result))
+	for key, value := range result {
+		filtered[key] = value
+	}
+
+	items, ok := result["items"].([]interface{})
+	if !ok {
+		return filtered
+	}
+
+	kept := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		if isVisibleIngress(itemMap) {
+			kept = append(kept, item)
+		}
+	}
+	filtered["items"] = kept
+	return filtered
+}
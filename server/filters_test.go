@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+func testIngress(namespace, name string, hosts ...string) map[string]interface{} {
+	rules := make([]interface{}, 0, len(hosts))
+	for _, host := range hosts {
+		rules = append(rules, map[string]interface{}{"host": host})
+	}
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": namespace, "name": name},
+		"spec":     map[string]interface{}{"rules": rules},
+	}
+}
+
+func TestParseHostPatterns(t *testing.T) {
+	got := parseHostPatterns(" *.Internal.Local , ,admin.example.com,[bad")
+	want := []string{"*.internal.local", "admin.example.com"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestIsHiddenIngress(t *testing.T) {
+	patterns := parseHostPatterns("*.internal.local,admin.example.com")
+
+	cases := []struct {
+		name   string
+		hosts  []string
+		hidden bool
+	}{
+		{"no hosts", nil, false},
+		{"single matching host", []string{"grafana.internal.local"}, true},
+		{"case insensitive", []string{"ADMIN.Example.com"}, true},
+		{"all hosts match", []string{"a.internal.local", "admin.example.com"}, true},
+		{"one host visible", []string{"a.internal.local", "app.example.com"}, false},
+		{"no match", []string{"app.example.com"}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isHiddenIngress(testIngress("default", "app", tc.hosts...), patterns); got != tc.hidden {
+				t.Fatalf("expected hidden=%v, got %v", tc.hidden, got)
+			}
+		})
+	}
+}
+
+func TestFilterIngressesHiddenHosts(t *testing.T) {
+	setFlags(t, func(f *featureFlags) { f.hiddenHostPatterns = parseHostPatterns("*.internal.local") })
+
+	result := map[string]interface{}{
+		"items": []interface{}{
+			testIngress("default", "visible", "app.example.com"),
+			testIngress("default", "hidden", "admin.internal.local"),
+		},
+	}
+	items := filterIngresses(result)["items"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item after filtering, got %d", len(items))
+	}
+}
+
+func TestFilterIngressesExcludedNamespaces(t *testing.T) {
+	setFlags(t, func(f *featureFlags) { f.optInOnly = true })
+
+	system := testIngress("kube-system", "dashboard", "dashboard.example.com")
+	system["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{showAnnotation: "true"}
+	app := testIngress("default", "app", "app.example.com")
+	app["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{showAnnotation: "true"}
+
+	result := map[string]interface{}{"items": []interface{}{system, app}}
+	items := filterIngresses(result)["items"].([]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["metadata"].(map[string]interface{})["namespace"] != "default" {
+		t.Fatalf("expected kube-system to be excluded even when opted in, got %v", items)
+	}
+
+	t.Setenv("EXCLUDE_NAMESPACES", "")
+	setFlags(t, func(f *featureFlags) { f.excludedNamespaces = loadFeatureFlags(nil).excludedNamespaces })
+	if items := filterIngresses(result)["items"].([]interface{}); len(items) != 2 {
+		t.Fatalf("expected an empty EXCLUDE_NAMESPACES to show every namespace, got %d items", len(items))
+	}
+}
+
+func TestFilterIngressesOptInOnly(t *testing.T) {
+	setFlags(t, func(f *featureFlags) { f.optInOnly = true })
+
+	shown := testIngress("default", "shown", "app.example.com")
+	shown["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{showAnnotation: "true"}
+	notShown := testIngress("default", "not-shown", "other.example.com")
+	notShown["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{showAnnotation: "false"}
+
+	result := map[string]interface{}{
+		"items": []interface{}{shown, notShown, testIngress("default", "unannotated", "x.example.com")},
+	}
+	items := filterIngresses(result)["items"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("expected only the opted-in ingress, got %d items", len(items))
+	}
+	name := items[0].(map[string]interface{})["metadata"].(map[string]interface{})["name"]
+	if name != "shown" {
+		t.Fatalf("expected shown ingress, got %v", name)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// featureFlags are the settings that only shape responses, so they can change
+// without rebinding the listener. They are read from FLAGS_FILE, falling back
+// to the environment, and re-read on SIGHUP.
+type featureFlags struct {
+	hiddenHostPatterns []string
+	optInOnly          bool
+	excludedNamespaces map[string]bool
+
+	// internalIngressClasses and publicIngressClasses decide the visibility
+	// of ingresses without a visibility annotation.
+	internalIngressClasses map[string]bool
+	publicIngressClasses   map[string]bool
+
+	// dedupeHosts collapses summaries that share a primary host into one entry.
+	dedupeHosts bool
+
+	// dedupePaths keeps only the first backend for each host, path and path
+	// type of an ingress, which matches the one the controller routes to.
+	dedupePaths bool
+
+	// deprecateRaw marks raw-format responses as deprecated in favour of the
+	// summary format. The raw format keeps working.
+	deprecateRaw bool
+
+	// forceHTTPS links every host over https, for clusters where TLS is
+	// terminated outside the ingress.
+	forceHTTPS bool
+
+	// maintenance forces maintenance mode on.
+	maintenance bool
+}
+
+var (
+	defaultFeatureFlags = featureFlags{
+		excludedNamespaces: parseNameSet(defaultExcludedNamespaces),
+	}
+
+	activeFlags atomic.Pointer[featureFlags]
+)
+
+// currentFlags returns the flags in effect. Callers must not modify them.
+func currentFlags() *featureFlags {
+	if flags := activeFlags.Load(); flags != nil {
+		return flags
+	}
+	return &defaultFeatureFlags
+}
+
+// flagSource looks a flag up in FLAGS_FILE first, then in the environment.
+type flagSource map[string]string
+
+func (s flagSource) lookup(name string) (string, bool) {
+	if value, ok := s[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+func (s flagSource) get(name string) string {
+	value, _ := s.lookup(name)
+	return value
+}
+
+func (s flagSource) bool(name string, fallback bool) bool {
+	parsed, err := strconv.ParseBool(strings.TrimSpace(s.get(name)))
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// loadFeatureFlags parses every feature flag from source.
+func loadFeatureFlags(source flagSource) *featureFlags {
+	excluded, ok := source.lookup("EXCLUDE_NAMESPACES")
+	if !ok {
+		excluded = defaultExcludedNamespaces
+	}
+	return &featureFlags{
+		hiddenHostPatterns:     parseHostPatterns(source.get("HIDDEN_HOSTS")),
+		optInOnly:              source.bool("OPT_IN_ONLY", false),
+		excludedNamespaces:     parseNameSet(excluded),
+		internalIngressClasses: parseNameSet(source.get("INTERNAL_INGRESS_CLASSES")),
+		publicIngressClasses:   parseNameSet(source.get("PUBLIC_INGRESS_CLASSES")),
+		dedupeHosts:            source.bool("DEDUPE_HOSTS", false),
+		dedupePaths:            source.bool("DEDUPE_PATHS", false),
+		deprecateRaw:           source.bool("DEPRECATE_RAW", false),
+		forceHTTPS:             source.bool("FORCE_HTTPS", false),
+		maintenance:            source.bool("MAINTENANCE", false),
+	}
+}
+
+// readFlagsFile parses a file of NAME=value lines, as written for a
+// ConfigMap mounted as a file. Blank lines and lines starting with # are
+// skipped.
+func readFlagsFile(path string) (flagSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	source := make(flagSource)
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("line %d: expected NAME=value", number)
+		}
+		source[name] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// reloadFeatureFlags re-reads the flags from path, or from the environment
+// alone when path is empty. A file that cannot be read leaves the previous
+// flags in place.
+func reloadFeatureFlags(path string) error {
+	source := flagSource{}
+	if path != "" {
+		var err error
+		source, err = readFlagsFile(path)
+		recordConfigReload(err)
+		if err != nil {
+			return err
+		}
+	}
+	activeFlags.Store(loadFeatureFlags(source))
+	return nil
+}
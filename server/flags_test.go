@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// setFlags applies change to a copy of the current flags until the test ends.
+func setFlags(t *testing.T, change func(*featureFlags)) {
+	t.Helper()
+	prev := activeFlags.Load()
+	next := *currentFlags()
+	change(&next)
+	activeFlags.Store(&next)
+	t.Cleanup(func() { activeFlags.Store(prev) })
+}
+
+func TestLoadFeatureFlagsPrefersFlagsFile(t *testing.T) {
+	t.Setenv("OPT_IN_ONLY", "true")
+	t.Setenv("DEDUPE_HOSTS", "true")
+	flags := loadFeatureFlags(flagSource{"DEDUPE_HOSTS": "false", "HIDDEN_HOSTS": "*.lan"})
+	if !flags.optInOnly {
+		t.Error("expected OPT_IN_ONLY from the environment")
+	}
+	if flags.dedupeHosts {
+		t.Error("expected FLAGS_FILE to override DEDUPE_HOSTS")
+	}
+	if len(flags.hiddenHostPatterns) != 1 || flags.hiddenHostPatterns[0] != "*.lan" {
+		t.Errorf("unexpected hidden hosts %v", flags.hiddenHostPatterns)
+	}
+	if !flags.excludedNamespaces["kube-system"] || flags.dedupePaths {
+		t.Errorf("expected unset flags to keep their defaults, got %+v", flags)
+	}
+}
+
+func TestFlagsFileReloadsOnSIGHUP(t *testing.T) {
+	resetMetrics()
+	defer resetMetrics()
+	prevFlags := activeFlags.Load()
+	configReloadersMu.Lock()
+	prevReloaders := configReloaders
+	configReloaders = nil
+	configReloadersMu.Unlock()
+	defer func() {
+		activeFlags.Store(prevFlags)
+		configReloadersMu.Lock()
+		configReloaders = prevReloaders
+		configReloadersMu.Unlock()
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "flags.env")
+	writeTestFile(t, dir, "flags.env", "# home-pager flags\nMAINTENANCE=false\nHIDDEN_HOSTS=*.lan\n")
+	if err := reloadFeatureFlags(path); err != nil {
+		t.Fatal(err)
+	}
+	registerConfigReloader("FLAGS_FILE", func() error { return reloadFeatureFlags(path) })
+	if inMaintenance() {
+		t.Fatal("expected maintenance off")
+	}
+
+	writeTestFile(t, dir, "flags.env", "MAINTENANCE=true\n")
+	if err := reloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if !inMaintenance() || len(currentFlags().hiddenHostPatterns) != 0 {
+		t.Fatalf("expected the reload to apply the new flags, got %+v", currentFlags())
+	}
+
+	writeTestFile(t, dir, "flags.env", "MAINTENANCE\n")
+	if err := reloadConfig(); err == nil {
+		t.Fatal("expected a malformed line to fail the reload")
+	}
+	if !inMaintenance() {
+		t.Fatal("expected a failed reload to keep the previous flags")
+	}
+}
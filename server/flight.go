@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// flightTimeout bounds a shared call, which has no caller deadline of its
+// own; main sets it to KUBERNETES_TIMEOUT.
+var flightTimeout = defaultHTTPTimeout
+
+// flightGroup coalesces concurrent calls with the same key into a single
+// execution whose result is shared by every caller.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done   chan struct{}
+	result map[string]interface{}
+	err    error
+}
+
+// do runs fn once per key at a time. The shared call is detached from the
+// caller's cancellation so one impatient caller cannot fail the others, and
+// bounded by flightTimeout instead; each caller still stops waiting when its
+// own context is done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		call = &flightCall{done: make(chan struct{})}
+		g.calls[key] = call
+		callCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), flightTimeout)
+		go func() {
+			defer cancel()
+			g.run(callCtx, key, call, fn)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, call *flightCall, fn func(context.Context) (map[string]interface{}, error)) {
+	call.result, call.err = fn(ctx)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHealthCheckInterval    = time.Minute
+	defaultHealthCheckTimeout     = 5 * time.Second
+	defaultHealthCheckConcurrency = 4
+	defaultHealthThreshold        = 1
+
+	healthUp   = "up"
+	healthDown = "down"
+)
+
+// healthChecks probes every tile URL in the background when HEALTH_CHECKS is
+// enabled; nil disables the feature.
+var healthChecks *healthChecker
+
+// healthChecker spreads one probe per target evenly across each interval
+// rather than firing them all at once, and never runs more than cap(slots)
+// probes concurrently, so hundreds of tiles do not spike CPU and network on
+// the node or load on the probed services. Each probe also runs through
+// backgroundPool, so probes share WORKER_POOL_SIZE with the other background
+// tasks and cap(slots) only keeps them from taking over the whole pool.
+type healthChecker struct {
+	client   *http.Client
+	interval time.Duration
+	timeout  time.Duration
+	slots    chan struct{}
+
+	// failureThreshold and successThreshold are the consecutive probe
+	// results needed to mark a target down or back up, so one transient
+	// failure does not flap its tile.
+	failureThreshold int
+	successThreshold int
+
+	mu      sync.Mutex
+	status  map[string]string
+	streaks map[string]healthStreak
+
+	targets     atomic.Int64
+	checked     atomic.Int64
+	lastRunEnd  atomic.Int64
+	lastRunTook atomic.Int64
+}
+
+func newHealthChecker(interval, timeout time.Duration, concurrency int) *healthChecker {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	if concurrency <= 0 {
+		concurrency = defaultHealthCheckConcurrency
+	}
+	return &healthChecker{
+		client:   &http.Client{Timeout: timeout},
+		interval: interval,
+		timeout:  timeout,
+		slots:    make(chan struct{}, concurrency),
+		status:   make(map[string]string),
+		streaks:  make(map[string]healthStreak),
+
+		failureThreshold: defaultHealthThreshold,
+		successThreshold: defaultHealthThreshold,
+	}
+}
+
+// healthStreak counts consecutive identical probe results for a target.
+type healthStreak struct {
+	result string
+	count  int
+}
+
+func (s healthStreak) observe(result string) healthStreak {
+	if s.result != result {
+		return healthStreak{result: result, count: 1}
+	}
+	s.count++
+	return s
+}
+
+// loadHealthChecker reads HEALTH_CHECKS and its tuning variables.
+func loadHealthChecker() *healthChecker {
+	if !getEnvBool("HEALTH_CHECKS", false) {
+		return nil
+	}
+	c := newHealthChecker(
+		getEnvDuration("HEALTH_CHECK_INTERVAL", defaultHealthCheckInterval),
+		getEnvDuration("HEALTH_CHECK_TIMEOUT", defaultHealthCheckTimeout),
+		int(getEnvInt64("HEALTH_CHECK_CONCURRENCY", defaultHealthCheckConcurrency)),
+	)
+	c.failureThreshold = int(getEnvInt64("HEALTH_FAILURE_THRESHOLD", defaultHealthThreshold))
+	c.successThreshold = int(getEnvInt64("HEALTH_SUCCESS_THRESHOLD", defaultHealthThreshold))
+	return c
+}
+
+// run checks the current tiles every interval until ctx is cancelled.
+func (c *healthChecker) run(ctx context.Context, timeout time.Duration) {
+	h := registerHeartbeat("healthChecks", c.interval+timeout+c.timeout)
+	defer h.stop()
+
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+		targets, err := healthCheckTargets(fetchCtx)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error listing health check targets: %v", err)
+		}
+
+		start := time.Now()
+		c.runOnce(ctx, targets, h)
+		if ctx.Err() != nil {
+			return
+		}
+		h.beat()
+
+		// runOnce takes about one interval, so the next run starts right away
+		// unless there was nothing to check.
+		timer := time.NewTimer(c.interval - time.Since(start))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// healthCheckTargets lists the distinct tile URLs of the summary response.
+func healthCheckTargets(ctx context.Context) ([]string, error) {
+	ingresses, err := ingressesCache.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var targets []string
+	for _, summary := range summarizeIngresses(ctx, ingresses, fetchExtraSummaries(ctx)).Ingresses {
+		if summary.URL != "" && !seen[summary.URL] {
+			seen[summary.URL] = true
+			targets = append(targets, summary.URL)
+		}
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+// runOnce probes targets, starting probe i at i/len(targets) of the interval.
+// Results for URLs that are no longer targets are dropped.
+func (c *healthChecker) runOnce(ctx context.Context, targets []string, h *heartbeat) {
+	start := time.Now()
+	c.targets.Store(int64(len(targets)))
+	c.checked.Store(0)
+
+	results := make(map[string]string, len(targets))
+	var resultsMu sync.Mutex
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		if !sleepUntil(ctx, start.Add(c.interval*time.Duration(i)/time.Duration(len(targets)))) {
+			break
+		}
+		acquired := false
+		select {
+		case c.slots <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		if !acquired {
+			break
+		}
+		if h != nil {
+			h.beat()
+		}
+
+		wg.Add(1)
+		go func(target string) {
+			defer func() {
+				<-c.slots
+				c.checked.Add(1)
+				wg.Done()
+			}()
+			_ = backgroundPool.do(ctx, func() {
+				health := c.probe(ctx, target)
+				resultsMu.Lock()
+				results[target] = health
+				resultsMu.Unlock()
+			})
+		}(target)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.apply(results)
+	c.mu.Unlock()
+	c.lastRunEnd.Store(time.Now().Unix())
+	c.lastRunTook.Store(int64(time.Since(start)))
+}
+
+// apply updates each target's health from one run's probe results. A target
+// changes to a result once it has been seen threshold times in a row; a new
+// target is marked up on its first success but only marked down after the
+// failure threshold, so it shows no health until then. c.mu must be held.
+func (c *healthChecker) apply(results map[string]string) {
+	status := make(map[string]string, len(results))
+	streaks := make(map[string]healthStreak, len(results))
+	for target, result := range results {
+		streak := c.streaks[target].observe(result)
+		streaks[target] = streak
+
+		health := c.status[target]
+		threshold := c.failureThreshold
+		if result == healthUp {
+			threshold = c.successThreshold
+		}
+		if (health == "" && result == healthUp) || streak.count >= threshold {
+			health = result
+		}
+		if health != "" {
+			status[target] = health
+		}
+	}
+	c.status = status
+	c.streaks = streaks
+}
+
+// sleepUntil waits for deadline, returning false if ctx is cancelled first.
+func sleepUntil(ctx context.Context, deadline time.Time) bool {
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// probe reports a target as up when it answers with any status below 500.
+func (c *healthChecker) probe(ctx context.Context, target string) string {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return healthDown
+	}
+	req.Header.Set("User-Agent", kubeUserAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return healthDown
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return healthDown
+	}
+	return healthUp
+}
+
+func (c *healthChecker) health(target string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status[target]
+}
+
+// markHealth sets the health of each summary from the last completed run.
+func markHealth(summaries []ingressSummary) {
+	if healthChecks == nil {
+		return
+	}
+	for i := range summaries {
+		summaries[i].Health = healthChecks.health(summaries[i].URL)
+	}
+}
+
+func (c *healthChecker) write(w io.Writer) {
+	c.mu.Lock()
+	var up, down int
+	for _, health := range c.status {
+		if health == healthUp {
+			up++
+		} else {
+			down++
+		}
+	}
+	c.mu.Unlock()
+
+	_, _ = io.WriteString(w, "# HELP home_pager_health_check_targets Tile URLs probed in the current run.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_health_check_targets gauge\n")
+	_, _ = io.WriteString(w, "home_pager_health_check_targets "+strconv.FormatInt(c.targets.Load(), 10)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_health_check_progress Probes finished in the current run.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_health_check_progress gauge\n")
+	_, _ = io.WriteString(w, "home_pager_health_check_progress "+strconv.FormatInt(c.checked.Load(), 10)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_health_check_last_run_timestamp_seconds Unix time the last complete run finished.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_health_check_last_run_timestamp_seconds gauge\n")
+	_, _ = io.WriteString(w, "home_pager_health_check_last_run_timestamp_seconds "+strconv.FormatInt(c.lastRunEnd.Load(), 10)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_health_check_last_run_duration_seconds How long the last complete run took.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_health_check_last_run_duration_seconds gauge\n")
+	_, _ = io.WriteString(w, "home_pager_health_check_last_run_duration_seconds "+strconv.FormatFloat(time.Duration(c.lastRunTook.Load()).Seconds(), 'f', 3, 64)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_health_check_results Tile URLs by result of the last complete run.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_health_check_results gauge\n")
+	_, _ = io.WriteString(w, "home_pager_health_check_results{result=\"up\"} "+strconv.Itoa(up)+"\n")
+	_, _ = io.WriteString(w, "home_pager_health_check_results{result=\"down\"} "+strconv.Itoa(down)+"\n")
+}
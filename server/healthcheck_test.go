@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckerStaggersAndLimitsProbes(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	var active, maxActive atomic.Int64
+	handler := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			mu.Lock()
+			starts = append(starts, time.Now())
+			mu.Unlock()
+			if n := active.Add(1); n > maxActive.Load() {
+				maxActive.Store(n)
+			}
+			time.Sleep(20 * time.Millisecond)
+			active.Add(-1)
+			w.WriteHeader(status)
+		}
+	}
+	up := httptest.NewServer(handler(http.StatusOK))
+	defer up.Close()
+	notFound := httptest.NewServer(handler(http.StatusNotFound))
+	defer notFound.Close()
+	failing := httptest.NewServer(handler(http.StatusBadGateway))
+	defer failing.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	prevPool := backgroundPool
+	backgroundPool = newWorkerPool(2)
+	defer func() { backgroundPool = prevPool }()
+
+	checker := newHealthChecker(300*time.Millisecond, time.Second, 1)
+	checker.runOnce(context.Background(), []string{up.URL, notFound.URL, failing.URL, closed.URL}, nil)
+	if got := backgroundPool.completed.Load(); got != 4 {
+		t.Fatalf("expected every probe to run in the worker pool, got %d", got)
+	}
+
+	for target, want := range map[string]string{up.URL: healthUp, notFound.URL: healthUp, failing.URL: healthDown, closed.URL: healthDown} {
+		if got := checker.health(target); got != want {
+			t.Fatalf("%s: expected %s, got %q", target, want, got)
+		}
+	}
+	if maxActive.Load() != 1 {
+		t.Fatalf("expected at most one concurrent probe, got %d", maxActive.Load())
+	}
+	if len(starts) != 3 {
+		t.Fatalf("expected three probes to reach servers, got %d", len(starts))
+	}
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < 50*time.Millisecond {
+			t.Fatalf("expected probes to be spread across the interval, got a %v gap", gap)
+		}
+	}
+
+	var body strings.Builder
+	checker.write(&body)
+	for _, want := range []string{
+		"home_pager_health_check_targets 4\n",
+		"home_pager_health_check_progress 4\n",
+		`home_pager_health_check_results{result="down"} 2`,
+	} {
+		if !strings.Contains(body.String(), want) {
+			t.Fatalf("expected %q in metrics, got %q", want, body.String())
+		}
+	}
+}
+
+func TestMarkHealth(t *testing.T) {
+	prev := healthChecks
+	defer func() { healthChecks = prev }()
+
+	summaries := []ingressSummary{{URL: "https://a.example.com"}, {URL: "https://b.example.com"}}
+	healthChecks = nil
+	markHealth(summaries)
+	if summaries[0].Health != "" {
+		t.Fatalf("expected no health without HEALTH_CHECKS, got %q", summaries[0].Health)
+	}
+
+	healthChecks = newHealthChecker(0, 0, 0)
+	healthChecks.status = map[string]string{"https://a.example.com": healthDown}
+	markHealth(summaries)
+	if summaries[0].Health != healthDown || summaries[1].Health != "" {
+		t.Fatalf("unexpected health %+v", summaries)
+	}
+}
+
+func TestHealthCheckerThresholds(t *testing.T) {
+	checker := newHealthChecker(0, 0, 0)
+	checker.failureThreshold = 3
+	checker.successThreshold = 2
+	const target = "https://app.example.com"
+
+	for i, tc := range []struct {
+		result string
+		want   string
+	}{
+		{healthUp, healthUp},   // a new target is up on its first success
+		{healthDown, healthUp}, // one failure is not enough
+		{healthDown, healthUp}, // nor two
+		{healthUp, healthUp},   // a success resets the failure streak
+		{healthDown, healthUp},
+		{healthDown, healthUp},
+		{healthDown, healthDown}, // the third failure in a row marks it down
+		{healthUp, healthDown},   // one success is not enough to recover
+		{healthUp, healthUp},     // two are
+	} {
+		checker.apply(map[string]string{target: tc.result})
+		if got := checker.health(target); got != tc.want {
+			t.Fatalf("probe %d (%s): expected %s, got %q", i+1, tc.result, tc.want, got)
+		}
+	}
+
+	checker.apply(map[string]string{"https://new.example.com": healthDown})
+	if got := checker.health("https://new.example.com"); got != "" {
+		t.Fatalf("expected a new failing target to show no health before the threshold, got %q", got)
+	}
+	if got := checker.health(target); got != "" {
+		t.Fatalf("expected targets missing from a run to be dropped, got %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultWorkerWatchdogInterval = 10 * time.Second
+
+// heartbeatTimeout is how late a background loop's heartbeat may be before
+// /healthz fails so Kubernetes restarts the pod. Zero disables the check.
+var heartbeatTimeout time.Duration
+
+// heartbeat is updated by a background loop on every iteration. interval is
+// how often the loop is expected to beat.
+type heartbeat struct {
+	name     string
+	interval time.Duration
+	last     atomic.Int64
+}
+
+var heartbeats struct {
+	mu     sync.Mutex
+	active map[*heartbeat]struct{}
+}
+
+// registerHeartbeat starts tracking a background loop that beats at least
+// every interval. The loop calls stop when it exits normally.
+func registerHeartbeat(name string, interval time.Duration) *heartbeat {
+	h := &heartbeat{name: name, interval: interval}
+	h.beat()
+
+	heartbeats.mu.Lock()
+	defer heartbeats.mu.Unlock()
+	if heartbeats.active == nil {
+		heartbeats.active = make(map[*heartbeat]struct{})
+	}
+	heartbeats.active[h] = struct{}{}
+	return h
+}
+
+func (h *heartbeat) beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+func (h *heartbeat) stop() {
+	heartbeats.mu.Lock()
+	defer heartbeats.mu.Unlock()
+	delete(heartbeats.active, h)
+}
+
+// staleHeartbeats lists the loops that have not beaten within their interval
+// plus timeout.
+func staleHeartbeats(now time.Time, timeout time.Duration) []string {
+	heartbeats.mu.Lock()
+	defer heartbeats.mu.Unlock()
+
+	var stale []string
+	for h := range heartbeats.active {
+		if now.Sub(time.Unix(0, h.last.Load())) > h.interval+timeout {
+			stale = append(stale, h.name)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// heartbeatError describes stale heartbeats for /healthz, or returns "" when
+// the check is disabled or every loop is on time.
+func heartbeatError() string {
+	if heartbeatTimeout <= 0 {
+		return ""
+	}
+	stale := staleHeartbeats(time.Now(), heartbeatTimeout)
+	if len(stale) == 0 {
+		return ""
+	}
+	return "stale heartbeat: " + strings.Join(stale, ", ")
+}
+
+// watchWorkerPool beats every interval in which the background worker pool
+// was idle or finished a task, so the heartbeat goes stale only when tasks
+// hold every slot without completing. A pool that is merely busy still beats.
+func watchWorkerPool(ctx context.Context, interval time.Duration) {
+	h := registerHeartbeat("workerPool", interval)
+	defer h.stop()
+
+	pool := backgroundPool
+	last := pool.completed.Load()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			completed := pool.completed.Load()
+			if completed != last || pool.busy.Load() == 0 {
+				h.beat()
+			}
+			last = completed
+		}
+	}
+}
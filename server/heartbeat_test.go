@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthFailsWhenWorkerPoolIsWedged(t *testing.T) {
+	prevPool := backgroundPool
+	backgroundPool = newWorkerPool(1)
+	heartbeatTimeout = 50 * time.Millisecond
+	defer func() {
+		backgroundPool = prevPool
+		heartbeatTimeout = 0
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watchWorkerPool(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	health := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handleHealth(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rr
+	}
+	waitFor := func(code int) *httptest.ResponseRecorder {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			rr := health()
+			if rr.Code == code {
+				return rr
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected /healthz to return %d, got %d: %s", code, rr.Code, rr.Body.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitFor(http.StatusOK)
+
+	// A pool kept busy by tasks that finish is making progress.
+	stopBusy := make(chan struct{})
+	busyDone := make(chan struct{})
+	go func() {
+		defer close(busyDone)
+		for {
+			select {
+			case <-stopBusy:
+				return
+			default:
+				_ = backgroundPool.do(context.Background(), func() { time.Sleep(5 * time.Millisecond) })
+			}
+		}
+	}()
+	for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if rr := health(); rr.Code != http.StatusOK {
+			t.Fatalf("expected a busy but progressing pool to stay healthy, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	close(stopBusy)
+	<-busyDone
+
+	// Hold the only worker slot so the watchdog sees no progress.
+	release := make(chan struct{})
+	held := make(chan struct{})
+	go func() {
+		_ = backgroundPool.do(context.Background(), func() {
+			close(held)
+			<-release
+		})
+	}()
+	<-held
+
+	rr := waitFor(http.StatusServiceUnavailable)
+	if !strings.Contains(rr.Body.String(), "workerPool") {
+		t.Fatalf("expected the stale loop to be named, got %s", rr.Body.String())
+	}
+
+	close(release)
+	waitFor(http.StatusOK)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultLatencyBuckets mirrors the Prometheus client's default buckets.
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram is a minimal Prometheus-style histogram with fixed upper bounds.
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// write renders the histogram in the Prometheus text exposition format.
+func (h *histogram) write(w io.Writer, name, help string) {
+	writeHistogramHeader(w, name, help)
+	h.writeSeries(w, name, "")
+}
+
+func writeHistogramHeader(w io.Writer, name, help string) {
+	_, _ = io.WriteString(w, "# HELP "+name+" "+help+"\n")
+	_, _ = io.WriteString(w, "# TYPE "+name+" histogram\n")
+}
+
+// writeSeries renders the bucket, sum and count samples. labels, when set,
+// is a rendered label pair such as `class="api"` added to every sample.
+func (h *histogram) writeSeries(w io.Writer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	prefix := ""
+	suffix := ""
+	if labels != "" {
+		prefix = labels + ","
+		suffix = "{" + labels + "}"
+	}
+
+	for i, bound := range h.buckets {
+		_, _ = io.WriteString(w, name+"_bucket{"+prefix+"le=\""+strconv.FormatFloat(bound, 'g', -1, 64)+"\"} ")
+		_, _ = io.WriteString(w, strconv.FormatUint(h.counts[i], 10))
+		_, _ = io.WriteString(w, "\n")
+	}
+	_, _ = io.WriteString(w, name+"_bucket{"+prefix+"le=\"+Inf\"} ")
+	_, _ = io.WriteString(w, strconv.FormatUint(h.count, 10))
+	_, _ = io.WriteString(w, "\n")
+	_, _ = io.WriteString(w, name+"_sum"+suffix+" ")
+	_, _ = io.WriteString(w, strconv.FormatFloat(h.sum, 'f', -1, 64))
+	_, _ = io.WriteString(w, "\n")
+	_, _ = io.WriteString(w, name+"_count"+suffix+" ")
+	_, _ = io.WriteString(w, strconv.FormatUint(h.count, 10))
+	_, _ = io.WriteString(w, "\n")
+}
+
+// histogramVec is a set of histograms sharing bucket bounds, keyed by the
+// value of a single label.
+type histogramVec struct {
+	mu      sync.Mutex
+	label   string
+	buckets []float64
+	series  map[string]*histogram
+}
+
+func newHistogramVec(label string, buckets []float64) *histogramVec {
+	return &histogramVec{label: label, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+func (v *histogramVec) observe(labelValue string, value float64) {
+	v.mu.Lock()
+	h, ok := v.series[labelValue]
+	if !ok {
+		h = newHistogram(v.buckets)
+		v.series[labelValue] = h
+	}
+	v.mu.Unlock()
+
+	h.observe(value)
+}
+
+// write renders every series, sorted by label value for stable output.
+func (v *histogramVec) write(w io.Writer, name, help string) {
+	v.mu.Lock()
+	values := make([]string, 0, len(v.series))
+	for value := range v.series {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	series := make([]*histogram, len(values))
+	for i, value := range values {
+		series[i] = v.series[value]
+	}
+	v.mu.Unlock()
+
+	writeHistogramHeader(w, name, help)
+	for i, value := range values {
+		series[i].writeSeries(w, name, v.label+"=\""+value+"\"")
+	}
+}
+
+// parseBuckets parses a comma-separated list of bucket upper bounds, which
+// must be finite, positive and strictly ascending. The +Inf bucket is always
+// added when the histogram is written.
+func parseBuckets(raw string) ([]float64, error) {
+	var buckets []float64
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(bound) || math.IsInf(bound, 0) {
+			return nil, errors.New("bucket bounds must be finite")
+		}
+		if bound <= 0 {
+			return nil, errors.New("bucket bounds must be positive")
+		}
+		if len(buckets) > 0 && bound <= buckets[len(buckets)-1] {
+			return nil, errors.New("bucket bounds must be strictly ascending")
+		}
+		buckets = append(buckets, bound)
+	}
+	if len(buckets) == 0 {
+		return nil, errors.New("no bucket bounds given")
+	}
+	return buckets, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBuckets(t *testing.T) {
+	got, err := parseBuckets("0.01, 0.025,0.05")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[0] != 0.01 || got[2] != 0.05 {
+		t.Fatalf("unexpected buckets: %v", got)
+	}
+
+	for _, raw := range []string{"", "0.1,abc", "0,0.1", "-1", "0.5,0.1", "0.1,0.1", "NaN", "0.1,NaN", "+Inf", "0.1,Inf", "-Inf"} {
+		if _, err := parseBuckets(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestHistogramWrite(t *testing.T) {
+	h := newHistogram([]float64{0.1, 1})
+	h.observe(0.05)
+	h.observe(0.5)
+	h.observe(2)
+
+	var b strings.Builder
+	h.write(&b, "test_seconds", "Test histogram.")
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{le="0.1"} 1`,
+		`test_seconds_bucket{le="1"} 2`,
+		`test_seconds_bucket{le="+Inf"} 3`,
+		"test_seconds_sum 2.55",
+		"test_seconds_count 3",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
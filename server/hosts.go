@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+type hostIngress struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type hostEntry struct {
+	Host string `json:"host"`
+	// Ingresses lists every ingress routing the host; more than one
+	// is worth checking for a collision.
+	Ingresses []hostIngress `json:"ingresses"`
+}
+
+type hostsResponse struct {
+	Hosts []hostEntry `json:"hosts"`
+}
+
+// listHosts collects the distinct, lowercased hosts of every ingress in
+// result, sorted by host, each with the ingresses that declare it. Hidden,
+// excluded and opted-out ingresses are included: a DNS audit needs them all.
+func listHosts(result map[string]interface{}) hostsResponse {
+	items, _ := result["items"].([]interface{})
+
+	byHost := make(map[string][]hostIngress)
+	for _, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		metadata, _ := itemMap["metadata"].(map[string]interface{})
+		ingress := hostIngress{Namespace: stringField(metadata, "namespace"), Name: stringField(metadata, "name")}
+		seen := make(map[string]bool)
+		for _, host := range ingressHosts(itemMap) {
+			host = strings.ToLower(host)
+			if seen[host] {
+				continue
+			}
+			seen[host] = true
+			byHost[host] = append(byHost[host], ingress)
+		}
+	}
+
+	response := hostsResponse{Hosts: make([]hostEntry, 0, len(byHost))}
+	for host, ingresses := range byHost {
+		sort.Slice(ingresses, func(i, j int) bool {
+			if ingresses[i].Namespace != ingresses[j].Namespace {
+				return ingresses[i].Namespace < ingresses[j].Namespace
+			}
+			return ingresses[i].Name < ingresses[j].Name
+		})
+		response.Hosts = append(response.Hosts, hostEntry{Host: host, Ingresses: ingresses})
+	}
+	sort.Slice(response.Hosts, func(i, j int) bool {
+		return response.Hosts[i].Host < response.Hosts[j].Host
+	})
+	return response
+}
+
+// handleHosts lists the hosts of all ingresses, served from the
+// ingress cache.
+func handleHosts(timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		ingresses, err := ingressesCache.fetch(ctx)
+		if err != nil {
+			log.Printf("Error fetching ingresses: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", apiCacheControl)
+		_ = json.NewEncoder(w).Encode(listHosts(ingresses))
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHandleHosts(t *testing.T) {
+	setFlags(t, func(f *featureFlags) {
+		f.hiddenHostPatterns = []string{"*.internal"}
+		f.excludedNamespaces = map[string]bool{"kube-system": true}
+	})
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{
+				testIngress("web", "b", "shared.example.com", "b.example.com"),
+				testIngress("api", "a", "Shared.Example.com", "shared.example.com"),
+				testIngress("default", "nohost"),
+				testIngress("kube-system", "dashboard", "dash.internal"),
+			},
+		})
+	}))
+
+	rr := httptest.NewRecorder()
+	handleHosts(time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/hosts", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var payload hostsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json from /api/hosts: %v", err)
+	}
+	want := hostsResponse{Hosts: []hostEntry{
+		{Host: "b.example.com", Ingresses: []hostIngress{{Namespace: "web", Name: "b"}}},
+		{Host: "dash.internal", Ingresses: []hostIngress{{Namespace: "kube-system", Name: "dashboard"}}},
+		{Host: "shared.example.com", Ingresses: []hostIngress{{Namespace: "api", Name: "a"}, {Namespace: "web", Name: "b"}}},
+	}}
+	if !reflect.DeepEqual(payload, want) {
+		t.Fatalf("expected %+v, got %+v", want, payload)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultLanguage = "en"
+
+// Message keys for server-generated text, translated via locales/*.json.
+const (
+	msgCrossOriginRejected    = "cross_origin_rejected"
+	msgHeaderTooLarge         = "header_too_large"
+	msgInvalidFavorite        = "invalid_favorite"
+	msgInvalidResourceVersion = "invalid_resource_version"
+	msgInvalidTagFilter       = "invalid_tag_filter"
+	msgMaintenance            = "maintenance"
+	msgMethodNotAllowed       = "method_not_allowed"
+	msgMissingHost            = "missing_host"
+	msgNotFound               = "not_found"
+	msgRequestTooLarge        = "request_too_large"
+	msgRequestTimeout         = "request_timeout"
+	msgTooManyStreams         = "too_many_streams"
+	msgUnauthorized           = "unauthorized"
+	msgUnsupportedFormat      = "unsupported_format"
+	msgWatchUnavailable       = "watch_unavailable"
+)
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var translations = mustLoadTranslations()
+
+// mustLoadTranslations reads the embedded locale files, keyed by the
+// lowercased language tag taken from each file name.
+func mustLoadTranslations() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic("invalid locale file " + entry.Name() + ": " + err.Error())
+		}
+		loaded[strings.ToLower(strings.TrimSuffix(entry.Name(), ".json"))] = messages
+	}
+	return loaded
+}
+
+// preferredLanguages parses an Accept-Language header into language tags
+// ordered by descending quality, dropping tags with q=0.
+func preferredLanguages(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+		langs = append(langs, weighted{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].quality > langs[j].quality })
+
+	tags := make([]string, 0, len(langs))
+	for _, lang := range langs {
+		tags = append(tags, lang.tag)
+	}
+	return tags
+}
+
+// negotiateLanguage picks the best available locale for an Accept-Language
+// header, matching full tags first and then their base language.
+func negotiateLanguage(header string) string {
+	for _, tag := range preferredLanguages(header) {
+		if _, ok := translations[tag]; ok {
+			return tag
+		}
+		base, _, _ := strings.Cut(tag, "-")
+		if _, ok := translations[base]; ok {
+			return base
+		}
+	}
+	return defaultLanguage
+}
+
+func translate(r *http.Request, key string) string {
+	lang := negotiateLanguage(r.Header.Get("Accept-Language"))
+	if message, ok := translations[lang][key]; ok {
+		return message
+	}
+	return translations[defaultLanguage][key]
+}
+
+// localizedError replies with an error message in the client's preferred
+// language, as JSON when the client accepts it and plain text otherwise.
+func localizedError(w http.ResponseWriter, r *http.Request, key string, code int) {
+	writeLocalizedError(w, r, key, code, nil)
+}
+
+// errorResponse is the JSON body of an error sent to a client that accepts
+// application/json.
+type errorResponse struct {
+	Error          string   `json:"error"`
+	AllowedMethods []string `json:"allowedMethods,omitempty"`
+}
+
+// methodNotAllowed sends a 405 listing the allowed methods in the Allow header
+// and, for JSON clients, in the body.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeLocalizedError(w, r, msgMethodNotAllowed, http.StatusMethodNotAllowed, allowed)
+}
+
+// handleNotFound answers unknown API paths instead of letting them fall
+// through to the static file server.
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	localizedError(w, r, msgNotFound, http.StatusNotFound)
+}
+
+func writeLocalizedError(w http.ResponseWriter, r *http.Request, key string, code int, allowed []string) {
+	w.Header().Set("Content-Language", negotiateLanguage(r.Header.Get("Accept-Language")))
+	w.Header().Add("Vary", "Accept, Accept-Language")
+	if !acceptsJSON(r) {
+		http.Error(w, translate(r, key), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: translate(r, key), AllowedMethods: allowed})
+}
+
+// acceptsJSON reports whether the client explicitly asked for JSON.
+func acceptsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
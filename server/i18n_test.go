@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocalesHaveAllKeys(t *testing.T) {
+	for lang, messages := range translations {
+		for key := range translations[defaultLanguage] {
+			if messages[key] == "" {
+				t.Errorf("locale %s is missing %q", lang, key)
+			}
+		}
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                          defaultLanguage,
+		"de":                        "de",
+		"de-AT,de;q=0.9":            "de",
+		"ja,fr;q=0.5":               "fr",
+		"en;q=0.2, es;q=0.8":        "es",
+		"es;q=0, de;q=0.1":          "de",
+		"pt-BR":                     defaultLanguage,
+		"garbage;q=abc, fr;q=0.1":   "fr",
+		"FR-ca;q=0.9, xx;q=invalid": "fr",
+	}
+	for header, want := range cases {
+		if got := negotiateLanguage(header); got != want {
+			t.Errorf("negotiateLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestLocalizedError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/ingresses", nil)
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	rr := httptest.NewRecorder()
+	withAllowedMethods(methodsGet, handleIngresses(0)).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rr.Code)
+	}
+	if got := strings.TrimSpace(rr.Body.String()); got != translations["de"][msgMethodNotAllowed] {
+		t.Fatalf("expected German message, got %q", got)
+	}
+	if got := rr.Header().Get("Content-Language"); got != "de" {
+		t.Fatalf("expected Content-Language de, got %q", got)
+	}
+}
+
+func TestMethodNotAllowedNegotiatesJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/ingresses", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	withAllowedMethods(methodsGet, handleIngresses(0)).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Allow"); got != http.MethodGet {
+		t.Fatalf("expected Allow GET, got %q", got)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("expected JSON content type, got %q", got)
+	}
+	var body errorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body.Error != translations["en"][msgMethodNotAllowed] || len(body.AllowedMethods) != 1 || body.AllowedMethods[0] != http.MethodGet {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/ingresses", nil)
+	rr = httptest.NewRecorder()
+	withAllowedMethods(methodsGet, handleIngresses(0)).ServeHTTP(rr, req)
+	if got := rr.Header().Get("Allow"); got != http.MethodGet {
+		t.Fatalf("expected Allow header on plain-text 405, got %q", got)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected plain text without Accept JSON, got %q", rr.Header().Get("Content-Type"))
+	}
+}
+
+func TestHandleNotFound(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/missing", nil)
+	req.Header.Set("Accept", "application/json")
+	rr := httptest.NewRecorder()
+	handleNotFound(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	var body errorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body.Error != translations["en"][msgNotFound] {
+		t.Fatalf("unexpected body %q (%v)", rr.Body.String(), err)
+	}
+}
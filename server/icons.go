@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIconCacheTTL = time.Hour
+	maxIconBytes        = 100 << 10
+	iconPath            = "/favicon.ico"
+)
+
+// iconProxyEnabled turns on /api/icon, which fetches favicons server-side so
+// the browser never contacts ingress hosts directly.
+var iconProxyEnabled bool
+
+var icons = &iconCache{ttl: defaultIconCacheTTL}
+
+// iconCache keeps fetched favicons (and failures) per host for ttl.
+type iconCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]iconEntry
+	client  *http.Client
+}
+
+type iconEntry struct {
+	data        []byte
+	contentType string
+	fetchedAt   time.Time
+}
+
+func (c *iconCache) lookup(host string) (iconEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[host]
+	if !ok || time.Since(entry.fetchedAt) >= c.ttl {
+		return iconEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *iconCache) store(host string, entry iconEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]iconEntry)
+	}
+	c.entries[host] = entry
+}
+
+func (c *iconCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// httpClient returns the client used for favicon fetches. Redirects are only
+// followed within the original host so a backend cannot bounce the request to
+// an internal address.
+func (c *iconCache) httpClient(timeout time.Duration) *http.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		c.client = &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 3 {
+					return errors.New("too many redirects")
+				}
+				if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+					return fmt.Errorf("redirect to %s leaves the ingress host", req.URL.Host)
+				}
+				return nil
+			},
+		}
+	}
+	return c.client
+}
+
+// iconURL returns the favicon URL for host if it is the host of a health
+// check target. Sharing the health checker's allow-list keeps the proxy from
+// being used to reach any address the health checker would not.
+func iconURL(ctx context.Context, host string) (string, bool, error) {
+	targets, err := healthCheckTargets(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	for _, target := range targets {
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			continue
+		}
+		if strings.EqualFold(parsed.Host, host) {
+			return parsed.Scheme + "://" + strings.ToLower(parsed.Host) + iconPath, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// fetchIcon downloads a favicon, rejecting oversized or non-image responses.
+// Failures are cached as empty entries so a dead backend is not retried on
+// every page load.
+func fetchIcon(ctx context.Context, client *http.Client, target string) iconEntry {
+	entry := iconEntry{fetchedAt: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return entry
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Error fetching icon %s: %v", target, err)
+		return entry
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return entry
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconBytes+1))
+	if err != nil || len(data) == 0 || len(data) > maxIconBytes {
+		return entry
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return entry
+	}
+
+	entry.data = data
+	entry.contentType = contentType
+	return entry
+}
+
+// handleIcon serves the favicon of an ingress host from /api/icon?host=...
+func handleIcon(timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("host")))
+		if host == "" {
+			localizedError(w, r, msgMissingHost, http.StatusBadRequest)
+			return
+		}
+
+		entry, ok := icons.lookup(host)
+		if !ok {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			target, known, err := iconURL(ctx, host)
+			if err != nil {
+				log.Printf("Error fetching ingresses: %v", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !known {
+				localizedError(w, r, msgNotFound, http.StatusNotFound)
+				return
+			}
+
+			entry = fetchIcon(ctx, icons.httpClient(timeout), target)
+			icons.store(host, entry)
+		}
+
+		if entry.data == nil {
+			localizedError(w, r, msgNotFound, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", entry.contentType)
+		w.Header().Set("Cache-Control", "public, max-age="+fmt.Sprint(int(icons.ttl.Seconds())))
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		_, _ = w.Write(entry.data)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandleIcon(t *testing.T) {
+	icons.reset()
+	defer icons.reset()
+
+	var fetches atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if r.URL.Path != iconPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/x-icon")
+		_, _ = w.Write([]byte("\x00\x00\x01\x00icon"))
+	}))
+	defer backend.Close()
+	backendHost := strings.TrimPrefix(backend.URL, "http://")
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{
+				testIngress("default", "app", backendHost),
+				testIngress("default", "hidden", "hidden.example.com"),
+			},
+		})
+	}))
+
+	handler := handleIcon(time.Second)
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/icon?host="+strings.ToUpper(backendHost), nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if got := rr.Header().Get("Content-Type"); got != "image/x-icon" {
+			t.Fatalf("expected image/x-icon, got %q", got)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("expected the icon to be cached after one fetch, got %d", got)
+	}
+
+	setFlags(t, func(f *featureFlags) { f.hiddenHostPatterns = []string{"hidden.example.com"} })
+	for _, host := range []string{"169.254.169.254", "hidden.example.com"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/icon?host="+host, nil))
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for %s, outside the health check targets, got %d", host, rr.Code)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/icon", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a host, got %d", rr.Code)
+	}
+}
+
+func TestFetchIconRejectsNonImages(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			_, _ = w.Write([]byte("<html>login</html>"))
+		case "/large":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(make([]byte, maxIconBytes+1))
+		case "/redirect":
+			http.Redirect(w, r, "http://169.254.169.254/favicon.ico", http.StatusFound)
+		}
+	}))
+	defer backend.Close()
+
+	client := (&iconCache{}).httpClient(time.Second)
+	for _, path := range []string{"/html", "/large", "/redirect"} {
+		if entry := fetchIcon(t.Context(), client, backend.URL+path); entry.data != nil {
+			t.Fatalf("%s: expected the icon to be rejected", path)
+		}
+	}
+}
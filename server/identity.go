@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var (
+	// authProxyHeader names the header an authenticating reverse proxy uses
+	// to pass the signed-in user, e.g. X-Forwarded-User.
+	authProxyHeader string
+
+	// authTrustedProxies are the networks allowed to set authProxyHeader.
+	authTrustedProxies []netip.Prefix
+
+	// impersonateUsers sends Kubernetes API requests as the request's
+	// identity, so users only see what their own RBAC permits.
+	impersonateUsers bool
+)
+
+type identityContextKey struct{}
+
+// parseTrustedProxies splits a comma-separated list of IP addresses and CIDR
+// ranges, skipping invalid entries with a warning.
+func parseTrustedProxies(raw string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(part); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(part); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		log.Printf("Warning: ignoring invalid trusted proxy %q", part)
+	}
+	return prefixes
+}
+
+func isTrustedProxy(remoteAddr string, trusted []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestIdentity returns the user set by a trusted auth proxy, or "" for
+// anonymous requests.
+func requestIdentity(ctx context.Context) string {
+	identity, _ := ctx.Value(identityContextKey{}).(string)
+	return identity
+}
+
+// setImpersonation adds Impersonate-User for the request's identity when
+// impersonation is enabled. Anonymous API requests are refused by
+// withProxyIdentity, so only background work such as prewarming and health
+// checks uses the service account.
+func setImpersonation(req *http.Request) {
+	if !impersonateUsers {
+		return
+	}
+	if identity := requestIdentity(req.Context()); identity != "" {
+		req.Header.Set("Impersonate-User", identity)
+	}
+}
+
+// withProxyIdentity reads the authenticated user from authProxyHeader when
+// the request comes directly from a trusted proxy, stores it in the request
+// context and logs the access. Requests from anywhere else are anonymous, so
+// clients cannot claim an identity by sending the header themselves. With
+// impersonation enabled, anonymous API requests get 401 rather than the
+// service account's cluster-wide view.
+func withProxyIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var identity string
+		if authProxyHeader != "" && isTrustedProxy(r.RemoteAddr, authTrustedProxies) {
+			identity = strings.TrimSpace(r.Header.Get(authProxyHeader))
+		}
+		if identity == "" {
+			if impersonateUsers && strings.HasPrefix(r.URL.Path, "/api/") {
+				localizedError(w, r, msgUnauthorized, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Printf("%s %s user=%q", r.Method, r.URL.Path, identity)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityContextKey{}, identity)))
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	got := parseTrustedProxies(" 10.0.0.0/8 , 192.168.1.5,bogus, fd00::/8")
+	if len(got) != 3 {
+		t.Fatalf("expected 3 prefixes, got %v", got)
+	}
+	if got[1].String() != "192.168.1.5/32" {
+		t.Fatalf("expected single address as /32, got %s", got[1])
+	}
+}
+
+func TestWithProxyIdentity(t *testing.T) {
+	prevHeader, prevProxies := authProxyHeader, authTrustedProxies
+	defer func() { authProxyHeader, authTrustedProxies = prevHeader, prevProxies }()
+	authProxyHeader = "X-Forwarded-User"
+	authTrustedProxies = parseTrustedProxies("10.0.0.0/8")
+
+	var seen string
+	handler := withProxyIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIdentity(r.Context())
+	}))
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		want       string
+	}{
+		{"trusted proxy", "10.1.2.3:4567", "alice"},
+		{"untrusted client", "203.0.113.9:4567", ""},
+		{"mapped trusted proxy", "[::ffff:10.1.2.3]:4567", "alice"},
+	}
+	for _, tc := range cases {
+		seen = "unset"
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		req.Header.Set("X-Forwarded-User", "alice")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if seen != tc.want {
+			t.Fatalf("%s: expected identity %q, got %q", tc.name, tc.want, seen)
+		}
+	}
+
+	authProxyHeader = ""
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	req.Header.Set("X-Forwarded-User", "alice")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if seen != "" {
+		t.Fatalf("expected anonymous request without AUTH_PROXY_HEADER, got %q", seen)
+	}
+}
+
+func TestNewKubernetesRequestImpersonatesIdentity(t *testing.T) {
+	withTestKubernetesAPI(t, http.NotFoundHandler())
+	ctx := context.WithValue(context.Background(), identityContextKey{}, "alice@example.com")
+
+	req, err := newKubernetesRequest(ctx, "/apis", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Impersonate-User"); got != "" {
+		t.Fatalf("expected no impersonation by default, got %q", got)
+	}
+
+	impersonateUsers = true
+	defer func() { impersonateUsers = false }()
+	req, err = newKubernetesRequest(ctx, "/apis", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Impersonate-User"); got != "alice@example.com" {
+		t.Fatalf("expected Impersonate-User alice@example.com, got %q", got)
+	}
+}
+
+func TestWithProxyIdentityRejectsAnonymousImpersonation(t *testing.T) {
+	prevHeader, prevProxies, prevImpersonate := authProxyHeader, authTrustedProxies, impersonateUsers
+	defer func() {
+		authProxyHeader, authTrustedProxies, impersonateUsers = prevHeader, prevProxies, prevImpersonate
+	}()
+	authProxyHeader = "X-Forwarded-User"
+	authTrustedProxies = parseTrustedProxies("10.0.0.0/8")
+	impersonateUsers = true
+
+	handler := withProxyIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	cases := []struct {
+		path, remoteAddr, user string
+		want                   int
+	}{
+		{"/api/ingresses", "10.1.2.3:4567", "alice", http.StatusOK},
+		{"/api/ingresses", "10.1.2.3:4567", "", http.StatusUnauthorized},
+		{"/api/ingresses", "203.0.113.9:4567", "alice", http.StatusUnauthorized},
+		{"/healthz", "203.0.113.9:4567", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.user != "" {
+			req.Header.Set("X-Forwarded-User", tc.user)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s from %s as %q: expected %d, got %d", tc.path, tc.remoteAddr, tc.user, tc.want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// legacyIngressClassAnnotation predates spec.ingressClassName and is still set
+// by some charts.
+const legacyIngressClassAnnotation = "kubernetes.io/ingress.class"
+
+type ingressClassCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type ingressClassesResponse struct {
+	Classes []ingressClassCount `json:"classes"`
+	// Unclassified counts visible ingresses that name no class and so use
+	// the cluster default.
+	Unclassified int `json:"unclassified"`
+}
+
+// ingressClassName returns the class of an ingress, falling back to the
+// legacy annotation.
+func ingressClassName(item map[string]interface{}) string {
+	spec, _ := item["spec"].(map[string]interface{})
+	if name := strings.TrimSpace(stringField(spec, "ingressClassName")); name != "" {
+		return name
+	}
+	return annotationValue(item, legacyIngressClassAnnotation)
+}
+
+// countIngressClasses tallies the classes of the visible ingresses in result,
+// sorted by descending count and then by name.
+func countIngressClasses(result map[string]interface{}) ingressClassesResponse {
+	items, _ := filterIngresses(result)["items"].([]interface{})
+
+	counts := make(map[string]int)
+	response := ingressClassesResponse{Classes: []ingressClassCount{}}
+	for _, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		name := ingressClassName(itemMap)
+		if name == "" {
+			response.Unclassified++
+			continue
+		}
+		counts[name]++
+	}
+
+	for name, count := range counts {
+		response.Classes = append(response.Classes, ingressClassCount{Name: name, Count: count})
+	}
+	sort.Slice(response.Classes, func(i, j int) bool {
+		if response.Classes[i].Count != response.Classes[j].Count {
+			return response.Classes[i].Count > response.Classes[j].Count
+		}
+		return response.Classes[i].Name < response.Classes[j].Name
+	})
+	return response
+}
+
+// handleIngressClasses lists the ingress classes in use, served from the
+// ingress cache.
+func handleIngressClasses(timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		ingresses, err := ingressesCache.fetch(ctx)
+		if err != nil {
+			log.Printf("Error fetching ingresses: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", apiCacheControl)
+		_ = json.NewEncoder(w).Encode(countIngressClasses(ingresses))
+	}
+}
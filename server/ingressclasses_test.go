@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func classedIngress(namespace, name, class string) map[string]interface{} {
+	item := testIngress(namespace, name, name+".example.com")
+	item["spec"].(map[string]interface{})["ingressClassName"] = class
+	return item
+}
+
+func TestHandleIngressClasses(t *testing.T) {
+	legacy := testIngress("default", "legacy", "legacy.example.com")
+	legacy["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{legacyIngressClassAnnotation: "traefik"}
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{
+				classedIngress("default", "a", "nginx"),
+				classedIngress("default", "b", "nginx"),
+				classedIngress("default", "c", "traefik"),
+				legacy,
+				testIngress("default", "plain", "plain.example.com"),
+			},
+		})
+	}))
+
+	rr := httptest.NewRecorder()
+	handleIngressClasses(time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingress-classes", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var payload ingressClassesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json from /api/ingress-classes: %v", err)
+	}
+	want := ingressClassesResponse{
+		Classes:      []ingressClassCount{{Name: "nginx", Count: 2}, {Name: "traefik", Count: 2}},
+		Unclassified: 1,
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Fatalf("expected %+v, got %+v", want, payload)
+	}
+}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// kubeconfigTarget is the apiserver and credentials of the current context of
+// a kubeconfig, used when running outside the cluster.
+type kubeconfigTarget struct {
+	server    string
+	token     string
+	tlsConfig *tls.Config
+}
+
+// kubeconfigAPI is set when no in-cluster service is found but a kubeconfig
+// is, so the server can be run locally against a real cluster.
+var kubeconfigAPI *kubeconfigTarget
+
+// kubeconfigErr records why a kubeconfig that was found could not be used, so
+// it can be repeated when no apiserver is found at all.
+var kubeconfigErr error
+
+// kubeAPIConfigured reports whether there is an apiserver to talk to, either
+// in-cluster or through a kubeconfig.
+func kubeAPIConfigured() bool {
+	return inCluster() || kubeconfigAPI != nil
+}
+
+func inCluster() bool {
+	return kubernetesServiceHost != "" && kubernetesServicePort != ""
+}
+
+// kubeAPIBaseURL returns the apiserver URL without a trailing slash. The
+// in-cluster service always takes precedence over a kubeconfig.
+func kubeAPIBaseURL() string {
+	if !inCluster() && kubeconfigAPI != nil {
+		return kubeconfigAPI.server
+	}
+	return "https://" + net.JoinHostPort(kubernetesServiceHost, kubernetesServicePort)
+}
+
+// kubeAPIToken returns the bearer token for the apiserver; it is empty for a
+// kubeconfig user that authenticates with a client certificate.
+func kubeAPIToken() (string, error) {
+	if !inCluster() && kubeconfigAPI != nil {
+		return kubeconfigAPI.token, nil
+	}
+	tokenBytes, err := os.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(tokenBytes)), nil
+}
+
+// findKubeconfig returns the first existing file listed in KUBECONFIG, or
+// ~/.kube/config, or "" when there is none.
+func findKubeconfig() string {
+	candidates := filepath.SplitList(os.Getenv("KUBECONFIG"))
+	if len(candidates) == 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			candidates = []string{filepath.Join(home, ".kube", "config")}
+		}
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// loadKubeconfig resolves the current context of the kubeconfig at path.
+// Tokens, token files, client certificates and CA bundles are supported;
+// exec and auth-provider plugins are not.
+func loadKubeconfig(path string) (*kubeconfigTarget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseKubeconfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	contextName := stringField(doc, "current-context")
+	if contextName == "" {
+		return nil, errors.New("no current-context set")
+	}
+	kubeContext, ok := namedEntry(doc, "contexts", contextName, "context")
+	if !ok {
+		return nil, fmt.Errorf("context %q not found", contextName)
+	}
+	cluster, ok := namedEntry(doc, "clusters", stringField(kubeContext, "cluster"), "cluster")
+	if !ok {
+		return nil, fmt.Errorf("cluster %q not found", stringField(kubeContext, "cluster"))
+	}
+	user, _ := namedEntry(doc, "users", stringField(kubeContext, "user"), "user")
+
+	target := &kubeconfigTarget{
+		server:    strings.TrimRight(stringField(cluster, "server"), "/"),
+		tlsConfig: &tls.Config{MinVersion: kubeTLSMinVersion},
+	}
+	if target.server == "" {
+		return nil, fmt.Errorf("cluster %q has no server", stringField(kubeContext, "cluster"))
+	}
+
+	dir := filepath.Dir(path)
+	caPEM, err := kubeconfigBytes(cluster, "certificate-authority-data", "certificate-authority", dir)
+	if err != nil {
+		return nil, err
+	}
+	if caPEM != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("no certificates found in the cluster certificate authority")
+		}
+		target.tlsConfig.RootCAs = pool
+	}
+	if skip, _ := strconv.ParseBool(stringField(cluster, "insecure-skip-tls-verify")); skip {
+		target.tlsConfig.InsecureSkipVerify = true
+	}
+
+	if _, ok := user["exec"]; ok {
+		return nil, errors.New("exec credential plugins are not supported; use a token or client certificate")
+	}
+	if _, ok := user["auth-provider"]; ok {
+		return nil, errors.New("auth-provider plugins are not supported; use a token or client certificate")
+	}
+	target.token = strings.TrimSpace(stringField(user, "token"))
+	if tokenFile := stringField(user, "tokenFile"); target.token == "" && tokenFile != "" {
+		tokenBytes, err := os.ReadFile(resolveKubeconfigPath(dir, tokenFile))
+		if err != nil {
+			return nil, err
+		}
+		target.token = strings.TrimSpace(string(tokenBytes))
+	}
+
+	certPEM, err := kubeconfigBytes(user, "client-certificate-data", "client-certificate", dir)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := kubeconfigBytes(user, "client-key-data", "client-key", dir)
+	if err != nil {
+		return nil, err
+	}
+	if certPEM != nil && keyPEM != nil {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		target.tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return target, nil
+}
+
+// namedEntry finds the entry called name in a kubeconfig list such as
+// clusters and returns its nested field (cluster, user or context).
+func namedEntry(doc map[string]interface{}, list, name, field string) (map[string]interface{}, bool) {
+	entries, _ := doc[list].([]interface{})
+	for _, entry := range entries {
+		entryMap, _ := entry.(map[string]interface{})
+		if stringField(entryMap, "name") == name {
+			value, _ := entryMap[field].(map[string]interface{})
+			return value, value != nil
+		}
+	}
+	return nil, false
+}
+
+// kubeconfigBytes returns base64 inline data, or the contents of a file path
+// relative to the kubeconfig, or nil when neither is set.
+func kubeconfigBytes(m map[string]interface{}, dataKey, fileKey, dir string) ([]byte, error) {
+	if encoded := stringField(m, dataKey); encoded != "" {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dataKey, err)
+		}
+		return data, nil
+	}
+	if path := stringField(m, fileKey); path != "" {
+		return os.ReadFile(resolveKubeconfigPath(dir, path))
+	}
+	return nil, nil
+}
+
+func resolveKubeconfigPath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// parseKubeconfig decodes a kubeconfig written as JSON or as the block-style
+// YAML that kubectl produces. YAML features kubectl never writes, such as
+// block scalars, anchors and flow collections, are refused rather than
+// misread.
+func parseKubeconfig(data []byte) (map[string]interface{}, error) {
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		var doc map[string]interface{}
+		err := json.Unmarshal(data, &doc)
+		return doc, err
+	}
+
+	var lines []yamlLine
+	for number, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		content := strings.TrimLeft(text, " ")
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", number+1)
+		}
+		lines = append(lines, yamlLine{number: number + 1, indent: len(text) - len(content), text: content})
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("empty kubeconfig")
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	doc, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("kubeconfig is not a mapping")
+	}
+	return doc, nil
+}
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// yamlParser handles the subset of YAML found in kubeconfig files: nested
+// block mappings and sequences with plain or quoted scalar values.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isYAMLSequenceItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && isYAMLSequenceItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected 'key: value'", line.number)
+		}
+		p.pos++
+
+		if rest != "" {
+			value, err := yamlScalar(line.number, rest)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+			continue
+		}
+		switch {
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			value, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text):
+			// kubectl writes list items at the same indentation as their key.
+			value, err := p.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		default:
+			m[key] = nil
+		}
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]interface{}, error) {
+	items := []interface{}{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isYAMLSequenceItem(line.text) {
+			break
+		}
+
+		rest := strings.TrimPrefix(line.text, "-")
+		content := strings.TrimLeft(rest, " ")
+		if content == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				value, err := p.block(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, value)
+			} else {
+				items = append(items, nil)
+			}
+			continue
+		}
+
+		if _, _, isKey := splitYAMLKey(content); !isKey {
+			p.pos++
+			value, err := yamlScalar(line.number, content)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+			continue
+		}
+
+		// The item is a mapping whose first key shares the dash's line; treat
+		// that key as if it started on its own line at the content's column.
+		itemIndent := indent + 1 + len(rest) - len(content)
+		p.lines[p.pos] = yamlLine{number: line.number, indent: itemIndent, text: content}
+		value, err := p.mapping(itemIndent)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+	}
+	return items, nil
+}
+
+// splitYAMLKey splits "key: value" or "key:" outside of quotes.
+func splitYAMLKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key := parseYAMLScalar(text[:end+2]).(string)
+		rest := text[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+	if strings.HasSuffix(text, ":") && !strings.Contains(text, ": ") {
+		return strings.TrimSuffix(text, ":"), "", true
+	}
+	key, rest, ok := strings.Cut(text, ": ")
+	if !ok || key == "" {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(rest), true
+}
+
+// yamlScalar parses the scalar on line number, refusing the YAML features
+// that need a full parser.
+func yamlScalar(number int, text string) (interface{}, error) {
+	var feature string
+	switch {
+	case text == "[]" || text == "{}":
+	case strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{"):
+		feature = "flow collections"
+	case strings.HasPrefix(text, "|") || strings.HasPrefix(text, ">"):
+		feature = "block scalars"
+	case strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*"):
+		feature = "anchors and aliases"
+	case strings.HasPrefix(text, "!"):
+		feature = "tags"
+	case (text[0] == '"' || text[0] == '\'') && (len(text) < 2 || text[len(text)-1] != text[0]):
+		feature = "multi-line quoted strings"
+	}
+	if feature != "" {
+		return nil, fmt.Errorf("line %d: %s are not supported; convert the kubeconfig with kubectl config view --raw --flatten -o json", number, feature)
+	}
+	return parseYAMLScalar(text), nil
+}
+
+// parseYAMLScalar unquotes a scalar. Plain scalars stay strings, except for
+// the empty flow collections kubectl writes for unused fields.
+func parseYAMLScalar(text string) interface{} {
+	switch {
+	case text == "[]":
+		return []interface{}{}
+	case text == "{}":
+		return map[string]interface{}{}
+	case text == "null" || text == "~":
+		return nil
+	case len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"':
+		if unquoted, err := strconv.Unquote(text); err == nil {
+			return unquoted
+		}
+		return text[1 : len(text)-1]
+	case len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'':
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'")
+	default:
+		return text
+	}
+}
+
+// stripYAMLComment removes a trailing "# comment" that is not inside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKubeconfigJSON(t *testing.T) {
+	doc, err := parseKubeconfig([]byte(`{
+  "apiVersion": "v1",
+  "kind": "Config",
+  "clusters": [{"cluster": {"certificate-authority": "ca.crt", "server": "https://127.0.0.1:6443"}, "name": "dev"}],
+  "contexts": [{"context": {"cluster": "dev", "namespace": "apps", "user": "dev-admin"}, "name": "dev"}],
+  "current-context": "dev",
+  "preferences": {},
+  "users": [{"name": "dev-admin", "user": {"token": "abc#123"}}]
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := stringField(doc, "current-context"); got != "dev" {
+		t.Fatalf("expected current-context dev, got %q", got)
+	}
+	cluster, ok := namedEntry(doc, "clusters", "dev", "cluster")
+	if !ok || stringField(cluster, "server") != "https://127.0.0.1:6443" {
+		t.Fatalf("unexpected cluster %#v", cluster)
+	}
+	user, _ := namedEntry(doc, "users", "dev-admin", "user")
+	if got := stringField(user, "token"); got != "abc#123" {
+		t.Fatalf("expected the token, got %q", got)
+	}
+}
+
+func TestParseKubeconfigYAML(t *testing.T) {
+	doc, err := parseKubeconfig([]byte(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority: ca.crt
+    server: "https://127.0.0.1:6443"  # local
+  name: dev
+contexts:
+- context:
+    cluster: dev
+    namespace: apps
+    user: 'dev-admin'
+  name: dev
+current-context: dev
+preferences: {}
+users:
+- name: dev-admin
+  user:
+    token: abc#123
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cluster, ok := namedEntry(doc, "clusters", "dev", "cluster")
+	if !ok || stringField(cluster, "server") != "https://127.0.0.1:6443" {
+		t.Fatalf("unexpected cluster %#v", cluster)
+	}
+	kubeContext, _ := namedEntry(doc, "contexts", "dev", "context")
+	if got := stringField(kubeContext, "user"); got != "dev-admin" {
+		t.Fatalf("expected quoted user to be unquoted, got %q", got)
+	}
+	user, _ := namedEntry(doc, "users", "dev-admin", "user")
+	if got := stringField(user, "token"); got != "abc#123" {
+		t.Fatalf("expected # inside a value to be kept, got %q", got)
+	}
+}
+
+// kubectlConfigView is `kubectl config view --raw` output for a minikube
+// context and an EKS context, with the certificate data shortened.
+const kubectlConfigView = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0tCg==
+    server: https://ABCDEF0123456789.gr7.eu-west-1.eks.amazonaws.com
+  name: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+- cluster:
+    certificate-authority: /home/dev/.minikube/ca.crt
+    extensions:
+    - extension:
+        last-update: Fri, 16 Oct 2026 09:12:44 UTC
+        provider: minikube.sigs.k8s.io
+        version: v1.34.0
+      name: cluster_info
+    server: https://192.168.49.2:8443
+  name: minikube
+contexts:
+- context:
+    cluster: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+    user: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+  name: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+- context:
+    cluster: minikube
+    extensions:
+    - extension:
+        last-update: Fri, 16 Oct 2026 09:12:44 UTC
+        provider: minikube.sigs.k8s.io
+        version: v1.34.0
+      name: context_info
+    namespace: default
+    user: minikube
+  name: minikube
+current-context: minikube
+kind: Config
+preferences: {}
+users:
+- name: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      args:
+      - --region
+      - eu-west-1
+      - eks
+      - get-token
+      - --cluster-name
+      - prod
+      command: aws
+      env: null
+      interactiveMode: IfAvailable
+      provideClusterInfo: false
+- name: minikube
+  user:
+    client-certificate: /home/dev/.minikube/profiles/minikube/client.crt
+    client-key: /home/dev/.minikube/profiles/minikube/client.key
+`
+
+func TestParseKubeconfigKubectlOutput(t *testing.T) {
+	doc, err := parseKubeconfig([]byte(kubectlConfigView))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := stringField(doc, "current-context"); got != "minikube" {
+		t.Fatalf("expected current-context minikube, got %q", got)
+	}
+	cluster, ok := namedEntry(doc, "clusters", "minikube", "cluster")
+	if !ok || stringField(cluster, "server") != "https://192.168.49.2:8443" || stringField(cluster, "certificate-authority") != "/home/dev/.minikube/ca.crt" {
+		t.Fatalf("unexpected minikube cluster %#v", cluster)
+	}
+	extensions, _ := cluster["extensions"].([]interface{})
+	if len(extensions) != 1 {
+		t.Fatalf("expected one cluster extension, got %#v", cluster["extensions"])
+	}
+	kubeContext, _ := namedEntry(doc, "contexts", "minikube", "context")
+	if stringField(kubeContext, "user") != "minikube" || stringField(kubeContext, "namespace") != "default" {
+		t.Fatalf("unexpected minikube context %#v", kubeContext)
+	}
+	user, _ := namedEntry(doc, "users", "minikube", "user")
+	if got := stringField(user, "client-key"); got != "/home/dev/.minikube/profiles/minikube/client.key" {
+		t.Fatalf("unexpected client key %q", got)
+	}
+
+	eks, _ := namedEntry(doc, "users", "arn:aws:eks:eu-west-1:123456789012:cluster/prod", "user")
+	exec, _ := eks["exec"].(map[string]interface{})
+	args, _ := exec["args"].([]interface{})
+	if stringField(exec, "command") != "aws" || len(args) != 6 || args[0] != "--region" || exec["env"] != nil {
+		t.Fatalf("unexpected exec plugin %#v", exec)
+	}
+	if prefs, ok := doc["preferences"].(map[string]interface{}); !ok || len(prefs) != 0 {
+		t.Fatalf("expected empty preferences, got %#v", doc["preferences"])
+	}
+}
+
+func TestParseKubeconfigRejectsUnsupportedYAML(t *testing.T) {
+	for _, doc := range []string{
+		"users:\n- name: u\n  user:\n    token: |\n      abc\n",
+		"clusters:\n- name: a\n  cluster: &base\n    server: https://example.com\n",
+		"clusters:\n- name: a\n  cluster: {server: https://example.com}\n",
+		"users:\n- name: u\n  user:\n    token: \"abc\n      def\"\n",
+		"clusters:\n  - name: a\n   server: b\n",
+	} {
+		if _, err := parseKubeconfig([]byte(doc)); err == nil {
+			t.Errorf("expected an error for %q", doc)
+		}
+	}
+}
+
+func TestFindKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "config")
+	writeTestFile(t, dir, "config", "current-context: dev\n")
+
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "missing")+string(filepath.ListSeparator)+existing)
+	if got := findKubeconfig(); got != existing {
+		t.Fatalf("expected %s, got %q", existing, got)
+	}
+
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("HOME", dir)
+	if got := findKubeconfig(); got != "" {
+		t.Fatalf("expected no kubeconfig without ~/.kube/config, got %q", got)
+	}
+	writeTestFile(t, dir, ".kube/config", "current-context: dev\n")
+	if got := findKubeconfig(); got != filepath.Join(dir, ".kube", "config") {
+		t.Fatalf("expected ~/.kube/config, got %q", got)
+	}
+}
+
+func TestLoadKubeconfigRejectsExecPlugins(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	writeTestFile(t, dir, "config", `{
+  "clusters": [{"name": "c", "cluster": {"server": "https://example.com"}}],
+  "contexts": [{"name": "c", "context": {"cluster": "c", "user": "u"}}],
+  "current-context": "c",
+  "users": [{"name": "u", "user": {"exec": {"command": "aws"}}}]
+}`)
+	if _, err := loadKubeconfig(path); err == nil || !strings.Contains(err.Error(), "exec") {
+		t.Fatalf("expected exec plugins to be rejected, got %v", err)
+	}
+}
+
+func TestInitKubernetesClientFallsBackToKubeconfig(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{testIngress("apps", "web", "web.example.com")},
+		})
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	writeTestFile(t, dir, "token", "local-token\n")
+	writeTestFile(t, dir, "config", `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: `+base64.StdEncoding.EncodeToString(caPEM)+`
+    server: `+srv.URL+`/
+  name: local
+contexts:
+- context:
+    cluster: local
+    user: developer
+  name: local
+current-context: local
+kind: Config
+preferences: {}
+users:
+- name: developer
+  user:
+    tokenFile: token
+`)
+
+	prevClient, prevAPI := httpClient, kubeconfigAPI
+	defer func() {
+		httpClient, kubeconfigAPI = prevClient, prevAPI
+		kubernetesServiceHost, kubernetesServicePort = "", ""
+	}()
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+	t.Setenv("KUBECONFIG", filepath.Join(dir, "config"))
+
+	initKubernetesClient(5*time.Second, time.Second)
+	if !kubeAPIConfigured() {
+		t.Fatal("expected the kubeconfig to configure the API")
+	}
+
+	result, err := fetchIngresses(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := result["items"].([]interface{}); len(items) != 1 {
+		t.Fatalf("expected one ingress from the kubeconfig cluster, got %#v", result)
+	}
+	if gotAuth != "Bearer local-token" {
+		t.Fatalf("expected the kubeconfig token, got %q", gotAuth)
+	}
+	if checks := readinessChecks(); !checks["kubeApi"].OK || checks["token"] != skippedCheck {
+		t.Fatalf("unexpected readiness checks %#v", checks)
+	}
+}
+
+func TestInitKubernetesClientWithoutKubeconfigStaysEmpty(t *testing.T) {
+	prevClient, prevAPI := httpClient, kubeconfigAPI
+	defer func() { httpClient, kubeconfigAPI = prevClient, prevAPI }()
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "")
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+
+	initKubernetesClient(time.Second, time.Second)
+
+	result, err := fetchIngresses(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := result["items"].([]interface{}); len(items) != 0 {
+		t.Fatalf("expected an empty list, got %#v", result)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"io"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// lifecyclePhase is where the pod is in its life, which decides how /readyz
+// answers. The only transitions are starting → ready, once every readiness
+// check has passed, and starting or ready → draining, on SIGTERM. draining is
+// final, so a pod told to stop during startup never reports ready.
+type lifecyclePhase int32
+
+const (
+	phaseStarting lifecyclePhase = iota
+	phaseReady
+	phaseDraining
+)
+
+var lifecyclePhases = []lifecyclePhase{phaseStarting, phaseReady, phaseDraining}
+
+func (p lifecyclePhase) String() string {
+	switch p {
+	case phaseStarting:
+		return "starting"
+	case phaseReady:
+		return "ready"
+	case phaseDraining:
+		return "draining"
+	default:
+		return "unknown"
+	}
+}
+
+type lifecycleState struct {
+	phase     atomic.Int32
+	changedAt atomic.Int64
+}
+
+// lifecycle is the process's lifecycle state machine.
+var lifecycle lifecycleState
+
+func (l *lifecycleState) current() lifecyclePhase {
+	return lifecyclePhase(l.phase.Load())
+}
+
+// transition moves from one phase to another, reporting false when the
+// state machine is not in from, such as when SIGTERM won the race against
+// becoming ready.
+func (l *lifecycleState) transition(from, to lifecyclePhase) bool {
+	if !l.phase.CompareAndSwap(int32(from), int32(to)) {
+		return false
+	}
+	l.changedAt.Store(time.Now().Unix())
+	log.Printf("Lifecycle: %s -> %s", from, to)
+	return true
+}
+
+// markReady moves starting → ready once checks all pass. Later check
+// failures make /readyz answer 503 but do not return the pod to starting.
+func (l *lifecycleState) markReady(checks map[string]readinessCheck) {
+	if l.current() == phaseStarting && allChecksOK(checks) {
+		l.transition(phaseStarting, phaseReady)
+	}
+}
+
+// drain moves to draining from any phase.
+func (l *lifecycleState) drain() {
+	for {
+		phase := l.current()
+		if phase == phaseDraining || l.transition(phase, phaseDraining) {
+			return
+		}
+	}
+}
+
+// reset returns the state machine to starting.
+func (l *lifecycleState) reset() {
+	l.phase.Store(int32(phaseStarting))
+	l.changedAt.Store(0)
+}
+
+func (l *lifecycleState) write(w io.Writer) {
+	current := l.current()
+	_, _ = io.WriteString(w, "# HELP home_pager_lifecycle_phase Current lifecycle phase (starting, ready or draining).\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_lifecycle_phase gauge\n")
+	for _, phase := range lifecyclePhases {
+		value := "0"
+		if phase == current {
+			value = "1"
+		}
+		_, _ = io.WriteString(w, "home_pager_lifecycle_phase{phase=\""+phase.String()+"\"} "+value+"\n")
+	}
+	_, _ = io.WriteString(w, "# HELP home_pager_lifecycle_phase_changed_timestamp_seconds Unix time of the last lifecycle transition.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_lifecycle_phase_changed_timestamp_seconds gauge\n")
+	_, _ = io.WriteString(w, "home_pager_lifecycle_phase_changed_timestamp_seconds "+strconv.FormatInt(l.changedAt.Load(), 10)+"\n")
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func readyz(t *testing.T) (int, readinessResponse) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	handleReady(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var response readinessResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid /readyz body %q: %v", rr.Body.String(), err)
+	}
+	return rr.Code, response
+}
+
+func TestLifecycleStartingToReadyToDraining(t *testing.T) {
+	lifecycle.reset()
+	defer lifecycle.reset()
+	requireFirstFetch = true
+	firstFetchDone.Store(false)
+	defer func() { requireFirstFetch = false }()
+
+	if code, response := readyz(t); code != http.StatusServiceUnavailable || response.Phase != "starting" {
+		t.Fatalf("expected 503 while starting, got %d %+v", code, response)
+	}
+
+	if _, err := fetchIngresses(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code, response := readyz(t); code != http.StatusOK || response.Phase != "ready" {
+		t.Fatalf("expected 200 once the first fetch succeeded, got %d %+v", code, response)
+	}
+
+	drainBeforeShutdown(0)
+	if code, response := readyz(t); code != http.StatusServiceUnavailable || response.Phase != "draining" {
+		t.Fatalf("expected 503 while draining, got %d %+v", code, response)
+	}
+}
+
+func TestLifecycleSIGTERMDuringStartup(t *testing.T) {
+	lifecycle.reset()
+	defer lifecycle.reset()
+	requireFirstFetch = true
+	firstFetchDone.Store(false)
+	defer func() { requireFirstFetch = false }()
+
+	drainBeforeShutdown(0)
+	if _, err := fetchIngresses(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every check but shutdown now passes; the pod must still not report
+	// ready, since draining is final.
+	code, response := readyz(t)
+	if code != http.StatusServiceUnavailable || response.Phase != "draining" {
+		t.Fatalf("expected a pod stopped during startup to stay draining, got %d %+v", code, response)
+	}
+	if !response.Checks["firstFetch"].OK || response.Checks["shutdown"].OK {
+		t.Fatalf("unexpected checks %+v", response.Checks)
+	}
+}
+
+func TestLifecycleReadyStaysReadyWhenChecksFail(t *testing.T) {
+	lifecycle.reset()
+	defer lifecycle.reset()
+
+	if code, _ := readyz(t); code != http.StatusOK {
+		t.Fatalf("expected ready, got %d", code)
+	}
+
+	prevClient := httpClient
+	httpClient = nil
+	kubernetesServiceHost, kubernetesServicePort = "10.96.0.1", "443"
+	defer func() {
+		httpClient = prevClient
+		kubernetesServiceHost, kubernetesServicePort = "", ""
+	}()
+	code, response := readyz(t)
+	if code != http.StatusServiceUnavailable || response.Phase != "ready" {
+		t.Fatalf("expected a failing check to answer 503 without leaving ready, got %d %+v", code, response)
+	}
+}
+
+func TestLifecycleMetrics(t *testing.T) {
+	lifecycle.reset()
+	defer lifecycle.reset()
+	lifecycle.drain()
+
+	var body strings.Builder
+	lifecycle.write(&body)
+	for _, want := range []string{
+		`home_pager_lifecycle_phase{phase="starting"} 0`,
+		`home_pager_lifecycle_phase{phase="draining"} 1`,
+	} {
+		if !strings.Contains(body.String(), want) {
+			t.Fatalf("expected %q in %q", want, body.String())
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
@@ -18,20 +22,39 @@ import (
 	"time"
 )
 
+var (
+	serviceAccountCAPath    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// version is the release version, set at build time with
+// -ldflags "-X main.version=...".
+var version = "dev"
+
 var (
 	httpClient            *http.Client
+	kubeUserAgent         = "home-pager/" + version
 	kubernetesServiceHost string
 	kubernetesServicePort string
+	apiCacheControl       = defaultAPICacheControl
+
+	// kubernetesServiceErr is set when the in-cluster service host or port
+	// is malformed.
+	kubernetesServiceErr error
 )
 
 const (
 	defaultPort           = "8080"
 	defaultHTTPTimeout    = 10 * time.Second
+	defaultDialTimeout    = time.Second
 	maxIngressesBodyBytes = 4 << 20
-)
 
-var startTime = time.Now()
-var totalRequests uint64
+	// listPageSize is the limit requested per page when listing ingresses.
+	listPageSize        = 500
+	defaultMaxListPages = 100
+
+	defaultAPICacheControl = "no-cache"
+)
 
 func main() {
 	port := os.Getenv("PORT")
@@ -40,29 +63,223 @@ func main() {
 	}
 
 	kubeTimeout := getEnvDuration("KUBERNETES_TIMEOUT", defaultHTTPTimeout)
-	initKubernetesClient(kubeTimeout)
+	flightTimeout = kubeTimeout
+	if raw := strings.TrimSpace(os.Getenv("KUBE_TLS_MIN_VERSION")); raw != "" {
+		if minVersion, err := parseTLSMinVersion(raw); err != nil {
+			log.Printf("Warning: invalid KUBE_TLS_MIN_VERSION %q: %v; using 1.2", raw, err)
+		} else {
+			kubeTLSMinVersion = minVersion
+		}
+	}
+	kubeUserAgent = loadKubeUserAgent()
+	initKubernetesClient(kubeTimeout, getEnvDuration("KUBE_DIAL_TIMEOUT", defaultDialTimeout))
+	chaos = loadChaosConfig()
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	backgroundPool = newWorkerPool(int(getEnvInt64("WORKER_POOL_SIZE", defaultWorkerPoolSize)))
+	heartbeatTimeout = getEnvDuration("HEARTBEAT_TIMEOUT", 0)
+	if heartbeatTimeout > 0 {
+		backgroundWorkers.start("workerWatchdog", func() { watchWorkerPool(backgroundCtx, defaultWorkerWatchdogInterval) })
+	}
+	ingressesCache.ttl = getEnvDuration("CACHE_TTL", 0)
+	entriesCache.ttl = ingressesCache.ttl
+	ingressesCache.staleWhileRevalidate = getEnvDuration("STALE_WHILE_REVALIDATE", 0)
+	entriesCache.staleWhileRevalidate = ingressesCache.staleWhileRevalidate
+	homepageEntries = loadHomepageEntrySource()
+	if getEnvBool("CACHE_PREWARM", false) {
+		requireFirstFetch = true
+		backgroundWorkers.start("cachePrewarm", func() { ingressesCache.prewarm(backgroundCtx, kubeTimeout) })
+	}
+
+	flagsFile := strings.TrimSpace(os.Getenv("FLAGS_FILE"))
+	if err := reloadFeatureFlags(flagsFile); err != nil {
+		log.Fatalf("Error loading FLAGS_FILE: %v", err)
+	}
+	if flagsFile != "" {
+		registerConfigReloader("FLAGS_FILE", func() error { return reloadFeatureFlags(flagsFile) })
+	}
+	staticFS = loadStaticFS()
+	listedResource = loadListedResource()
+	watchNamespaces = parseNamespaceList(os.Getenv("WATCH_NAMESPACES"))
+	namespaceTimeout = getEnvDuration("NAMESPACE_TIMEOUT", 0)
+	maxListPages = int(getEnvInt64("MAX_PAGES", defaultMaxListPages))
+	serveUI := getEnvBool("SERVE_UI", true)
+	requireStaticAssets = getEnvBool("READY_REQUIRE_UI", false)
+	staticIndexMarker = os.Getenv("READY_UI_MARKER")
+	if staticIndexMarker != "" {
+		requireStaticAssets = true
+	}
+	if !serveUI {
+		if requireStaticAssets {
+			log.Printf("Warning: SERVE_UI=false; ignoring READY_REQUIRE_UI and READY_UI_MARKER")
+		}
+		requireStaticAssets = false
+	} else if !staticAssetsPresent(staticFS) {
+		log.Printf("Warning: %s not found in static roots (or lacks READY_UI_MARKER); the UI will not be served", staticIndexFile)
+	}
+	if err := loadAssetManifest(staticFS); err != nil {
+		log.Printf("Warning: could not load %s: %v; fingerprinted assets will not be cached as immutable", strings.TrimPrefix(assetManifestFile, "/"), err)
+	}
+	registerConfigReloader("asset-manifest", func() error { return reloadAssetManifest(staticFS) })
+	staticWriteTimeout := getEnvDuration("STATIC_WRITE_TIMEOUT", defaultStaticWriteTimeout)
+	csrfTrustedOrigins = parseTrustedOrigins(os.Getenv("CSRF_TRUSTED_ORIGINS"))
+	corsAllowedOrigins = parseCORSOrigins(os.Getenv("CORS_ALLOWED_ORIGINS"))
+	authProxyHeader = strings.TrimSpace(os.Getenv("AUTH_PROXY_HEADER"))
+	authTrustedProxies = parseTrustedProxies(os.Getenv("AUTH_TRUSTED_PROXIES"))
+	impersonateUsers = getEnvBool("IMPERSONATE_USERS", false)
+	if impersonateUsers && (authProxyHeader == "" || len(authTrustedProxies) == 0) {
+		log.Fatalf("IMPERSONATE_USERS needs AUTH_PROXY_HEADER and AUTH_TRUSTED_PROXIES; otherwise every API request is rejected")
+	}
+	if authProxyHeader != "" && len(authTrustedProxies) == 0 {
+		log.Printf("Warning: AUTH_PROXY_HEADER is set but AUTH_TRUSTED_PROXIES is empty; all requests are anonymous")
+	}
+	audit = loadAuditor()
+	if audit != nil && audit.queue != nil {
+		backgroundWorkers.start("auditWebhook", func() { audit.run(backgroundCtx) })
+	}
+	location, err := loadDisplayLocation(os.Getenv("DISPLAY_TIMEZONE"))
+	if err != nil {
+		log.Fatalf("Error loading DISPLAY_TIMEZONE: %v", err)
+	}
+	displayLocation = location
+	metricsToken = strings.TrimSpace(os.Getenv("METRICS_TOKEN"))
+	metricsProfile = parseMetricsProfile(os.Getenv("METRICS_PROFILE"))
+	statusToken = firstEnv("STATUS_TOKEN", "METRICS_TOKEN")
+	sourceMapToken = strings.TrimSpace(os.Getenv("SOURCE_MAP_TOKEN"))
+	maintenanceFile = strings.TrimSpace(os.Getenv("MAINTENANCE_FILE"))
+	kubeMaxRetries = int(getEnvInt64("KUBE_MAX_RETRIES", defaultKubeMaxRetries))
+	kubeRetryBudget = loadRetryBudget()
+	metrics := newServerMetrics(latencyBuckets(os.Getenv("LATENCY_BUCKETS")))
+	if addr := strings.TrimSpace(os.Getenv("STATSD_ADDR")); addr != "" {
+		emitter, err := newStatsDEmitter(addr, metrics)
+		if err != nil {
+			log.Printf("Warning: StatsD disabled: %v", err)
+		} else {
+			statsdInterval := getEnvDuration("STATSD_INTERVAL", defaultStatsDInterval)
+			backgroundWorkers.start("statsd", func() { emitter.run(backgroundCtx, statsdInterval) })
+		}
+	}
+	if value := strings.TrimSpace(os.Getenv("API_CACHE_CONTROL")); value != "" {
+		apiCacheControl = value
+	}
+
+	compressionExclusions = loadCompressionExclusions()
+	trailingSlashMode := parseTrailingSlashMode(os.Getenv("TRAILING_SLASH"))
+	maxRequestBody := getEnvInt64("MAX_REQUEST_BODY", defaultMaxRequestBody)
+	maxStreams = getEnvInt64("MAX_STREAMS", 0)
+	apiTimeout := getEnvDuration("API_TIMEOUT", kubeTimeout)
+	metricsTimeout := getEnvDuration("METRICS_TIMEOUT", defaultMetricsTimeout)
+
+	if path := strings.TrimSpace(os.Getenv("FAVORITES_FILE")); path != "" {
+		store, err := loadFavoriteStore(path)
+		if err != nil {
+			log.Fatalf("Error loading FAVORITES_FILE: %v", err)
+		}
+		favorites = store
+	}
+	iconProxyEnabled = getEnvBool("ICON_PROXY", false)
+	icons.ttl = getEnvDuration("ICON_CACHE_TTL", defaultIconCacheTTL)
+	redirectsFile = strings.TrimSpace(os.Getenv("REDIRECTS_FILE"))
+	if redirectsFile != "" {
+		if err := reloadRedirects(redirectsFile); err != nil {
+			log.Fatalf("Error loading REDIRECTS_FILE: %v", err)
+		}
+		registerConfigReloader("REDIRECTS_FILE", func() error { return reloadRedirects(redirectsFile) })
+	}
+
+	tilesFile = strings.TrimSpace(os.Getenv("TILES_FILE"))
+	if tilesFile != "" {
+		if err := reloadTiles(tilesFile); err != nil {
+			log.Fatalf("Error loading TILES_FILE: %v", err)
+		}
+		registerConfigReloader("TILES_FILE", func() error { return reloadTiles(tilesFile) })
+		tilesPollInterval := getEnvDuration("TILES_FILE_POLL_INTERVAL", defaultTilesPollInterval)
+		backgroundWorkers.start("tilesFile", func() { watchTilesFile(backgroundCtx, tilesFile, tilesPollInterval) })
+	}
+	robotsTxtFile = strings.TrimSpace(os.Getenv("ROBOTS_TXT_FILE"))
+	if err := loadRobotsTxt(); err != nil {
+		log.Fatalf("Error loading ROBOTS_TXT_FILE: %v", err)
+	}
+	if robotsTxtFile != "" {
+		registerConfigReloader("ROBOTS_TXT_FILE", reloadRobotsTxt)
+	}
+
+	healthChecks = loadHealthChecker()
+	if healthChecks != nil {
+		backgroundWorkers.start("healthChecks", func() { healthChecks.run(backgroundCtx, kubeTimeout) })
+	}
+
+	routes := []route{
+		{pattern: "/api/ingresses", methods: methodsGet, handler: handleIngresses(kubeTimeout), timeout: apiTimeout},
+		{pattern: "/api/ingresses/diff", methods: methodsGet, handler: handleIngressDiff(kubeTimeout), timeout: apiTimeout},
+		{pattern: "/api/ingresses/count", methods: methodsGet, handler: handleIngressCount(kubeTimeout), timeout: apiTimeout},
+		{pattern: "/api/dashboard", methods: methodsGet, handler: handleDashboard(kubeTimeout), timeout: apiTimeout},
+		{pattern: "/api/ingress-classes", methods: methodsGet, handler: handleIngressClasses(kubeTimeout), timeout: apiTimeout},
+		{pattern: "/api/hosts", methods: methodsGet, handler: handleHosts(kubeTimeout), timeout: apiTimeout},
+		{pattern: "/api/ingresses/stream", methods: methodsGet, handler: handleIngressStream(kubeTimeout)},
+		{pattern: "/api/validate-selector", methods: methodsGet, handler: http.HandlerFunc(handleValidateSelector), timeout: apiTimeout},
+		{pattern: "/api/stats", methods: methodsGet, handler: http.HandlerFunc(metrics.handleStats), timeout: apiTimeout},
+		{pattern: "/api/config", methods: methodsGet, handler: http.HandlerFunc(handleConfig), timeout: apiTimeout},
+		{pattern: "/api/", handler: http.HandlerFunc(handleNotFound)},
+		{pattern: "/healthz", methods: methodsRead, handler: http.HandlerFunc(handleHealth)},
+		{pattern: "/readyz", methods: methodsRead, handler: http.HandlerFunc(handleReady)},
+		{pattern: "/status", methods: methodsRead, handler: requireBearerToken(&statusToken, metrics.handleStatus), timeout: metricsTimeout},
+		{pattern: "/robots.txt", methods: methodsRead, handler: http.HandlerFunc(handleRobotsTxt)},
+		{pattern: "/metrics", methods: methodsGet, handler: requireBearerToken(&metricsToken, metrics.handleMetrics), timeout: metricsTimeout},
+	}
+	if favorites != nil {
+		routes = append(routes, route{pattern: "/api/favorites", methods: []string{http.MethodGet, http.MethodPost}, handler: http.HandlerFunc(handleFavorites), timeout: apiTimeout})
+	}
+	if iconProxyEnabled {
+		routes = append(routes, route{pattern: "/api/icon", methods: methodsGet, handler: handleIcon(kubeTimeout), timeout: apiTimeout})
+	}
+	if redirectsFile != "" {
+		routes = append(routes, route{pattern: redirectsPathPrefix, methods: methodsRead, handler: http.HandlerFunc(handleRedirect), timeout: apiTimeout})
+	}
+
+	rootHandler := withWriteDeadline(staticWriteTimeout, withAssetCaching(withSourceMapAuth(withPrecompressedAssets(staticFS, withLocalizedNotFound(http.FileServer(staticFS))))))
+	if !serveUI {
+		rootHandler = handleAPIIndex(routes)
+	}
+	routes = append(routes, route{pattern: "/", methods: methodsRead, handler: rootHandler})
+
+	maxHeaderBytes := getEnvInt64("MAX_HEADER_BYTES", defaultMaxHeaderBytes)
+	if apiMaxHeaderBytes := getEnvInt64("API_MAX_HEADER_BYTES", 0); apiMaxHeaderBytes > 0 {
+		for i := range routes {
+			if strings.HasPrefix(routes[i].pattern, "/api/") {
+				routes[i].maxHeaderBytes = apiMaxHeaderBytes
+			}
+		}
+	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/ingresses", handleIngresses(kubeTimeout))
-	mux.HandleFunc("/healthz", handleHealth)
-	mux.HandleFunc("/readyz", handleReady)
-	mux.HandleFunc("/metrics", handleMetrics)
-	mux.Handle("/", http.FileServer(http.Dir("/app")))
+	registerRoutes(mux, routes)
 
 	server := &http.Server{
-		Addr:              ":" + port,
-		Handler:           withSecurityHeaders(withRequestMetrics(mux)),
-		ReadHeaderTimeout: 5 * time.Second,
-		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      15 * time.Second,
-		IdleTimeout:       60 * time.Second,
-		MaxHeaderBytes:    1 << 20,
+		Addr:           ":" + port,
+		Handler:        withServerHeader(os.Getenv("SERVER_HEADER"), withCORS(withSecurityHeaders(metrics.middleware(withClientCert(withProxyIdentity(withAudit(withMaintenance(withCSRFProtection(withMaxRequestBody(maxRequestBody, withCompression(loadGzipLevel(), withTrailingSlash(trailingSlashMode, mux)))))))))))),
+		MaxHeaderBytes: int(maxHeaderBytes),
 	}
+	loadServerTimeouts().apply(server)
+	tlsConfig, err := loadServerTLSConfig()
+	if err != nil {
+		log.Fatalf("Error loading server TLS config: %v", err)
+	}
+	server.TLSConfig = tlsConfig
 
 	shutdownErr := make(chan error, 1)
 	go func() {
-		log.Printf("Serving on :%s", port)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if server.TLSConfig != nil {
+			log.Printf("Serving TLS on :%s", port)
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Serving on :%s", port)
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			shutdownErr <- err
 		}
 		close(shutdownErr)
@@ -70,6 +287,9 @@ func main() {
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	backgroundWorkers.start("reloadSignals", func() { handleReloadSignals(backgroundCtx, reload) })
 
 	select {
 	case err := <-shutdownErr:
@@ -77,9 +297,15 @@ func main() {
 			log.Fatal(err)
 		}
 	case <-stop:
+		drainBeforeShutdown(getEnvDuration("PRESTOP_DELAY", 0))
 		log.Printf("Shutting down")
 	}
 
+	stopBackground()
+	if lingering := backgroundWorkers.wait(getEnvDuration("WORKER_SHUTDOWN_TIMEOUT", defaultWorkerShutdownTimeout)); len(lingering) > 0 {
+		log.Printf("Warning: background workers did not stop in time and were abandoned: %s", strings.Join(lingering, ", "))
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
@@ -87,14 +313,75 @@ func main() {
 	}
 }
 
-func initKubernetesClient(timeout time.Duration) {
-	kubernetesServiceHost = strings.TrimSpace(os.Getenv("KUBERNETES_SERVICE_HOST"))
-	kubernetesServicePort = strings.TrimSpace(os.Getenv("KUBERNETES_SERVICE_PORT"))
+// kubeTLSMinVersion is the lowest TLS version accepted from the apiserver.
+var kubeTLSMinVersion uint16 = tls.VersionTLS12
+
+// parseTLSMinVersion accepts "1.2" or "1.3", with an optional "TLS" prefix.
+func parseTLSMinVersion(raw string) (uint16, error) {
+	version := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(raw)), "TLS")
+	switch strings.TrimSpace(version) {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, errors.New("must be 1.2 or 1.3")
+	}
+}
+
+// loadKubeUserAgent returns the User-Agent sent to the apiserver so its calls
+// are easy to find in audit logs: KUBE_USER_AGENT when set, otherwise
+// "home-pager/<version>" followed by CLUSTER_NAME in parentheses.
+func loadKubeUserAgent() string {
+	if agent := strings.TrimSpace(os.Getenv("KUBE_USER_AGENT")); agent != "" {
+		return agent
+	}
+	agent := "home-pager/" + version
+	if cluster := strings.TrimSpace(os.Getenv("CLUSTER_NAME")); cluster != "" {
+		agent += " (" + cluster + ")"
+	}
+	return agent
+}
+
+func initKubernetesClient(timeout, dialTimeout time.Duration) {
+	kubernetesServiceHost, kubernetesServicePort = "", ""
+	kubeconfigAPI = nil
+	kubernetesServiceErr = nil
+	kubeconfigErr = nil
+
+	methods := parseDiscoveryMethods(os.Getenv("KUBE_API_DISCOVERY"))
+	method, target := discoverKubernetesAPI(methods, dialTimeout)
+	switch method {
+	case "":
+		log.Printf("No Kubernetes API found (tried %s)", strings.Join(methods, ", "))
+		if kubeconfigErr != nil {
+			log.Printf("Error: %v; the ingress list will be empty", kubeconfigErr)
+		}
+	case discoveryKubeconfig:
+		log.Printf("Kubernetes API %s found via %s", target.server, method)
+		kubeconfigAPI = target
+		httpClient = &http.Client{
+			Timeout:       timeout,
+			Transport:     newKubernetesTransport(dialTimeout, target.tlsConfig),
+			CheckRedirect: checkKubernetesRedirect,
+		}
+		return
+	default:
+		log.Printf("Kubernetes API %s found via %s", net.JoinHostPort(kubernetesServiceHost, kubernetesServicePort), method)
+		kubernetesServiceErr = validateKubernetesService(kubernetesServiceHost, kubernetesServicePort)
+		if kubernetesServiceErr != nil {
+			log.Printf("Warning: %v; Kubernetes API requests will fail", kubernetesServiceErr)
+		}
+	}
 
-	caCert, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/ca.crt")
+	caCert, err := os.ReadFile(serviceAccountCAPath)
 	if err != nil {
 		log.Printf("Warning: Could not read CA cert: %v (running outside cluster?)", err)
-		httpClient = &http.Client{Timeout: timeout}
+		httpClient = &http.Client{
+			Timeout:       timeout,
+			Transport:     newKubernetesTransport(dialTimeout, &tls.Config{MinVersion: kubeTLSMinVersion}),
+			CheckRedirect: checkKubernetesRedirect,
+		}
 		return
 	}
 
@@ -103,55 +390,321 @@ func initKubernetesClient(timeout time.Duration) {
 
 	httpClient = &http.Client{
 		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs: caCertPool,
-			},
-		},
+		Transport: newKubernetesTransport(dialTimeout, &tls.Config{
+			RootCAs:    caCertPool,
+			MinVersion: kubeTLSMinVersion,
+		}),
+		CheckRedirect: checkKubernetesRedirect,
+	}
+}
+
+// maxKubernetesRedirects bounds redirect chains from the apiserver, matching
+// the net/http default.
+const maxKubernetesRedirects = 10
+
+// checkKubernetesRedirect only follows redirects that stay on the configured
+// apiserver's scheme, host and port, so a proxy in front of it cannot send
+// the bearer token or the request to an untrusted location.
+func checkKubernetesRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxKubernetesRedirects {
+		return fmt.Errorf("stopped after %d redirects from the Kubernetes API", maxKubernetesRedirects)
+	}
+	apiserver, err := url.Parse(kubeAPIBaseURL())
+	if err != nil {
+		return err
+	}
+	if req.URL.Scheme != apiserver.Scheme || urlHostPort(req.URL) != urlHostPort(apiserver) {
+		return fmt.Errorf("refusing to follow redirect from the Kubernetes API to %s://%s", req.URL.Scheme, req.URL.Host)
+	}
+	return nil
+}
+
+// urlHostPort returns the lowercased host and port of u, filling in the
+// scheme's default port.
+func urlHostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+// validateKubernetesService checks the injected service host and port up
+// front, so a malformed value fails readiness with a clear message instead of
+// surfacing later as a confusing dial or URL error.
+func validateKubernetesService(host, port string) error {
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("KUBERNETES_SERVICE_PORT %q is not a port number between 1 and 65535", port)
+	}
+	if net.ParseIP(host) == nil && !isValidDNSName(host) {
+		return fmt.Errorf("KUBERNETES_SERVICE_HOST %q is not an IP address or DNS name", host)
+	}
+	return nil
+}
+
+// isValidDNSName reports whether name is a hostname made of 1-63 character
+// labels of letters, digits and inner hyphens.
+func isValidDNSName(name string) bool {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
 	}
+	return true
+}
+
+// newKubernetesTransport bounds connection setup separately from the overall
+// request timeout so an unreachable apiserver fails fast.
+func newKubernetesTransport(dialTimeout time.Duration, tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = dialTimeout
+	transport.TLSClientConfig = tlsConfig
+	return transport
 }
 
 func handleIngresses(timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		format, ok := parseFormat(r.URL.Query().Get("format"))
+		if !ok {
+			localizedError(w, r, msgUnsupportedFormat, http.StatusBadRequest)
+			return
+		}
+		tagFilters, ok := parseTagFilters(r.URL.Query()["tag"])
+		if !ok {
+			localizedError(w, r, msgInvalidTagFilter, http.StatusBadRequest)
 			return
 		}
 
 		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
 
-		ingresses, err := fetchIngresses(ctx)
+		if resourceVersion := r.URL.Query().Get("resourceVersion"); resourceVersion != "" {
+			if watchUnavailable(w, r) {
+				return
+			}
+			writeIngressDelta(ctx, w, resourceVersion)
+			return
+		}
+
+		ingresses, stale, err := ingressesCache.fetchWithStaleness(ctx)
 		if err != nil {
 			log.Printf("Error fetching ingresses: %v", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if stale {
+			w.Header().Set(staleHeader, "true")
+		}
+
+		metadata, _ := ingresses["metadata"].(map[string]interface{})
+		resourceVersion := stringField(metadata, "resourceVersion")
+		ingresses = filterByTags(filterIngresses(ingresses), tagFilters)
 
 		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Cache-Control", "no-cache")
-		_ = json.NewEncoder(w).Encode(ingresses)
+		w.Header().Set("Cache-Control", apiCacheControl)
+		switch format {
+		case formatSummary, formatTable, formatBookmarks:
+			extra := filterSummariesByTags(fetchExtraSummaries(ctx), tagFilters)
+			summary := summarizeIngresses(ctx, ingresses, extra)
+			var body bytes.Buffer
+			switch format {
+			case formatTable:
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				_ = writeSummaryTable(&body, summary)
+			case formatBookmarks:
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Header().Set("Content-Disposition", `attachment; filename="`+bookmarksFilename+`"`)
+				_ = writeBookmarks(&body, summary)
+			default:
+				_ = json.NewEncoder(&body).Encode(summary)
+			}
+			if checkNotModified(w, r, weakContentETag(resourceVersion, body.Bytes())) {
+				return
+			}
+			_, _ = w.Write(body.Bytes())
+		default:
+			if currentFlags().deprecateRaw {
+				setRawDeprecationHeaders(w)
+			}
+			if checkNotModified(w, r, weakETag(resourceVersion)) {
+				return
+			}
+			_ = json.NewEncoder(w).Encode(ingresses)
+		}
 	}
 }
 
-func fetchIngresses(ctx context.Context) (map[string]interface{}, error) {
-	if kubernetesServiceHost == "" || kubernetesServicePort == "" {
-		return map[string]interface{}{"items": []interface{}{}}, nil
+// handleIngressCount counts the tiles the summary format of /api/ingresses
+// would return for the same ?tag= filters, extra tiles included, so a badge
+// agrees with the list it stands for.
+func handleIngressCount(timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tagFilters, ok := parseTagFilters(r.URL.Query()["tag"])
+		if !ok {
+			localizedError(w, r, msgInvalidTagFilter, http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		ingresses, err := ingressesCache.fetch(ctx)
+		if err != nil {
+			log.Printf("Error fetching ingresses: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ingresses = filterByTags(filterIngresses(ingresses), tagFilters)
+		extra := filterSummariesByTags(fetchExtraSummaries(ctx), tagFilters)
+		summary := summarizeIngresses(ctx, ingresses, extra)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", apiCacheControl)
+		_ = json.NewEncoder(w).Encode(map[string]int{"count": len(summary.Ingresses)})
 	}
+}
 
-	tokenBytes, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/token")
-	if err != nil {
+func fetchIngresses(ctx context.Context) (result map[string]interface{}, err error) {
+	defer func() {
+		if err != nil {
+			atomic.AddUint64(&fetchErrors, 1)
+			return
+		}
+		firstFetchDone.Store(true)
+		lastFetchTime.Store(time.Now().UnixNano())
+	}()
+
+	if err := chaos.inject(ctx); err != nil {
 		return nil, err
 	}
-	token := strings.TrimSpace(string(tokenBytes))
 
-	url := "https://" + kubernetesServiceHost + ":" + kubernetesServicePort + "/apis/networking.k8s.io/v1/ingresses"
+	if !kubeAPIConfigured() {
+		return map[string]interface{}{"items": []interface{}{}}, nil
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if len(watchNamespaces) > 0 {
+		return listNamespaces(ctx, watchNamespaces)
+	}
+	result, err = listKubernetesPages(ctx, listedResource.path())
+	if err == nil {
+		ingressListHistory.record(cacheKey(ctx), result)
+	}
+	return result, err
+}
+
+// maxListPages caps how many pages listKubernetesPages follows.
+var maxListPages = defaultMaxListPages
+
+// listKubernetesPages lists path page by page, following continue tokens and
+// merging every page's items into the first page. When maxListPages is
+// reached with pages still remaining, it returns what it has with
+// "truncated": true so clients know the view is incomplete.
+func listKubernetesPages(ctx context.Context, path string) (map[string]interface{}, error) {
+	return listKubernetesPagesAt(ctx, path, "")
+}
+
+// listKubernetesPagesAt is listKubernetesPages at exactly resourceVersion,
+// or at the latest version when it is empty.
+func listKubernetesPagesAt(ctx context.Context, path, resourceVersion string) (map[string]interface{}, error) {
+	query := url.Values{"limit": {strconv.Itoa(listPageSize)}}
+	if resourceVersion != "" {
+		query.Set("resourceVersion", resourceVersion)
+		query.Set("resourceVersionMatch", "Exact")
+	}
+
+	var result map[string]interface{}
+	items := []interface{}{}
+	for page := 1; ; page++ {
+		body, err := getKubernetesJSON(ctx, path, query)
+		if err != nil {
+			return nil, err
+		}
+		pageItems, _ := body["items"].([]interface{})
+		items = append(items, pageItems...)
+		if result == nil {
+			result = body
+		}
+
+		metadata, _ := body["metadata"].(map[string]interface{})
+		next := stringField(metadata, "continue")
+		if next == "" {
+			break
+		}
+		if maxListPages > 0 && page >= maxListPages {
+			log.Printf("Warning: listing %s stopped after %d pages (MAX_PAGES); results are truncated", path, page)
+			result["truncated"] = true
+			break
+		}
+		// The continue token carries the resourceVersion of the first page.
+		query.Del("resourceVersion")
+		query.Del("resourceVersionMatch")
+		query.Set("continue", next)
+	}
+
+	if metadata, ok := result["metadata"].(map[string]interface{}); ok {
+		merged := make(map[string]interface{}, len(metadata))
+		for key, value := range metadata {
+			if key != "continue" && key != "remainingItemCount" {
+				merged[key] = value
+			}
+		}
+		result["metadata"] = merged
+	}
+	result["items"] = items
+	return result, nil
+}
+
+// kubernetesAPIError is returned when the Kubernetes API answers with a
+// non-200 status.
+type kubernetesAPIError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *kubernetesAPIError) Error() string {
+	return "kubernetes api error: " + e.Status + " " + e.Body
+}
+
+// getKubernetesJSON fetches and decodes a JSON object from the Kubernetes API,
+// retrying transient failures while the shared retry budget allows.
+func getKubernetesJSON(ctx context.Context, path string, query url.Values) (map[string]interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := getKubernetesJSONOnce(ctx, path, query)
+		if err == nil || attempt >= kubeMaxRetries || !isRetryableKubeError(ctx, err) {
+			return result, err
+		}
+		if !kubeRetryBudget.take() {
+			return nil, err
+		}
+		if !sleepUntil(ctx, time.Now().Add(kubeRetryDelay(attempt))) {
+			return nil, err
+		}
+	}
+}
+
+func getKubernetesJSONOnce(ctx context.Context, path string, query url.Values) (map[string]interface{}, error) {
+	req, err := newKubernetesRequest(ctx, path, query)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
@@ -161,7 +714,11 @@ func fetchIngresses(ctx context.Context) (map[string]interface{}, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxIngressesBodyBytes))
-		return nil, errors.New("kubernetes api error: " + resp.Status + " " + strings.TrimSpace(string(body)))
+		return nil, &kubernetesAPIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       strings.TrimSpace(string(body)),
+		}
 	}
 
 	body, err := io.ReadAll(io.LimitReader(resp.Body, maxIngressesBodyBytes))
@@ -169,48 +726,69 @@ func fetchIngresses(ctx context.Context) (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	// UseNumber keeps large integers such as resourceVersions and ports exact
+	// when the list is re-encoded, instead of rounding them through float64.
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
 	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+	if err := decoder.Decode(&result); err != nil {
+		contentType := resp.Header.Get("Content-Type")
+		log.Printf("Unparseable response from Kubernetes API %s (%v); content-type %q, body starts with %q", path, err, contentType, bodyPrefix(body))
+		return nil, errors.New("unexpected non-JSON response from API (status " + resp.Status + ", content-type " + contentType + ")")
 	}
 
 	return result, nil
 }
 
-func handleHealth(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+// bodyPrefix returns the start of a response body for diagnostics.
+func bodyPrefix(body []byte) []byte {
+	const maxPrefix = 256
+	if len(body) > maxPrefix {
+		return body[:maxPrefix]
+	}
+	return body
 }
 
-func handleReady(w http.ResponseWriter, _ *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	if !isReady() {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_ = json.NewEncoder(w).Encode(map[string]string{"status": "not ready"})
-		return
+// newKubernetesRequest builds an authenticated GET request against path on
+// the in-cluster Kubernetes API.
+func newKubernetesRequest(ctx context.Context, path string, query url.Values) (*http.Request, error) {
+	if inCluster() && kubernetesServiceErr != nil {
+		return nil, kubernetesServiceErr
+	}
+	token, err := kubeAPIToken()
+	if err != nil {
+		return nil, err
 	}
 
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
-}
+	endpoint := kubeAPIBaseURL() + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
 
-func handleMetrics(w http.ResponseWriter, _ *http.Request) {
-	uptime := time.Since(startTime).Seconds()
-	requests := atomic.LoadUint64(&totalRequests)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("User-Agent", kubeUserAgent)
+	setImpersonation(req)
+	return req, nil
+}
 
-	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+// handleHealth reports liveness. It fails only when a background loop has
+// stopped beating (see HEARTBEAT_TIMEOUT), so Kubernetes restarts a wedged
+// process but not one that is merely not ready.
+func handleHealth(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if msg := heartbeatError(); msg != "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy", "error": msg})
+		return
+	}
 	w.WriteHeader(http.StatusOK)
-	_, _ = io.WriteString(w, "# HELP home_pager_uptime_seconds Process uptime in seconds.\n")
-	_, _ = io.WriteString(w, "# TYPE home_pager_uptime_seconds gauge\n")
-	_, _ = io.WriteString(w, "home_pager_uptime_seconds ")
-	_, _ = io.WriteString(w, strconv.FormatFloat(uptime, 'f', 0, 64))
-	_, _ = io.WriteString(w, "\n")
-	_, _ = io.WriteString(w, "# HELP home_pager_http_requests_total Total HTTP requests served.\n")
-	_, _ = io.WriteString(w, "# TYPE home_pager_http_requests_total counter\n")
-	_, _ = io.WriteString(w, "home_pager_http_requests_total ")
-	_, _ = io.WriteString(w, strconv.FormatUint(requests, 10))
-	_, _ = io.WriteString(w, "\n")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
 func withSecurityHeaders(next http.Handler) http.Handler {
@@ -224,13 +802,6 @@ func withSecurityHeaders(next http.Handler) http.Handler {
 	})
 }
 
-func withRequestMetrics(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		atomic.AddUint64(&totalRequests, 1)
-		next.ServeHTTP(w, r)
-	})
-}
-
 func getEnvDuration(name string, fallback time.Duration) time.Duration {
 	raw := strings.TrimSpace(os.Getenv(name))
 	if raw == "" {
@@ -251,20 +822,40 @@ func getEnvDuration(name string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
-func isReady() bool {
-	// Outside Kubernetes, always report ready for local/dev usage.
-	if kubernetesServiceHost == "" || kubernetesServicePort == "" {
-		return true
+func getEnvInt64(name string, fallback int64) int64 {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || parsed <= 0 {
+		return fallback
 	}
+	return parsed
+}
 
-	if httpClient == nil {
-		return false
+func getEnvBool(name string, fallback bool) bool {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return fallback
 	}
 
-	tokenBytes, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/token")
+	parsed, err := strconv.ParseBool(raw)
 	if err != nil {
-		return false
+		return fallback
 	}
+	return parsed
+}
 
-	return strings.TrimSpace(string(tokenBytes)) != ""
+// drainBeforeShutdown moves the lifecycle to draining, so /readyz fails and
+// load balancers stop routing new requests to this pod, and waits for delay
+// before the server stops accepting connections.
+func drainBeforeShutdown(delay time.Duration) {
+	lifecycle.drain()
+	if delay <= 0 {
+		return
+	}
+	log.Printf("Draining for %s before shutdown", delay)
+	time.Sleep(delay)
 }
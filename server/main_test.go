@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -38,6 +44,111 @@ func TestGetEnvDuration(t *testing.T) {
 	}
 }
 
+func TestGetEnvInt64(t *testing.T) {
+	t.Setenv("TEST_INT", "")
+	if got := getEnvInt64("TEST_INT", 7); got != 7 {
+		t.Fatalf("expected fallback for empty value, got %d", got)
+	}
+
+	t.Setenv("TEST_INT", "2048")
+	if got := getEnvInt64("TEST_INT", 7); got != 2048 {
+		t.Fatalf("expected 2048, got %d", got)
+	}
+
+	t.Setenv("TEST_INT", "-1")
+	if got := getEnvInt64("TEST_INT", 7); got != 7 {
+		t.Fatalf("expected fallback for non-positive value, got %d", got)
+	}
+
+	t.Setenv("TEST_INT", "1MB")
+	if got := getEnvInt64("TEST_INT", 7); got != 7 {
+		t.Fatalf("expected fallback for invalid value, got %d", got)
+	}
+}
+
+func TestGetEnvBool(t *testing.T) {
+	t.Setenv("TEST_BOOL", "")
+	if got := getEnvBool("TEST_BOOL", true); !got {
+		t.Fatal("expected fallback for empty value")
+	}
+
+	t.Setenv("TEST_BOOL", "true")
+	if got := getEnvBool("TEST_BOOL", false); !got {
+		t.Fatal("expected true to parse")
+	}
+
+	t.Setenv("TEST_BOOL", "0")
+	if got := getEnvBool("TEST_BOOL", true); got {
+		t.Fatal("expected 0 to parse as false")
+	}
+
+	t.Setenv("TEST_BOOL", "garbage")
+	if got := getEnvBool("TEST_BOOL", true); !got {
+		t.Fatal("expected fallback for invalid value")
+	}
+}
+
+func TestInitKubernetesClientDialTimeout(t *testing.T) {
+	prevClient, prevCAPath := httpClient, serviceAccountCAPath
+	serviceAccountCAPath = filepath.Join(t.TempDir(), "missing-ca.crt")
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing-kubeconfig"))
+	defer func() { httpClient, serviceAccountCAPath = prevClient, prevCAPath }()
+
+	initKubernetesClient(5*time.Second, 750*time.Millisecond)
+
+	if httpClient.Timeout != 5*time.Second {
+		t.Fatalf("expected overall timeout 5s, got %v", httpClient.Timeout)
+	}
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("expected *http.Transport, got %T", httpClient.Transport)
+	}
+	if transport.TLSHandshakeTimeout != 750*time.Millisecond {
+		t.Fatalf("expected TLS handshake timeout 750ms, got %v", transport.TLSHandshakeTimeout)
+	}
+	if transport.DialContext == nil {
+		t.Fatal("expected a bounded DialContext")
+	}
+}
+
+func TestInitKubernetesClientTLSMinVersion(t *testing.T) {
+	prevClient, prevCAPath, prevMin := httpClient, serviceAccountCAPath, kubeTLSMinVersion
+	defer func() { httpClient, serviceAccountCAPath, kubeTLSMinVersion = prevClient, prevCAPath, prevMin }()
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing-kubeconfig"))
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caPath, []byte("not a real cert"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		caPath string
+		raw    string
+		want   uint16
+	}{
+		{filepath.Join(t.TempDir(), "missing-ca.crt"), "1.2", tls.VersionTLS12},
+		{caPath, "1.2", tls.VersionTLS12},
+		{caPath, "TLS1.3", tls.VersionTLS13},
+	} {
+		version, err := parseTLSMinVersion(tc.raw)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.raw, err)
+		}
+		kubeTLSMinVersion = version
+		serviceAccountCAPath = tc.caPath
+		initKubernetesClient(time.Second, time.Second)
+
+		transport := httpClient.Transport.(*http.Transport)
+		if got := transport.TLSClientConfig.MinVersion; got != tc.want {
+			t.Fatalf("%s with CA %s: expected min version %x, got %x", tc.raw, tc.caPath, tc.want, got)
+		}
+	}
+
+	if _, err := parseTLSMinVersion("1.1"); err == nil {
+		t.Fatal("expected TLS 1.1 to be rejected")
+	}
+}
+
 func TestHealthAndReady(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
 	rr := httptest.NewRecorder()
@@ -61,12 +172,12 @@ func TestHealthAndReady(t *testing.T) {
 		t.Fatalf("expected 200 from /readyz, got %d", rr.Code)
 	}
 
-	var ready map[string]string
+	var ready readinessResponse
 	if err := json.Unmarshal(rr.Body.Bytes(), &ready); err != nil {
 		t.Fatalf("invalid json response from /readyz: %v", err)
 	}
-	if ready["status"] != "ready" {
-		t.Fatalf("expected status=ready from /readyz, got %q", ready["status"])
+	if ready.Status != "ready" {
+		t.Fatalf("expected status=ready from /readyz, got %q", ready.Status)
 	}
 
 	kubernetesServiceHost = "kubernetes.default.svc"
@@ -81,10 +192,137 @@ func TestHealthAndReady(t *testing.T) {
 	if rr.Code != http.StatusServiceUnavailable {
 		t.Fatalf("expected 503 from /readyz when in-cluster client is not ready, got %d", rr.Code)
 	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &ready); err != nil {
+		t.Fatalf("invalid json response from /readyz: %v", err)
+	}
+	if ready.Status != "not ready" || ready.Checks["kubeApi"].OK || ready.Checks["kubeApi"].Error == "" {
+		t.Fatalf("expected a failing kubeApi check, got %+v", ready)
+	}
+	if !ready.Checks["staticAssets"].Skipped {
+		t.Fatalf("expected staticAssets to be skipped, got %+v", ready.Checks["staticAssets"])
+	}
+}
+
+func TestKubernetesClientRefusesForeignRedirects(t *testing.T) {
+	var foreignHits atomic.Int64
+	foreign := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		foreignHits.Add(1)
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+	defer foreign.Close()
+
+	srv := withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": []interface{}{}})
+		case "/same-host":
+			http.Redirect(w, r, "/moved", http.StatusFound)
+		default:
+			http.Redirect(w, r, foreign.URL+"/steal", http.StatusFound)
+		}
+	}))
+	client := *srv.Client()
+	client.CheckRedirect = checkKubernetesRedirect
+	httpClient = &client
+
+	if _, err := getKubernetesJSON(context.Background(), "/same-host", nil); err != nil {
+		t.Fatalf("expected a redirect on the apiserver to be followed, got %v", err)
+	}
+	_, err := getKubernetesJSON(context.Background(), "/apis/networking.k8s.io/v1/ingresses", nil)
+	if err == nil || !strings.Contains(err.Error(), "refusing to follow redirect") {
+		t.Fatalf("expected a clear redirect error, got %v", err)
+	}
+	if foreignHits.Load() != 0 {
+		t.Fatal("expected the foreign host never to be contacted")
+	}
+}
+
+func TestValidateKubernetesService(t *testing.T) {
+	for _, tc := range []struct {
+		host, port string
+		ok         bool
+	}{
+		{"10.96.0.1", "443", true},
+		{"fd00::1", "6443", true},
+		{"kubernetes.default.svc", "443", true},
+		{"10.96.0.1", "https", false},
+		{"10.96.0.1", "443 ", false},
+		{"10.96.0.1", "0", false},
+		{"10.96.0.1", "70000", false},
+		{"10.96.0.1", "tcp://10.96.0.1:443", false},
+		{"https://10.96.0.1", "443", false},
+		{"kube_api", "443", false},
+		{"-kube.local", "443", false},
+		{"10.96.0.1/path", "443", false},
+	} {
+		err := validateKubernetesService(tc.host, tc.port)
+		if (err == nil) != tc.ok {
+			t.Fatalf("host %q port %q: expected ok=%v, got %v", tc.host, tc.port, tc.ok, err)
+		}
+	}
+}
+
+func TestMalformedKubernetesServiceFailsReadiness(t *testing.T) {
+	prevClient := httpClient
+	defer func() {
+		httpClient, kubernetesServiceErr = prevClient, nil
+		kubernetesServiceHost, kubernetesServicePort = "", ""
+	}()
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "tcp://10.96.0.1:443")
+
+	initKubernetesClient(time.Second, time.Second)
+
+	check := readinessChecks()["kubeApi"]
+	if check.OK || !strings.Contains(check.Error, "KUBERNETES_SERVICE_PORT") {
+		t.Fatalf("expected a failing kubeApi check naming the port, got %+v", check)
+	}
+	if _, err := fetchIngresses(context.Background()); err == nil || !strings.Contains(err.Error(), "KUBERNETES_SERVICE_PORT") {
+		t.Fatalf("expected fetches to fail with the validation error, got %v", err)
+	}
+}
+
+func TestReadinessWaitsForFirstFetch(t *testing.T) {
+	requireFirstFetch = true
+	firstFetchDone.Store(false)
+	defer func() { requireFirstFetch = false }()
+
+	if isReady() {
+		t.Fatal("expected not ready before the first fetch")
+	}
+	if _, err := fetchIngresses(context.Background()); err != nil {
+		t.Fatalf("unexpected fetch error: %v", err)
+	}
+	checks := readinessChecks()
+	if !checks["firstFetch"].OK || !isReady() {
+		t.Fatalf("expected ready after a successful fetch, got %+v", checks)
+	}
+}
+
+func TestReadyzFailsWhileDraining(t *testing.T) {
+	defer lifecycle.reset()
+
+	start := time.Now()
+	drainBeforeShutdown(20 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected drain to wait for the delay, returned after %v", elapsed)
+	}
+
+	rr := httptest.NewRecorder()
+	handleReady(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 from /readyz while draining, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handleHealth(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected /healthz to stay healthy while draining, got %d", rr.Code)
+	}
 }
 
 func TestHandleIngressesMethodAndFallback(t *testing.T) {
-	h := handleIngresses(time.Second)
+	h := withAllowedMethods(methodsGet, handleIngresses(time.Second))
 
 	req := httptest.NewRequest(http.MethodPost, "/api/ingresses", nil)
 	rr := httptest.NewRecorder()
@@ -102,6 +340,9 @@ func TestHandleIngressesMethodAndFallback(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200 for GET request without kube env, got %d", rr.Code)
 	}
+	if got := rr.Header().Get("Cache-Control"); got != defaultAPICacheControl {
+		t.Fatalf("expected default Cache-Control %q, got %q", defaultAPICacheControl, got)
+	}
 
 	var payload map[string][]any
 	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
@@ -116,49 +357,214 @@ func TestHandleIngressesMethodAndFallback(t *testing.T) {
 	}
 }
 
-func TestWithRequestMetrics(t *testing.T) {
-	initialRequests := atomic.LoadUint64(&totalRequests)
+func TestHandleIngressesCacheControl(t *testing.T) {
+	kubernetesServiceHost = ""
+	kubernetesServicePort = ""
+	apiCacheControl = "private, max-age=5"
+	defer func() { apiCacheControl = defaultAPICacheControl }()
+
+	rr := httptest.NewRecorder()
+	handleIngresses(time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses", nil))
+
+	if got := rr.Header().Get("Cache-Control"); got != "private, max-age=5" {
+		t.Fatalf("expected configured Cache-Control, got %q", got)
+	}
+}
+
+func TestHandleIngressCount(t *testing.T) {
+	setFlags(t, func(f *featureFlags) { f.hiddenHostPatterns = parseHostPatterns("*.internal.local") })
+	tagged := testIngress("monitoring", "grafana", "grafana.example.com")
+	tagged["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{tagAnnotationPrefix + "category": "infra"}
 
-	handler := withRequestMetrics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{
+				testIngress("default", "a", "a.example.com"),
+				testIngress("default", "b", "b.example.com"),
+				testIngress("default", "admin", "admin.internal.local"),
+				tagged,
+			},
+		})
 	}))
 
-	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	dir := t.TempDir()
+	writeTestFile(t, dir, "tiles.json", `[
+		{"title": "NAS", "url": "https://nas.example.com", "category": "infra"},
+		{"title": "Blog", "url": "https://blog.example.com"}
+	]`)
+	if err := reloadTiles(filepath.Join(dir, "tiles.json")); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		tilesMu.Lock()
+		fileTiles = nil
+		tilesMu.Unlock()
+	}()
+
+	count := func(target string) int {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handleIngressCount(time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", target, rr.Code, rr.Body.String())
+		}
+		var payload map[string]int
+		if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("invalid json from %s: %v", target, err)
+		}
+		return payload["count"]
+	}
+
+	if got := count("/api/ingresses/count"); got != 5 {
+		t.Fatalf("expected 3 visible ingresses and 2 file tiles, got %d", got)
+	}
+	if got := count("/api/ingresses/count?tag=category=infra"); got != 2 {
+		t.Fatalf("expected the tag filter to keep one ingress and one file tile, got %d", got)
+	}
+
 	rr := httptest.NewRecorder()
+	handleIngressCount(time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses/count?tag==x", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid tag filter, got %d", rr.Code)
+	}
+}
 
-	handler.ServeHTTP(rr, req)
+func TestFetchIngressesSendsUserAgent(t *testing.T) {
+	t.Setenv("CLUSTER_NAME", "homelab")
+	prev := kubeUserAgent
+	defer func() { kubeUserAgent = prev }()
+	kubeUserAgent = loadKubeUserAgent()
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected 200, got %d", rr.Code)
+	var got string
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.UserAgent()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": []interface{}{}})
+	}))
+
+	if _, err := fetchIngresses(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := "home-pager/dev (homelab)"; got != want {
+		t.Fatalf("expected User-Agent %q, got %q", want, got)
 	}
 
-	finalRequests := atomic.LoadUint64(&totalRequests)
-	if finalRequests != initialRequests+1 {
-		t.Errorf("expected totalRequests to be %d, got %d", initialRequests+1, finalRequests)
+	t.Setenv("KUBE_USER_AGENT", "custom/1.0")
+	if agent := loadKubeUserAgent(); agent != "custom/1.0" {
+		t.Fatalf("expected KUBE_USER_AGENT to override, got %q", agent)
 	}
 }
 
-func TestHandleMetrics(t *testing.T) {
-	// Ensure totalRequests is at least 1 for the test
-	atomic.AddUint64(&totalRequests, 1)
-	requests := atomic.LoadUint64(&totalRequests)
+func TestHandleIngressesKeepsLargeNumbersExact(t *testing.T) {
+	ingressesCache.reset()
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"metadata":{"generation":98765432109876543},"items":[{"metadata":{"namespace":"default","name":"app","generation":12345678901234567890},"spec":{"rules":[{"host":"app.example.com"}]}}]}`)
+	}))
 
-	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
 	rr := httptest.NewRecorder()
+	handleIngresses(time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{`"generation":98765432109876543`, `"generation":12345678901234567890`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s to round-trip exactly, got %s", want, body)
+		}
+	}
+	if strings.Contains(body, "e+") {
+		t.Fatalf("expected no scientific notation, got %s", body)
+	}
+}
 
-	handleMetrics(rr, req)
+func TestFetchIngressesNonJSONResponse(t *testing.T) {
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>Proxy login required</body></html>"))
+	}))
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected 200, got %d", rr.Code)
+	_, err := fetchIngresses(context.Background())
+	if err == nil {
+		t.Fatal("expected error for non-JSON response")
 	}
+	want := "unexpected non-JSON response from API (status 200 OK, content-type text/html)"
+	if err.Error() != want {
+		t.Fatalf("expected %q, got %q", want, err.Error())
+	}
+}
 
-	body := rr.Body.String()
-	expectedMetric := "home_pager_http_requests_total " + strconv.FormatUint(requests, 10)
-	if !strings.Contains(body, expectedMetric) {
-		t.Errorf("expected metric %q in output, got %q", expectedMetric, body)
+// withTestKubernetesAPI points the Kubernetes client at a fake apiserver
+// served by handler for the duration of the test.
+func withTestKubernetesAPI(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("test-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "https://"))
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if !strings.Contains(body, "home_pager_uptime_seconds") {
-		t.Error("expected uptime metric in output")
+	prevClient, prevTokenPath := httpClient, serviceAccountTokenPath
+	ingressListHistory.reset()
+	kubernetesServiceHost, kubernetesServicePort = host, port
+	httpClient = srv.Client()
+	serviceAccountTokenPath = tokenPath
+	t.Cleanup(func() {
+		kubernetesServiceHost, kubernetesServicePort = "", ""
+		httpClient, serviceAccountTokenPath = prevClient, prevTokenPath
+		ingressListHistory.reset()
+	})
+
+	return srv
+}
+
+func TestListKubernetesPagesFollowsContinue(t *testing.T) {
+	prevMax := maxListPages
+	defer func() { maxListPages = prevMax }()
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("limit"); got != strconv.Itoa(listPageSize) {
+			t.Errorf("expected limit %d, got %q", listPageSize, got)
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("continue"))
+		metadata := map[string]interface{}{"resourceVersion": "42"}
+		if page < 2 {
+			metadata["continue"] = strconv.Itoa(page + 1)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"metadata": metadata,
+			"items":    []interface{}{testIngress("default", "app-"+strconv.Itoa(page))},
+		})
+	}))
+
+	maxListPages = 10
+	result, err := listKubernetesPages(context.Background(), listedResource.path())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items := result["items"].([]interface{}); len(items) != 3 {
+		t.Fatalf("expected items from all 3 pages, got %d", len(items))
+	}
+	metadata := result["metadata"].(map[string]interface{})
+	if _, ok := metadata["continue"]; ok || metadata["resourceVersion"] != "42" || result["truncated"] != nil {
+		t.Fatalf("unexpected merged result %v", result)
+	}
+
+	maxListPages = 2
+	result, err = listKubernetesPages(context.Background(), listedResource.path())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items := result["items"].([]interface{}); len(items) != 2 || result["truncated"] != true {
+		t.Fatalf("expected 2 items and truncated=true, got %d items, truncated=%v", len(items), result["truncated"])
+	}
+	if !summarizeIngresses(context.Background(), result, nil).Truncated {
+		t.Fatal("expected the summary to be marked truncated")
 	}
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// maintenanceFile enables maintenance mode while the file exists, so it can
+// be toggled by mounting or removing a file without a restart.
+var maintenanceFile string
+
+// maintenanceExemptPaths keep responding during maintenance so Kubernetes
+// does not restart or deregister the pod.
+var maintenanceExemptPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+func inMaintenance() bool {
+	if currentFlags().maintenance {
+		return true
+	}
+	if maintenanceFile == "" {
+		return false
+	}
+	_, err := os.Stat(maintenanceFile)
+	return err == nil
+}
+
+func wantsJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") || strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// withMaintenance answers every non-probe request with 503 while maintenance
+// mode is active.
+func withMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maintenanceExemptPaths[r.URL.Path] || !inMaintenance() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		message := translate(r, msgMaintenance)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Retry-After", "300")
+		w.Header().Add("Vary", "Accept, Accept-Language")
+
+		if wantsJSON(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "maintenance", "message": message})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "<!doctype html>\n<html lang=\""+negotiateLanguage(r.Header.Get("Accept-Language"))+"\">\n<head><meta charset=\"UTF-8\" /><title>"+html.EscapeString(message)+"</title></head>\n<body><main><h1>"+html.EscapeString(message)+"</h1></main></body>\n</html>\n")
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithMaintenance(t *testing.T) {
+	handler := withMaintenance(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := serve("/", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 outside maintenance, got %d", rr.Code)
+	}
+
+	setFlags(t, func(f *featureFlags) { f.maintenance = true })
+
+	rr := serve("/", "text/html")
+	if rr.Code != http.StatusServiceUnavailable || !strings.Contains(rr.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("expected HTML 503 for UI, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	rr = serve("/api/ingresses", "")
+	if rr.Code != http.StatusServiceUnavailable || !strings.Contains(rr.Body.String(), `"status":"maintenance"`) {
+		t.Fatalf("expected JSON 503 for API, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	for _, probe := range []string{"/healthz", "/readyz"} {
+		if rr := serve(probe, ""); rr.Code != http.StatusOK {
+			t.Fatalf("expected %s to bypass maintenance, got %d", probe, rr.Code)
+		}
+	}
+}
+
+func TestMaintenanceFile(t *testing.T) {
+	maintenanceFile = filepath.Join(t.TempDir(), "maintenance")
+	defer func() { maintenanceFile = "" }()
+
+	if inMaintenance() {
+		t.Fatal("expected no maintenance while file is absent")
+	}
+	if err := os.WriteFile(maintenanceFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !inMaintenance() {
+		t.Fatal("expected maintenance while file exists")
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/subtle"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var startTime = time.Now()
+var fetchErrors uint64
+
+// responseSizeBuckets spans small probe replies up to multi-megabyte ingress
+// lists.
+var responseSizeBuckets = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+
+// metricsToken, when set, is the bearer token required to scrape /metrics.
+var metricsToken string
+
+// resetMetrics zeroes the process-wide counters so tests can assert exact
+// values without depending on fetches made by earlier tests. Request metrics
+// belong to each server's serverMetrics.
+func resetMetrics() {
+	atomic.StoreUint64(&fetchErrors, 0)
+	resetConfigReloadStatus()
+	namespaceFetches.reset()
+}
+
+// Metric profiles for METRICS_PROFILE, from least to most detailed.
+const (
+	metricsProfileMinimal = iota
+	metricsProfileStandard
+	metricsProfileFull
+)
+
+// metricsProfile limits which metric families /metrics exposes. minimal is
+// uptime and request count; standard adds unlabeled operational metrics;
+// full adds families labeled by path class, cluster or health result, which
+// some operators consider too revealing.
+var metricsProfile = metricsProfileFull
+
+// parseMetricsProfile maps METRICS_PROFILE to a profile, defaulting to full.
+func parseMetricsProfile(raw string) int {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "minimal":
+		return metricsProfileMinimal
+	case "standard":
+		return metricsProfileStandard
+	case "", "full":
+		return metricsProfileFull
+	default:
+		log.Printf("Warning: invalid METRICS_PROFILE %q; expected minimal, standard or full, using full", raw)
+		return metricsProfileFull
+	}
+}
+
+// serverMetrics is the request metrics of one server: the counters behind
+// /metrics, /api/stats, /status and StatsD for the requests that server
+// handled. Kubernetes fetches, background workers and the lifecycle phase
+// belong to the process and are shared.
+type serverMetrics struct {
+	requests     atomic.Uint64
+	duration     *histogram
+	responseSize *histogramVec
+	recent       *rollingStats
+}
+
+// newServerMetrics creates the metrics for one server, with buckets as the
+// request latency bounds.
+func newServerMetrics(buckets []float64) *serverMetrics {
+	return &serverMetrics{
+		duration: newHistogram(buckets),
+		// responseSize records bytes written to the client, after
+		// compression, labeled by pathClass.
+		responseSize: newHistogramVec("class", responseSizeBuckets),
+		recent:       newRollingStats(),
+	}
+}
+
+// middleware records every request next serves.
+func (m *serverMetrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.requests.Add(1)
+		start := time.Now()
+		cw := &countingResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r)
+		elapsed := time.Since(start)
+		m.duration.observe(elapsed.Seconds())
+		m.responseSize.observe(pathClass(r.URL.Path), float64(cw.bytes))
+		m.recent.record(elapsed, cw.statusCode())
+	})
+}
+
+// handleMetrics serves /metrics, limited to the families metricsProfile
+// exposes.
+func (m *serverMetrics) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	writeUptimeMetrics(w)
+	_, _ = io.WriteString(w, "# HELP home_pager_http_requests_total Total HTTP requests served.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_http_requests_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_http_requests_total ")
+	_, _ = io.WriteString(w, strconv.FormatUint(m.requests.Load(), 10))
+	_, _ = io.WriteString(w, "\n")
+	if metricsProfile < metricsProfileStandard {
+		return
+	}
+
+	writeFetchErrorMetrics(w)
+	m.duration.write(w, "home_pager_http_request_duration_seconds", "HTTP request latency in seconds.")
+	backgroundPool.write(w)
+	kubeRetryBudget.write(w)
+	lifecycle.write(w)
+	writeStreamMetrics(w)
+	writeConfigReloadMetrics(w)
+	if metricsProfile < metricsProfileFull {
+		return
+	}
+
+	m.responseSize.write(w, "home_pager_response_bytes", "HTTP response body size in bytes by path class.")
+	namespaceFetches.write(w)
+	if healthChecks != nil {
+		healthChecks.write(w)
+	}
+	if audit != nil {
+		audit.write(w)
+	}
+}
+
+func writeUptimeMetrics(w io.Writer) {
+	_, _ = io.WriteString(w, "# HELP home_pager_uptime_seconds Process uptime in seconds.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_uptime_seconds gauge\n")
+	_, _ = io.WriteString(w, "home_pager_uptime_seconds ")
+	_, _ = io.WriteString(w, strconv.FormatFloat(time.Since(startTime).Seconds(), 'f', 0, 64))
+	_, _ = io.WriteString(w, "\n")
+}
+
+func writeFetchErrorMetrics(w io.Writer) {
+	_, _ = io.WriteString(w, "# HELP home_pager_fetch_errors_total Failed Kubernetes API fetches.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_fetch_errors_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_fetch_errors_total ")
+	_, _ = io.WriteString(w, strconv.FormatUint(atomic.LoadUint64(&fetchErrors), 10))
+	_, _ = io.WriteString(w, "\n")
+}
+
+func writeConfigReloadMetrics(w io.Writer) {
+	lastReload, _ := configReloadStatus()
+	var lastReloadSeconds int64
+	if !lastReload.IsZero() {
+		lastReloadSeconds = lastReload.Unix()
+	}
+	_, _ = io.WriteString(w, "# HELP home_pager_config_reloads_total Config reload attempts by result.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_config_reloads_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_config_reloads_total{result=\"success\"} ")
+	_, _ = io.WriteString(w, strconv.FormatUint(atomic.LoadUint64(&configReloadSuccesses), 10))
+	_, _ = io.WriteString(w, "\n")
+	_, _ = io.WriteString(w, "home_pager_config_reloads_total{result=\"failure\"} ")
+	_, _ = io.WriteString(w, strconv.FormatUint(atomic.LoadUint64(&configReloadFailures), 10))
+	_, _ = io.WriteString(w, "\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_config_last_reload_timestamp_seconds Unix time of the last config reload attempt.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_config_last_reload_timestamp_seconds gauge\n")
+	_, _ = io.WriteString(w, "home_pager_config_last_reload_timestamp_seconds ")
+	_, _ = io.WriteString(w, strconv.FormatInt(lastReloadSeconds, 10))
+	_, _ = io.WriteString(w, "\n")
+}
+
+// requireBearerToken rejects requests that do not present token as a bearer
+// token. An empty token leaves the handler open.
+func requireBearerToken(token *string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if *token == "" {
+			next(w, r)
+			return
+		}
+
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(presented)), []byte(*token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			localizedError(w, r, msgUnauthorized, http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// latencyBuckets parses LATENCY_BUCKETS, falling back to the defaults when the
+// value is unset or invalid.
+func latencyBuckets(raw string) []float64 {
+	if strings.TrimSpace(raw) == "" {
+		return defaultLatencyBuckets
+	}
+	buckets, err := parseBuckets(raw)
+	if err != nil {
+		log.Printf("Warning: invalid LATENCY_BUCKETS %q: %v; using defaults", raw, err)
+		return defaultLatencyBuckets
+	}
+	return buckets
+}
+
+// pathClass groups request paths into a small fixed set of metric labels so
+// arbitrary URLs cannot blow up series cardinality.
+func pathClass(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/"):
+		return "api"
+	case path == "/metrics":
+		return "metrics"
+	case path == "/healthz" || path == "/readyz":
+		return "probe"
+	case strings.HasPrefix(path, redirectsPathPrefix):
+		return "redirect"
+	default:
+		return "static"
+	}
+}
+
+// countingResponseWriter counts the body bytes written through it and
+// remembers the response status.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	bytes  int64
+	status int
+}
+
+func (w *countingResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *countingResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *countingResponseWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *countingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRequestMetrics(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+
+	handler := metrics.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
+	}
+
+	if got := metrics.requests.Load(); got != 1 {
+		t.Errorf("expected totalRequests to be 1, got %d", got)
+	}
+
+	rr = httptest.NewRecorder()
+	metrics.handleMetrics(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rr.Body.String(), "home_pager_http_request_duration_seconds_count 1") {
+		t.Errorf("expected one latency observation, got %q", rr.Body.String())
+	}
+}
+
+func TestServerMetricsAreIndependent(t *testing.T) {
+	first := newServerMetrics(defaultLatencyBuckets)
+	second := newServerMetrics(defaultLatencyBuckets)
+	serve := func(m *serverMetrics, n int) {
+		handler := m.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		for i := 0; i < n; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/ingresses", nil))
+		}
+	}
+	serve(first, 2)
+	serve(second, 5)
+
+	for _, tc := range []struct {
+		metrics *serverMetrics
+		want    int
+	}{{first, 2}, {second, 5}} {
+		rr := httptest.NewRecorder()
+		tc.metrics.handleMetrics(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if want := "home_pager_http_requests_total " + strconv.Itoa(tc.want) + "\n"; !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("expected %q in /metrics, got %q", want, rr.Body.String())
+		}
+
+		rr = httptest.NewRecorder()
+		tc.metrics.handleStats(rr, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+		if want := `"requests":` + strconv.Itoa(tc.want) + `,`; !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("expected %s in /api/stats, got %s", want, rr.Body.String())
+		}
+
+		if got := tc.metrics.buildStatusPage(time.Now()).Requests; got != uint64(tc.want) {
+			t.Errorf("expected %d requests on the status page, got %d", tc.want, got)
+		}
+	}
+}
+
+func TestResponseSizeMetrics(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+	defer resetMetrics()
+
+	handler := metrics.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 2000))
+	}))
+	for _, path := range []string{"/api/ingresses", "/api/config", "/index.html"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	metrics.handleMetrics(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`home_pager_response_bytes_bucket{class="api",le="1024"} 0`,
+		`home_pager_response_bytes_bucket{class="api",le="4096"} 2`,
+		`home_pager_response_bytes_sum{class="api"} 4000`,
+		`home_pager_response_bytes_count{class="static"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in metrics output", want)
+		}
+	}
+}
+
+func TestPathClass(t *testing.T) {
+	cases := map[string]string{
+		"/api/ingresses": "api",
+		"/metrics":       "metrics",
+		"/readyz":        "probe",
+		"/go/nas":        "redirect",
+		"/js/app.js":     "static",
+	}
+	for path, want := range cases {
+		if got := pathClass(path); got != want {
+			t.Errorf("pathClass(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLatencyBuckets(t *testing.T) {
+	if got := latencyBuckets(""); len(got) != len(defaultLatencyBuckets) {
+		t.Fatalf("expected default buckets, got %v", got)
+	}
+	if got := latencyBuckets("0.5,0.1"); len(got) != len(defaultLatencyBuckets) {
+		t.Fatalf("expected default buckets for unsorted input, got %v", got)
+	}
+	if got := latencyBuckets("0.01,0.05"); len(got) != 2 {
+		t.Fatalf("expected configured buckets, got %v", got)
+	}
+}
+
+func TestHandleMetrics(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+	metrics.requests.Add(3)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rr := httptest.NewRecorder()
+
+	metrics.handleMetrics(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", rr.Code)
+	}
+
+	body := rr.Body.String()
+	expectedMetric := "home_pager_http_requests_total 3"
+	if !strings.Contains(body, expectedMetric) {
+		t.Errorf("expected metric %q in output, got %q", expectedMetric, body)
+	}
+
+	if !strings.Contains(body, "home_pager_uptime_seconds") {
+		t.Error("expected uptime metric in output")
+	}
+}
+
+func TestMetricsProfile(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+	defer func() { metricsProfile = metricsProfileFull }()
+
+	families := map[string]int{
+		"home_pager_http_requests_total":           metricsProfileMinimal,
+		"home_pager_fetch_errors_total":            metricsProfileStandard,
+		"home_pager_http_request_duration_seconds": metricsProfileStandard,
+		"home_pager_config_reloads_total":          metricsProfileStandard,
+		"home_pager_response_bytes":                metricsProfileFull,
+		"home_pager_namespace_fetch_errors_total":  metricsProfileFull,
+	}
+	for _, profile := range []string{"minimal", "standard", "full"} {
+		metricsProfile = parseMetricsProfile(profile)
+		rr := httptest.NewRecorder()
+		metrics.handleMetrics(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		body := rr.Body.String()
+		for family, minProfile := range families {
+			want := metricsProfile >= minProfile
+			if got := strings.Contains(body, "# TYPE "+family); got != want {
+				t.Errorf("profile %s: family %s present = %v, want %v", profile, family, got, want)
+			}
+		}
+	}
+
+	if got := parseMetricsProfile("verbose"); got != metricsProfileFull {
+		t.Errorf("expected invalid profile to fall back to full, got %d", got)
+	}
+}
+
+func TestMetricsBearerToken(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	metricsToken = "s3cret"
+	defer func() { metricsToken = "" }()
+
+	handler := requireBearerToken(&metricsToken, metrics.handleMetrics)
+
+	cases := []struct {
+		name   string
+		header string
+		code   int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "Basic s3cret", http.StatusUnauthorized},
+		{"valid token", "Bearer s3cret", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.code {
+				t.Fatalf("expected %d, got %d", tc.code, rr.Code)
+			}
+		})
+	}
+
+	metricsToken = ""
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected open /metrics without token configured, got %d", rr.Code)
+	}
+}
+
+func TestNamespaceFetchMetrics(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+	watchNamespaces = parseNamespaceList(`media,broken`)
+	defer func() { watchNamespaces = nil }()
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/namespaces/broken/") {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+
+	before := time.Now().Unix()
+	if _, err := fetchIngresses(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	metrics.handleMetrics(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+	for _, want := range []string{
+		`home_pager_namespace_fetch_errors_total{namespace="broken"} 1`,
+		`home_pager_namespace_fetch_errors_total{namespace="media"} 0`,
+		`home_pager_namespace_last_fetch_timestamp_seconds{namespace="broken"} 0`,
+		`home_pager_fetch_errors_total 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in output, got %q", want, body)
+		}
+	}
+
+	prefix := `home_pager_namespace_last_fetch_timestamp_seconds{namespace="media"} `
+	for _, line := range strings.Split(body, "\n") {
+		if value, ok := strings.CutPrefix(line, prefix); ok {
+			if seconds, _ := strconv.ParseInt(value, 10, 64); seconds < before {
+				t.Fatalf("expected a timestamp >= %d, got %q", before, line)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected a last fetch timestamp, got %q", body)
+}
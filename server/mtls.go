@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// requireClientCert makes withClientCert reject non-probe requests without a
+// verified client certificate. It is set when CLIENT_CA_FILE is used with
+// CLIENT_CERT_EXEMPT_PROBES, where the handshake itself cannot require a
+// certificate without also locking out the kubelet.
+var requireClientCert bool
+
+type clientCertContextKey struct{}
+
+// loadServerTLSConfig builds the listener's TLS config from TLS_CERT_FILE and
+// TLS_KEY_FILE, returning nil to serve plain HTTP when neither is set. With
+// CLIENT_CA_FILE, clients must present a certificate signed by one of its CAs.
+func loadServerTLSConfig() (*tls.Config, error) {
+	certFile := strings.TrimSpace(os.Getenv("TLS_CERT_FILE"))
+	keyFile := strings.TrimSpace(os.Getenv("TLS_KEY_FILE"))
+	caFile := strings.TrimSpace(os.Getenv("CLIENT_CA_FILE"))
+	requireClientCert = false
+
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, errors.New("CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if caFile == "" {
+		return config, nil
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in CLIENT_CA_FILE %s", caFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if getEnvBool("CLIENT_CERT_EXEMPT_PROBES", false) {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		requireClientCert = true
+	}
+	return config, nil
+}
+
+// clientCertCN returns the common name of the verified client certificate,
+// or "" when the connection did not present one.
+func clientCertCN(ctx context.Context) string {
+	cn, _ := ctx.Value(clientCertContextKey{}).(string)
+	return cn
+}
+
+// withClientCert records the verified client certificate's common name in
+// the request context for the audit log, and enforces client certificates for
+// non-probe requests when probes are exempt from the handshake requirement.
+func withClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			if requireClientCert && pathClass(r.URL.Path) != "probe" {
+				localizedError(w, r, msgUnauthorized, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientCertContextKey{}, cn)))
+	})
+}
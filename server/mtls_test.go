@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+// newTestCert issues a certificate for cn, signed by parent or self-signed
+// when parent is nil.
+func newTestCert(t *testing.T, cn string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+func startMTLSServer(t *testing.T, exemptProbes bool) (*httptest.Server, *testCert, *testCert) {
+	t.Helper()
+	ca := newTestCert(t, "test-ca", nil, 0)
+	serverCert := newTestCert(t, "home-pager", ca, x509.ExtKeyUsageServerAuth)
+	clientCert := newTestCert(t, "mesh-gateway", ca, x509.ExtKeyUsageClientAuth)
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "ca.crt", ca.certPEM)
+	writeTestFile(t, dir, "tls.crt", serverCert.certPEM)
+	writeTestFile(t, dir, "tls.key", serverCert.keyPEM)
+	t.Setenv("TLS_CERT_FILE", filepath.Join(dir, "tls.crt"))
+	t.Setenv("TLS_KEY_FILE", filepath.Join(dir, "tls.key"))
+	t.Setenv("CLIENT_CA_FILE", filepath.Join(dir, "ca.crt"))
+	if exemptProbes {
+		t.Setenv("CLIENT_CERT_EXEMPT_PROBES", "true")
+	}
+
+	config, err := loadServerTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { requireClientCert = false })
+
+	srv := httptest.NewUnstartedServer(withClientCert(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(clientCertCN(r.Context())))
+	})))
+	srv.TLS = config
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, ca, clientCert
+}
+
+func mtlsClient(ca, client *testCert) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: roots}
+	if client != nil {
+		config.Certificates = []tls.Certificate{{Certificate: [][]byte{client.cert.Raw}, PrivateKey: client.key}}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+func TestMutualTLSRequiresClientCertificate(t *testing.T) {
+	srv, ca, clientCert := startMTLSServer(t, false)
+
+	resp, err := mtlsClient(ca, clientCert).Get(srv.URL + "/api/ingresses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body[:n]) != "mesh-gateway" {
+		t.Fatalf("expected the client CN in the request context, got %d %q", resp.StatusCode, body[:n])
+	}
+
+	if resp, err := mtlsClient(ca, nil).Get(srv.URL + "/healthz"); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the handshake to fail without a client certificate")
+	}
+}
+
+func TestMutualTLSExemptProbes(t *testing.T) {
+	srv, ca, _ := startMTLSServer(t, true)
+	client := mtlsClient(ca, nil)
+
+	resp, err := client.Get(srv.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected probes to work without a client certificate, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.Get(srv.URL + "/api/ingresses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for other paths without a client certificate, got %d", resp.StatusCode)
+	}
+}
+
+func TestLoadServerTLSConfigValidation(t *testing.T) {
+	t.Setenv("TLS_CERT_FILE", "")
+	t.Setenv("TLS_KEY_FILE", "")
+	t.Setenv("CLIENT_CA_FILE", "")
+	if config, err := loadServerTLSConfig(); config != nil || err != nil {
+		t.Fatalf("expected plain HTTP by default, got %v, %v", config, err)
+	}
+
+	t.Setenv("CLIENT_CA_FILE", "/ca.crt")
+	if _, err := loadServerTLSConfig(); err == nil {
+		t.Fatal("expected CLIENT_CA_FILE without a server certificate to fail")
+	}
+}
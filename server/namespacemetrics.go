@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// namespaceFetches records each WATCH_NAMESPACES list separately, so a
+// namespace that keeps failing shows up even though the merged list, and so
+// home_pager_fetch_errors_total, still succeeds.
+var namespaceFetches = newNamespaceFetchStats()
+
+// namespaceFetchStats tracks fetch freshness and failures per namespace.
+type namespaceFetchStats struct {
+	mu     sync.Mutex
+	series map[string]*namespaceFetchSeries
+}
+
+type namespaceFetchSeries struct {
+	lastSuccess time.Time
+	errors      uint64
+}
+
+func newNamespaceFetchStats() *namespaceFetchStats {
+	return &namespaceFetchStats{series: make(map[string]*namespaceFetchSeries)}
+}
+
+func (s *namespaceFetchStats) get(namespace string) *namespaceFetchSeries {
+	series, ok := s.series[namespace]
+	if !ok {
+		series = &namespaceFetchSeries{}
+		s.series[namespace] = series
+	}
+	return series
+}
+
+func (s *namespaceFetchStats) recordSuccess(namespace string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(namespace).lastSuccess = at
+}
+
+func (s *namespaceFetchStats) recordError(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(namespace).errors++
+}
+
+func (s *namespaceFetchStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.series = make(map[string]*namespaceFetchSeries)
+}
+
+// write renders both metric families, sorted by namespace. A namespace that
+// has never been listed successfully reports a timestamp of 0.
+func (s *namespaceFetchStats) write(w io.Writer) {
+	s.mu.Lock()
+	namespaces := make([]string, 0, len(s.series))
+	for namespace := range s.series {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	series := make([]namespaceFetchSeries, len(namespaces))
+	for i, namespace := range namespaces {
+		series[i] = *s.series[namespace]
+	}
+	s.mu.Unlock()
+
+	_, _ = io.WriteString(w, "# HELP home_pager_namespace_last_fetch_timestamp_seconds Unix time of the last successful list per WATCH_NAMESPACES namespace.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_namespace_last_fetch_timestamp_seconds gauge\n")
+	for i, namespace := range namespaces {
+		var seconds int64
+		if !series[i].lastSuccess.IsZero() {
+			seconds = series[i].lastSuccess.Unix()
+		}
+		_, _ = io.WriteString(w, "home_pager_namespace_last_fetch_timestamp_seconds{namespace=\""+escapeLabelValue(namespace)+"\"} "+strconv.FormatInt(seconds, 10)+"\n")
+	}
+	_, _ = io.WriteString(w, "# HELP home_pager_namespace_fetch_errors_total Failed Kubernetes API lists per WATCH_NAMESPACES namespace.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_namespace_fetch_errors_total counter\n")
+	for i, namespace := range namespaces {
+		_, _ = io.WriteString(w, "home_pager_namespace_fetch_errors_total{namespace=\""+escapeLabelValue(namespace)+"\"} "+strconv.FormatUint(series[i].errors, 10)+"\n")
+	}
+}
+
+// escapeLabelValue escapes a Prometheus label value for the text format.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// watchNamespaces limits listing to these namespaces, fetched in
+	// parallel. Empty means one cluster-wide list.
+	watchNamespaces []string
+
+	// namespaceTimeout bounds each namespace's list separately so one slow
+	// namespace cannot use up the whole request deadline.
+	namespaceTimeout time.Duration
+)
+
+// parseNamespaceList splits a comma-separated list of namespaces into a
+// sorted list without duplicates.
+func parseNamespaceList(raw string) []string {
+	set := parseNameSet(raw)
+	namespaces := make([]string, 0, len(set))
+	for namespace := range set {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// namespacedPath is the list path of the resource within one namespace.
+func (r apiResource) namespacedPath(namespace string) string {
+	prefix := "/apis/" + r.group + "/" + r.version
+	if r.group == "" {
+		prefix = "/api/" + r.version
+	}
+	return prefix + "/namespaces/" + namespace + "/" + r.name
+}
+
+type namespaceList struct {
+	result map[string]interface{}
+	err    error
+}
+
+// listNamespaces lists each namespace concurrently with its own context and
+// merges the items in namespace order. Failed namespaces are reported in a
+// "warnings" array instead of failing the whole list; only when every
+// namespace fails is an error returned.
+func listNamespaces(ctx context.Context, namespaces []string) (map[string]interface{}, error) {
+	lists := make([]namespaceList, len(namespaces))
+	var wg sync.WaitGroup
+	for i, namespace := range namespaces {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nsCtx, cancel := context.WithCancel(ctx)
+			if namespaceTimeout > 0 {
+				nsCtx, cancel = context.WithTimeout(ctx, namespaceTimeout)
+			}
+			defer cancel()
+			lists[i].result, lists[i].err = listKubernetesPages(nsCtx, listedResource.namespacedPath(namespace))
+		}()
+	}
+	wg.Wait()
+
+	items := []interface{}{}
+	var warnings []interface{}
+	var errs []error
+	truncated := false
+	now := time.Now()
+	for i, list := range lists {
+		if list.err != nil {
+			namespaceFetches.recordError(namespaces[i])
+			log.Printf("Error listing namespace %s: %v", namespaces[i], list.err)
+			warnings = append(warnings, fmt.Sprintf("namespace %s: %v", namespaces[i], list.err))
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespaces[i], list.err))
+			continue
+		}
+		namespaceFetches.recordSuccess(namespaces[i], now)
+		pageItems, _ := list.result["items"].([]interface{})
+		items = append(items, pageItems...)
+		truncated = truncated || list.result["truncated"] == true
+	}
+	if len(errs) == len(namespaces) && len(namespaces) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	result := map[string]interface{}{
+		"kind":       "List",
+		"apiVersion": "v1",
+		"metadata":   map[string]interface{}{},
+		"items":      items,
+	}
+	if truncated {
+		result["truncated"] = true
+	}
+	if len(warnings) > 0 {
+		result["warnings"] = warnings
+	}
+	return result, nil
+}
+
+// watchUnavailable answers 501 for the watch-based endpoints (the stream,
+// resourceVersion polling and the diff) when WATCH_NAMESPACES is set. The
+// merged list has no resourceVersion to resume from, and a cluster-wide watch
+// would need the cluster-wide RBAC namespace mode avoids and would send
+// ingresses from namespaces outside the list.
+func watchUnavailable(w http.ResponseWriter, r *http.Request) bool {
+	if len(watchNamespaces) == 0 {
+		return false
+	}
+	localizedError(w, r, msgWatchUnavailable, http.StatusNotImplemented)
+	return true
+}
+
+// resultWarnings returns the warnings attached to a list by listNamespaces.
+func resultWarnings(result map[string]interface{}) []string {
+	raw, _ := result["warnings"].([]interface{})
+	var warnings []string
+	for _, warning := range raw {
+		if text, ok := warning.(string); ok && strings.TrimSpace(text) != "" {
+			warnings = append(warnings, text)
+		}
+	}
+	return warnings
+}
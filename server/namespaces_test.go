@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchIngressesIsolatesSlowNamespaces(t *testing.T) {
+	watchNamespaces = parseNamespaceList("media,slow,default,media")
+	namespaceTimeout = 100 * time.Millisecond
+	defer func() {
+		watchNamespaces = nil
+		namespaceTimeout = 0
+	}()
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace := strings.Split(strings.TrimPrefix(r.URL.Path, "/apis/networking.k8s.io/v1/namespaces/"), "/")[0]
+		if namespace == "slow" {
+			<-r.Context().Done()
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{testIngress(namespace, "app", namespace+".example.com")},
+		})
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	result, err := fetchIngresses(ctx)
+	if err != nil {
+		t.Fatalf("expected the healthy namespaces to be returned, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the slow namespace to be cut off by its own timeout, took %v", elapsed)
+	}
+
+	items := result["items"].([]interface{})
+	if len(items) != 2 {
+		t.Fatalf("expected items from default and media, got %d", len(items))
+	}
+	warnings := resultWarnings(result)
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "namespace slow:") {
+		t.Fatalf("expected one warning for the slow namespace, got %v", warnings)
+	}
+	if summary := summarizeIngresses(context.Background(), result, nil); len(summary.Warnings) != 1 || summary.Count != 2 {
+		t.Fatalf("expected warnings in the summary envelope, got %+v", summary)
+	}
+}
+
+func TestFetchIngressesFailsWhenEveryNamespaceFails(t *testing.T) {
+	watchNamespaces = parseNamespaceList("a,b")
+	defer func() { watchNamespaces = nil }()
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+
+	if _, err := fetchIngresses(context.Background()); err == nil {
+		t.Fatal("expected an error when no namespace could be listed")
+	}
+}
+
+func TestWatchEndpointsUnavailableInNamespaceMode(t *testing.T) {
+	watchNamespaces = parseNamespaceList("media")
+	defer func() { watchNamespaces = nil }()
+
+	for path, handler := range map[string]http.Handler{
+		"/api/ingresses?resourceVersion=5": handleIngresses(time.Second),
+		"/api/ingresses/stream":            handleIngressStream(time.Second),
+		"/api/ingresses/diff?from=5":       handleIngressDiff(time.Second),
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != http.StatusNotImplemented {
+			t.Errorf("%s: expected 501, got %d", path, rr.Code)
+		}
+	}
+}
+
+func TestPartialNamespaceListsAreNotCached(t *testing.T) {
+	var calls int
+	c := &ingressCache{ttl: time.Minute, fetcher: func(context.Context) (map[string]interface{}, error) {
+		calls++
+		return map[string]interface{}{"items": []interface{}{}, "warnings": []interface{}{"namespace slow: timeout"}}, nil
+	}}
+
+	for range 2 {
+		if _, err := c.fetch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("expected a partial list to be refetched, got %d fetches", calls)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	// requireFirstFetch holds readiness until the ingress list has been
+	// fetched once; it is set when CACHE_PREWARM starts fetching at boot.
+	requireFirstFetch bool
+
+	// firstFetchDone records that an ingress fetch has succeeded.
+	firstFetchDone atomic.Bool
+)
+
+// readinessCheck is the result of one readiness condition. Skipped checks do
+// not apply to this configuration and always count as ok.
+type readinessCheck struct {
+	OK      bool   `json:"ok"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string                    `json:"status"`
+	Phase  string                    `json:"phase"`
+	Checks map[string]readinessCheck `json:"checks"`
+}
+
+func checkResult(err error) readinessCheck {
+	if err != nil {
+		return readinessCheck{Error: err.Error()}
+	}
+	return readinessCheck{OK: true}
+}
+
+var skippedCheck = readinessCheck{OK: true, Skipped: true}
+
+// readinessChecks evaluates every readiness condition by name.
+func readinessChecks() map[string]readinessCheck {
+	checks := make(map[string]readinessCheck, 5)
+
+	checks["shutdown"] = readinessCheck{OK: true}
+	if lifecycle.current() == phaseDraining {
+		checks["shutdown"] = readinessCheck{Error: "draining before shutdown"}
+	}
+
+	checks["staticAssets"] = skippedCheck
+	if requireStaticAssets {
+		var err error
+		if !staticAssetsPresent(staticFS) {
+			err = errors.New(strings.TrimPrefix(staticIndexFile, "/") + " missing or incomplete")
+		}
+		checks["staticAssets"] = checkResult(err)
+	}
+
+	// Outside Kubernetes, the API checks do not apply for local/dev usage.
+	switch {
+	case !kubeAPIConfigured():
+		checks["kubeApi"] = skippedCheck
+		checks["token"] = skippedCheck
+	case !inCluster():
+		// A kubeconfig carries its own credentials instead of a token file.
+		checks["kubeApi"] = checkResult(kubeAPIClientError())
+		checks["token"] = skippedCheck
+	default:
+		checks["kubeApi"] = checkResult(kubeAPIClientError())
+		checks["token"] = checkResult(serviceAccountTokenError())
+	}
+
+	checks["firstFetch"] = skippedCheck
+	if requireFirstFetch {
+		var err error
+		if !firstFetchDone.Load() {
+			err = errors.New("ingresses not fetched yet")
+		}
+		checks["firstFetch"] = checkResult(err)
+	}
+
+	return checks
+}
+
+func kubeAPIClientError() error {
+	if inCluster() && kubernetesServiceErr != nil {
+		return kubernetesServiceErr
+	}
+	if httpClient == nil {
+		return errors.New("kubernetes client not initialized")
+	}
+	return nil
+}
+
+func serviceAccountTokenError() error {
+	tokenBytes, err := os.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(tokenBytes)) == "" {
+		return errors.New("service account token is empty")
+	}
+	return nil
+}
+
+func allChecksOK(checks map[string]readinessCheck) bool {
+	for _, check := range checks {
+		if !check.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateReadiness runs the readiness checks and advances the lifecycle
+// from starting to ready once they first pass. The pod is ready only in the
+// ready phase with every check passing.
+func evaluateReadiness() (lifecyclePhase, map[string]readinessCheck, bool) {
+	checks := readinessChecks()
+	lifecycle.markReady(checks)
+	phase := lifecycle.current()
+	return phase, checks, phase == phaseReady && allChecksOK(checks)
+}
+
+func isReady() bool {
+	_, _, ready := evaluateReadiness()
+	return ready
+}
+
+func handleReady(w http.ResponseWriter, _ *http.Request) {
+	phase, checks, ready := evaluateReadiness()
+	response := readinessResponse{Status: "ready", Phase: phase.String(), Checks: checks}
+	code := http.StatusOK
+	if !ready {
+		response.Status = "not ready"
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(response)
+}
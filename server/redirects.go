@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const redirectsPathPrefix = "/go/"
+
+var (
+	// redirectsFile is the REDIRECTS_FILE path; empty disables /go/ shortcuts.
+	redirectsFile string
+
+	redirectsMu     sync.RWMutex
+	redirectTargets map[string]string
+)
+
+// loadRedirects reads a JSON object mapping shortcut names to absolute http or
+// https URLs. Every entry is validated so a typo fails the load rather than
+// producing a broken link.
+func loadRedirects(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	targets := make(map[string]string, len(raw))
+	for name, target := range raw {
+		name = strings.TrimSpace(name)
+		target = strings.TrimSpace(target)
+		if name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid redirect name %q", name)
+		}
+		if !isValidLinkURL(target) {
+			return nil, fmt.Errorf("redirect %q: target %q is not an absolute http(s) URL", name, target)
+		}
+		targets[name] = target
+	}
+	return targets, nil
+}
+
+// reloadRedirects replaces the redirect table from path. On failure the
+// previous table stays in place.
+func reloadRedirects(path string) error {
+	targets, err := loadRedirects(path)
+	recordConfigReload(err)
+	if err != nil {
+		return err
+	}
+
+	redirectsMu.Lock()
+	redirectTargets = targets
+	redirectsMu.Unlock()
+	return nil
+}
+
+func lookupRedirect(name string) (string, bool) {
+	redirectsMu.RLock()
+	defer redirectsMu.RUnlock()
+	target, ok := redirectTargets[name]
+	return target, ok
+}
+
+// handleRedirect serves /go/<name> shortcuts with a 302 to the configured
+// target.
+func handleRedirect(w http.ResponseWriter, r *http.Request) {
+	target, ok := lookupRedirect(strings.TrimPrefix(r.URL.Path, redirectsPathPrefix))
+	if !ok {
+		localizedError(w, r, msgNotFound, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	http.Redirect(w, r, target, http.StatusFound)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoadRedirects(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := []struct {
+		content string
+		ok      bool
+	}{
+		{`{"nas": "https://nas.example.com", "wiki": "http://wiki.local/start"}`, true},
+		{`{"bad": "ftp://files.example.com"}`, false},
+		{`{"bad": "/relative"}`, false},
+		{`{"a/b": "https://example.com"}`, false},
+		{`not json`, false},
+	}
+	for _, tc := range cases {
+		writeTestFile(t, dir, "redirects.json", tc.content)
+		targets, err := loadRedirects(dir + "/redirects.json")
+		if (err == nil) != tc.ok {
+			t.Fatalf("%s: expected ok=%v, got %v", tc.content, tc.ok, err)
+		}
+		if tc.ok && targets["nas"] != "https://nas.example.com" {
+			t.Fatalf("unexpected targets %v", targets)
+		}
+	}
+
+	if _, err := loadRedirects(dir + "/missing.json"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestHandleRedirect(t *testing.T) {
+	defer func() { redirectTargets = nil }()
+	defer resetConfigReloadStatus()
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "redirects.json", `{"nas": "https://nas.example.com"}`)
+	if err := reloadRedirects(dir + "/redirects.json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	writeTestFile(t, dir, "broken.json", `{"nas": "nope"}`)
+	if err := reloadRedirects(dir + "/broken.json"); err == nil {
+		t.Fatal("expected an invalid file to fail")
+	}
+	if _, lastErr := configReloadStatus(); lastErr == "" {
+		t.Fatal("expected the failed reload to be recorded")
+	}
+
+	rr := httptest.NewRecorder()
+	handleRedirect(rr, httptest.NewRequest(http.MethodGet, "/go/nas", nil))
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "https://nas.example.com" {
+		t.Fatalf("expected 302 to the previous target, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	rr = httptest.NewRecorder()
+	handleRedirect(rr, httptest.NewRequest(http.MethodGet, "/go/unknown", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown shortcut, got %d", rr.Code)
+	}
+}
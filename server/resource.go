@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// apiResource identifies a listable Kubernetes resource.
+type apiResource struct {
+	group   string
+	version string
+	name    string
+}
+
+// defaultListedResource is what the dashboard lists unless RESOURCE_GROUP,
+// RESOURCE_VERSION or RESOURCE_NAME say otherwise.
+var defaultListedResource = apiResource{group: "networking.k8s.io", version: "v1", name: "ingresses"}
+
+// listedResource is the resource behind /api/ingresses and its watches.
+var listedResource = defaultListedResource
+
+// loadListedResource reads the resource to list from the environment. The
+// group "core" selects the legacy /api/v1 endpoints.
+func loadListedResource() apiResource {
+	resource := defaultListedResource
+	if group := strings.TrimSpace(os.Getenv("RESOURCE_GROUP")); group != "" {
+		resource.group = group
+		if group == "core" {
+			resource.group = ""
+		}
+	}
+	if version := strings.TrimSpace(os.Getenv("RESOURCE_VERSION")); version != "" {
+		resource.version = version
+	}
+	if name := strings.TrimSpace(os.Getenv("RESOURCE_NAME")); name != "" {
+		resource.name = strings.ToLower(name)
+	}
+	return resource
+}
+
+func (r apiResource) path() string {
+	if r.group == "" {
+		return "/api/" + r.version + "/" + r.name
+	}
+	return "/apis/" + r.group + "/" + r.version + "/" + r.name
+}
+
+// traefikHostMatcher finds the hosts in a Traefik rule such as
+// "Host(`a.example.com`) || Host(`b.example.com`)".
+var traefikHostMatcher = regexp.MustCompile("Host(?:SNI)?\\(([^)]*)\\)")
+
+// specHosts extracts hostnames from the spec shapes used by common routing
+// resources, so listing something other than Ingresses still yields links:
+//
+//   - spec.rules[].host (Ingress)
+//   - spec.hostnames[] (Gateway API HTTPRoute)
+//   - spec.hosts[] (Istio VirtualService)
+//   - spec.host (OpenShift Route)
+//   - spec.virtualhost.fqdn (Contour HTTPProxy)
+//   - spec.routes[].match (Traefik IngressRoute)
+func specHosts(spec map[string]interface{}) []string {
+	var hosts []string
+	add := func(host string) {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			return
+		}
+		for _, existing := range hosts {
+			if existing == host {
+				return
+			}
+		}
+		hosts = append(hosts, host)
+	}
+
+	rules, _ := spec["rules"].([]interface{})
+	for _, rule := range rules {
+		ruleMap, _ := rule.(map[string]interface{})
+		host, _ := ruleMap["host"].(string)
+		add(host)
+	}
+	for _, key := range []string{"hostnames", "hosts"} {
+		values, _ := spec[key].([]interface{})
+		for _, value := range values {
+			host, _ := value.(string)
+			add(host)
+		}
+	}
+	host, _ := spec["host"].(string)
+	add(host)
+	virtualHost, _ := spec["virtualhost"].(map[string]interface{})
+	fqdn, _ := virtualHost["fqdn"].(string)
+	add(fqdn)
+
+	routes, _ := spec["routes"].([]interface{})
+	for _, route := range routes {
+		routeMap, _ := route.(map[string]interface{})
+		match, _ := routeMap["match"].(string)
+		for _, groups := range traefikHostMatcher.FindAllStringSubmatch(match, -1) {
+			for _, quoted := range strings.Split(groups[1], ",") {
+				add(strings.Trim(strings.TrimSpace(quoted), "`\"'"))
+			}
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadListedResource(t *testing.T) {
+	if got := loadListedResource().path(); got != "/apis/networking.k8s.io/v1/ingresses" {
+		t.Fatalf("expected the ingress API by default, got %q", got)
+	}
+
+	t.Setenv("RESOURCE_GROUP", "gateway.networking.k8s.io")
+	t.Setenv("RESOURCE_VERSION", "v1")
+	t.Setenv("RESOURCE_NAME", "HTTPRoutes")
+	if got := loadListedResource().path(); got != "/apis/gateway.networking.k8s.io/v1/httproutes" {
+		t.Fatalf("unexpected path %q", got)
+	}
+
+	t.Setenv("RESOURCE_GROUP", "core")
+	t.Setenv("RESOURCE_NAME", "services")
+	if got := loadListedResource().path(); got != "/api/v1/services" {
+		t.Fatalf("unexpected core path %q", got)
+	}
+}
+
+func TestSpecHosts(t *testing.T) {
+	cases := []struct {
+		name string
+		spec map[string]interface{}
+		want []string
+	}{
+		{"ingress", map[string]interface{}{"rules": []interface{}{
+			map[string]interface{}{"host": "a.example.com"},
+			map[string]interface{}{"host": "a.example.com"},
+			map[string]interface{}{},
+		}}, []string{"a.example.com"}},
+		{"httproute", map[string]interface{}{"hostnames": []interface{}{"b.example.com", "c.example.com"}}, []string{"b.example.com", "c.example.com"}},
+		{"virtualservice", map[string]interface{}{"hosts": []interface{}{"d.example.com"}}, []string{"d.example.com"}},
+		{"route", map[string]interface{}{"host": "e.example.com"}, []string{"e.example.com"}},
+		{"httpproxy", map[string]interface{}{"virtualhost": map[string]interface{}{"fqdn": "f.example.com"}}, []string{"f.example.com"}},
+		{"ingressroute", map[string]interface{}{"routes": []interface{}{
+			map[string]interface{}{"match": "Host(`g.example.com`) && PathPrefix(`/api`)"},
+			map[string]interface{}{"match": "Host(`h.example.com`, `i.example.com`)"},
+		}}, []string{"g.example.com", "h.example.com", "i.example.com"}},
+		{"none", map[string]interface{}{}, nil},
+	}
+	for _, tc := range cases {
+		if got := specHosts(tc.spec); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
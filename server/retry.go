@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultKubeMaxRetries    = 2
+	defaultRetryBudget       = 10
+	defaultRetryBudgetRefill = time.Second
+	kubeRetryBaseDelay       = 100 * time.Millisecond
+)
+
+var (
+	// kubeMaxRetries is how many times one Kubernetes API request is retried
+	// after a transient failure, budget permitting.
+	kubeMaxRetries = defaultKubeMaxRetries
+
+	// kubeRetryBudget is shared by every Kubernetes API request, so during an
+	// apiserver outage this replica sends at most its capacity in retries at
+	// once and one more per refill interval, however many requests fail.
+	kubeRetryBudget = newRetryBudget(defaultRetryBudget, defaultRetryBudgetRefill)
+)
+
+// retryBudget is a token bucket of retries. Each retry spends one token and
+// tokens come back at one per refill interval up to capacity; with the
+// bucket empty, failures are returned without retrying.
+type retryBudget struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	refill   time.Duration
+	last     time.Time
+	now      func() time.Time
+
+	retries   atomic.Uint64
+	exhausted atomic.Uint64
+}
+
+func newRetryBudget(capacity int, refill time.Duration) *retryBudget {
+	if capacity < 0 {
+		capacity = 0
+	}
+	if refill <= 0 {
+		refill = defaultRetryBudgetRefill
+	}
+	b := &retryBudget{capacity: float64(capacity), tokens: float64(capacity), refill: refill, now: time.Now}
+	b.last = b.now()
+	return b
+}
+
+// loadRetryBudget reads KUBE_RETRY_BUDGET and KUBE_RETRY_BUDGET_REFILL.
+func loadRetryBudget() *retryBudget {
+	return newRetryBudget(
+		int(getEnvInt64("KUBE_RETRY_BUDGET", defaultRetryBudget)),
+		getEnvDuration("KUBE_RETRY_BUDGET_REFILL", defaultRetryBudgetRefill),
+	)
+}
+
+// replenish must be called with mu held.
+func (b *retryBudget) replenish() {
+	now := b.now()
+	b.tokens = min(b.capacity, b.tokens+float64(now.Sub(b.last))/float64(b.refill))
+	b.last = now
+}
+
+// take spends a token, reporting false when the budget is exhausted.
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.replenish()
+	if b.tokens < 1 {
+		b.exhausted.Add(1)
+		return false
+	}
+	b.tokens--
+	b.retries.Add(1)
+	return true
+}
+
+func (b *retryBudget) level() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.replenish()
+	return b.tokens
+}
+
+func (b *retryBudget) write(w io.Writer) {
+	_, _ = io.WriteString(w, "# HELP home_pager_kube_retry_budget_tokens Kubernetes API retries currently available to this replica.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_kube_retry_budget_tokens gauge\n")
+	_, _ = io.WriteString(w, "home_pager_kube_retry_budget_tokens "+strconv.FormatFloat(b.level(), 'f', 2, 64)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_kube_retries_total Kubernetes API requests retried.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_kube_retries_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_kube_retries_total "+strconv.FormatUint(b.retries.Load(), 10)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_kube_retry_budget_exhausted_total Transient Kubernetes API failures not retried because the budget was empty.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_kube_retry_budget_exhausted_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_kube_retry_budget_exhausted_total "+strconv.FormatUint(b.exhausted.Load(), 10)+"\n")
+}
+
+// isRetryableKubeError reports whether err is a transient failure worth
+// retrying: a network error reaching the apiserver or a 429 or 5xx answer.
+// Configuration errors, refused redirects and errors caused by ctx ending
+// are not retried.
+func isRetryableKubeError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *kubernetesAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(urlErr.Err, &netErr) || errors.Is(urlErr.Err, io.EOF) || errors.Is(urlErr.Err, io.ErrUnexpectedEOF)
+}
+
+// kubeRetryDelay returns the jittered, doubling wait before retry attempt.
+func kubeRetryDelay(attempt int) time.Duration {
+	delay := kubeRetryBaseDelay << min(attempt, 6)
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
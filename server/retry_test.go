@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newRetryBudget(2, time.Second)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	if !b.take() || !b.take() {
+		t.Fatal("expected the full budget to allow two retries")
+	}
+	if b.take() {
+		t.Fatal("expected the exhausted budget to refuse a retry")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if !b.take() {
+		t.Fatal("expected one token back after the refill interval")
+	}
+	if b.take() {
+		t.Fatal("expected only one token back after 1.5 refill intervals")
+	}
+
+	now = now.Add(time.Hour)
+	if got := b.level(); got != 2 {
+		t.Fatalf("expected the budget to refill up to its capacity, got %v", got)
+	}
+	if got := b.exhausted.Load(); got != 2 {
+		t.Fatalf("expected 2 exhausted attempts, got %d", got)
+	}
+}
+
+func TestGetKubernetesJSONRetriesWithinBudget(t *testing.T) {
+	var calls atomic.Int32
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			http.Error(w, "etcd leader changed", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[]}`))
+	}))
+	prevBudget := kubeRetryBudget
+	kubeRetryBudget = newRetryBudget(1, time.Hour)
+	defer func() { kubeRetryBudget = prevBudget }()
+
+	if _, err := getKubernetesJSON(context.Background(), "/apis/networking.k8s.io/v1/ingresses", nil); err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected 2 calls, got %d", got)
+	}
+
+	// The budget is now empty, so the next failure is returned at once.
+	calls.Store(0)
+	if _, err := getKubernetesJSON(context.Background(), "/apis/networking.k8s.io/v1/ingresses", nil); err == nil {
+		t.Fatal("expected the failure to be returned without a retry")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected 1 call with the budget exhausted, got %d", got)
+	}
+}
+
+func TestGetKubernetesJSONDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+
+	if _, err := getKubernetesJSON(context.Background(), "/apis/networking.k8s.io/v1/ingresses", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected a 403 not to be retried, got %d calls", got)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// defaultRobotsTxt asks every crawler to stay out, since the dashboard lists
+// internal services and should never be indexed even if exposed by mistake.
+const defaultRobotsTxt = "User-agent: *\nDisallow: /\n"
+
+var (
+	// robotsTxtFile is the ROBOTS_TXT_FILE path, re-read on config reload.
+	robotsTxtFile string
+
+	robotsTxt atomic.Pointer[string]
+)
+
+// loadRobotsTxt picks the robots.txt body: ROBOTS_TXT_FILE if set, then the
+// ROBOTS_TXT value, then the deny-all default.
+func loadRobotsTxt() error {
+	body := defaultRobotsTxt
+	if robotsTxtFile != "" {
+		data, err := os.ReadFile(robotsTxtFile)
+		if err != nil {
+			return err
+		}
+		body = string(data)
+	} else if value := os.Getenv("ROBOTS_TXT"); strings.TrimSpace(value) != "" {
+		body = strings.ReplaceAll(value, `\n`, "\n")
+		if !strings.HasSuffix(body, "\n") {
+			body += "\n"
+		}
+	}
+	robotsTxt.Store(&body)
+	return nil
+}
+
+// reloadRobotsTxt re-reads ROBOTS_TXT_FILE, keeping the previous body on
+// failure.
+func reloadRobotsTxt() error {
+	err := loadRobotsTxt()
+	recordConfigReload(err)
+	return err
+}
+
+func handleRobotsTxt(w http.ResponseWriter, _ *http.Request) {
+	body := defaultRobotsTxt
+	if loaded := robotsTxt.Load(); loaded != nil {
+		body = *loaded
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	_, _ = w.Write([]byte(body))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandleRobotsTxt(t *testing.T) {
+	defer robotsTxt.Store(nil)
+
+	serve := func() string {
+		rr := httptest.NewRecorder()
+		handleRobotsTxt(rr, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+			t.Fatalf("expected a plain-text 200, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		return rr.Body.String()
+	}
+
+	if err := loadRobotsTxt(); err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(); got != defaultRobotsTxt {
+		t.Errorf("expected the deny-all default, got %q", got)
+	}
+
+	t.Setenv("ROBOTS_TXT", `User-agent: *\nAllow: /`)
+	if err := loadRobotsTxt(); err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(); got != "User-agent: *\nAllow: /\n" {
+		t.Errorf("expected ROBOTS_TXT to override the default, got %q", got)
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "robots.txt", "User-agent: Googlebot\nDisallow: /\n")
+	robotsTxtFile = filepath.Join(dir, "robots.txt")
+	defer func() { robotsTxtFile = "" }()
+	if err := reloadRobotsTxt(); err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(); got != "User-agent: Googlebot\nDisallow: /\n" {
+		t.Errorf("expected ROBOTS_TXT_FILE to take precedence, got %q", got)
+	}
+
+	if err := os.Remove(robotsTxtFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadRobotsTxt(); err == nil {
+		t.Fatal("expected reloading a missing file to fail")
+	}
+	if got := serve(); got != "User-agent: Googlebot\nDisallow: /\n" {
+		t.Errorf("expected a failed reload to keep the previous body, got %q", got)
+	}
+}
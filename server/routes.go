@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
+const defaultMetricsTimeout = 2 * time.Second
+
+// Common allowed-method sets for routes.
+var (
+	methodsGet  = []string{http.MethodGet}
+	methodsRead = []string{http.MethodGet, http.MethodHead}
+)
+
+// route describes a handler registered on the mux. methods lists the allowed
+// request methods; other methods get a 405 before the handler runs, and an
+// empty list allows any method. A positive timeout wraps the handler in
+// http.TimeoutHandler; long-lived or streaming routes leave it zero so they
+// are not cut off. A positive maxHeaderBytes lowers the server-wide header
+// limit for the route.
+type route struct {
+	pattern        string
+	methods        []string
+	handler        http.Handler
+	timeout        time.Duration
+	maxHeaderBytes int64
+}
+
+func registerRoutes(mux *http.ServeMux, routes []route) {
+	for _, rt := range routes {
+		handler := rt.handler
+		if rt.timeout > 0 {
+			handler = withLocalizedTimeout(rt.timeout, handler)
+		}
+		handler = withAllowedMethods(rt.methods, handler)
+		if rt.maxHeaderBytes > 0 {
+			handler = withMaxHeaderBytes(rt.maxHeaderBytes, handler)
+		}
+		mux.Handle(rt.pattern, handler)
+	}
+}
+
+// withLocalizedTimeout wraps next in http.TimeoutHandler, with the timeout
+// message translated for each request.
+func withLocalizedTimeout(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.TimeoutHandler(next, timeout, translate(r, msgRequestTimeout)).ServeHTTP(w, r)
+	})
+}
+
+// withAllowedMethods answers requests whose method is not in methods with a
+// 405 carrying an Allow header.
+func withAllowedMethods(methods []string, next http.Handler) http.Handler {
+	if len(methods) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(methods, r.Method) {
+			methodNotAllowed(w, r, methods...)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
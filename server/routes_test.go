@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegisterRoutesAppliesTimeouts(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+		}
+	})
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, []route{
+		{pattern: "/fast", handler: slow, timeout: 10 * time.Millisecond},
+		{pattern: "/exempt", handler: slow},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/fast", nil)
+	req.Header.Set("Accept-Language", "de")
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 from timed-out route, got %d", rr.Code)
+	}
+	if got := rr.Body.String(); got != translations["de"][msgRequestTimeout] {
+		t.Fatalf("expected a German timeout message, got %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/exempt", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 from exempt route, got %d", rr.Code)
+	}
+}
+
+func TestRegisterRoutesEnforcesMethods(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux := http.NewServeMux()
+	registerRoutes(mux, []route{
+		{pattern: "/read", methods: methodsRead, handler: ok},
+		{pattern: "/any", handler: ok},
+	})
+
+	cases := []struct {
+		method string
+		path   string
+		code   int
+	}{
+		{http.MethodGet, "/read", http.StatusOK},
+		{http.MethodHead, "/read", http.StatusOK},
+		{http.MethodPost, "/read", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/any", http.StatusOK},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+		if rr.Code != tc.code {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.path, tc.code, rr.Code)
+		}
+		if tc.code == http.StatusMethodNotAllowed {
+			if got := rr.Header().Get("Allow"); got != "GET, HEAD" {
+				t.Fatalf("expected Allow: GET, HEAD, got %q", got)
+			}
+		}
+	}
+}
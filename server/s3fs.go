@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultS3Region     = "us-east-1"
+	defaultS3CacheTTL   = time.Minute
+	defaultS3CacheBytes = 64 << 20
+	maxS3ObjectBytes    = 32 << 20
+
+	// maxS3CachedMisses bounds remembered misses separately from objects, so
+	// requests for made-up paths cannot evict real assets.
+	maxS3CachedMisses    = 1024
+	emptyPayloadSHA256   = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3SigningAlgorithm   = "AWS4-HMAC-SHA256"
+	s3SigningTimeFormat  = "20060102T150405Z"
+	s3SigningDateFormat  = "20060102"
+	s3SigningServiceName = "s3"
+)
+
+// s3Config describes an S3-compatible bucket holding the UI bundle.
+type s3Config struct {
+	endpoint     string
+	bucket       string
+	prefix       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	cacheTTL     time.Duration
+	cacheBytes   int64
+}
+
+// loadS3Config reads the STATIC_S3_* settings. It reports false when no bucket
+// is configured. Credentials fall back to the standard AWS_* variables and
+// may be omitted entirely for public buckets.
+func loadS3Config() (s3Config, bool) {
+	bucket := strings.TrimSpace(os.Getenv("STATIC_S3_BUCKET"))
+	if bucket == "" {
+		return s3Config{}, false
+	}
+
+	cfg := s3Config{
+		endpoint:     strings.TrimRight(strings.TrimSpace(os.Getenv("STATIC_S3_ENDPOINT")), "/"),
+		bucket:       bucket,
+		prefix:       strings.Trim(strings.TrimSpace(os.Getenv("STATIC_S3_PREFIX")), "/"),
+		region:       strings.TrimSpace(os.Getenv("STATIC_S3_REGION")),
+		accessKey:    firstEnv("STATIC_S3_ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID"),
+		secretKey:    firstEnv("STATIC_S3_SECRET_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY"),
+		sessionToken: firstEnv("STATIC_S3_SESSION_TOKEN", "AWS_SESSION_TOKEN"),
+		cacheTTL:     getEnvDuration("STATIC_S3_CACHE_TTL", defaultS3CacheTTL),
+		cacheBytes:   getEnvInt64("STATIC_S3_CACHE_BYTES", defaultS3CacheBytes),
+	}
+	if cfg.region == "" {
+		cfg.region = defaultS3Region
+	}
+	if cfg.endpoint == "" {
+		cfg.endpoint = "https://s3." + cfg.region + ".amazonaws.com"
+	}
+	return cfg, true
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// s3FS serves static files from a bucket using path-style requests, keeping
+// fetched objects (and misses) in memory for cacheTTL.
+type s3FS struct {
+	cfg    s3Config
+	client *http.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache *s3Cache
+}
+
+type s3Object struct {
+	data      []byte
+	modTime   time.Time
+	missing   bool
+	fetchedAt time.Time
+}
+
+func newS3FS(cfg s3Config, timeout time.Duration) *s3FS {
+	if cfg.cacheBytes <= 0 {
+		cfg.cacheBytes = defaultS3CacheBytes
+	}
+	return &s3FS{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+		cache:  newS3Cache(cfg.cacheBytes, maxS3CachedMisses),
+	}
+}
+
+// s3Cache is a least-recently-used cache of bucket objects bounded by total
+// size, with misses kept in a separate list bounded by count.
+type s3Cache struct {
+	maxBytes  int64
+	maxMisses int
+	bytes     int64
+	objects   *list.List
+	misses    *list.List
+	entries   map[string]*list.Element
+}
+
+type s3CacheEntry struct {
+	name string
+	obj  *s3Object
+}
+
+func newS3Cache(maxBytes int64, maxMisses int) *s3Cache {
+	return &s3Cache{
+		maxBytes:  maxBytes,
+		maxMisses: maxMisses,
+		objects:   list.New(),
+		misses:    list.New(),
+		entries:   make(map[string]*list.Element),
+	}
+}
+
+// get must be called with the s3FS mutex held.
+func (c *s3Cache) get(name string) (*s3Object, bool) {
+	elem, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*s3CacheEntry)
+	c.list(entry.obj).MoveToFront(elem)
+	return entry.obj, true
+}
+
+// put must be called with the s3FS mutex held. Objects larger than the whole
+// cache are not kept.
+func (c *s3Cache) put(name string, obj *s3Object) {
+	c.remove(name)
+	if int64(len(obj.data)) > c.maxBytes {
+		return
+	}
+	c.entries[name] = c.list(obj).PushFront(&s3CacheEntry{name: name, obj: obj})
+	c.bytes += int64(len(obj.data))
+	for c.bytes > c.maxBytes {
+		c.remove(c.objects.Back().Value.(*s3CacheEntry).name)
+	}
+	for c.misses.Len() > c.maxMisses {
+		c.remove(c.misses.Back().Value.(*s3CacheEntry).name)
+	}
+}
+
+func (c *s3Cache) remove(name string) {
+	elem, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	entry := elem.Value.(*s3CacheEntry)
+	c.list(entry.obj).Remove(elem)
+	c.bytes -= int64(len(entry.obj.data))
+	delete(c.entries, name)
+}
+
+func (c *s3Cache) list(obj *s3Object) *list.List {
+	if obj.missing {
+		return c.misses
+	}
+	return c.objects
+}
+
+func (s *s3FS) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+	if name == "/" || strings.HasSuffix(name, "/") {
+		return newMemFile(name, nil, time.Time{}, true), nil
+	}
+
+	obj, err := s.object(name)
+	if err != nil {
+		return nil, err
+	}
+	if obj.missing {
+		return nil, fs.ErrNotExist
+	}
+	return newMemFile(name, obj.data, obj.modTime, false), nil
+}
+
+func (s *s3FS) object(name string) (*s3Object, error) {
+	s.mu.Lock()
+	cached, ok := s.cache.get(name)
+	s.mu.Unlock()
+	if ok && s.now().Sub(cached.fetchedAt) < s.cfg.cacheTTL {
+		return cached, nil
+	}
+
+	obj, err := s.fetch(name)
+	if err != nil {
+		if ok {
+			// Keep serving the last good copy while the bucket is unreachable.
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache.put(name, obj)
+	s.mu.Unlock()
+	return obj, nil
+}
+
+func (s *s3FS) fetch(name string) (*s3Object, error) {
+	key := strings.TrimPrefix(name, "/")
+	if s.cfg.prefix != "" {
+		key = s.cfg.prefix + "/" + key
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, s.cfg.endpoint+"/"+awsURIEncode(s.cfg.bucket, true)+"/"+awsURIEncode(key, false), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.accessKey != "" && s.cfg.secretKey != "" {
+		signS3Request(req, s.cfg, s.now())
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden:
+		// S3 answers 403 for missing keys when the caller cannot list the bucket.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return &s3Object{missing: true, fetchedAt: s.now()}, nil
+	case resp.StatusCode != http.StatusOK:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("s3 get %s: %s", key, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxS3ObjectBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxS3ObjectBytes {
+		return nil, fmt.Errorf("s3 get %s: object exceeds %d bytes", key, maxS3ObjectBytes)
+	}
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &s3Object{data: data, modTime: modTime, fetchedAt: s.now()}, nil
+}
+
+// signS3Request adds an AWS Signature Version 4 Authorization header covering
+// the host, any Range header and every x-amz-* header.
+func signS3Request(req *http.Request, cfg s3Config, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(s3SigningTimeFormat)
+	date := now.Format(s3SigningDateFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadSHA256)
+	if cfg.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadSHA256,
+	}, "\n")
+
+	scope := date + "/" + cfg.region + "/" + s3SigningServiceName + "/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := s3SigningAlgorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+cfg.secretKey), date)
+	key = hmacSHA256(key, cfg.region)
+	key = hmacSHA256(key, s3SigningServiceName)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", s3SigningAlgorithm+" Credential="+cfg.accessKey+"/"+scope+",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncode percent-encodes everything except the unreserved characters,
+// as required for SigV4 canonical URIs. Slashes are kept unless encodeSlash
+// is set.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// memFile is an in-memory http.File. Directories are always empty; the file
+// server only opens them to look for index.html.
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func newMemFile(name string, data []byte, modTime time.Time, dir bool) *memFile {
+	return &memFile{
+		Reader: bytes.NewReader(data),
+		info:   memFileInfo{name: path.Base(name), size: int64(len(data)), modTime: modTime, dir: dir},
+	}
+}
+
+func (f *memFile) Close() error                       { return nil }
+func (f *memFile) Readdir(int) ([]fs.FileInfo, error) { return nil, nil }
+func (f *memFile) Stat() (fs.FileInfo, error)         { return f.info, nil }
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return i.dir }
+func (i memFileInfo) Sys() interface{}   { return nil }
+
+func (i memFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxLabelNameLength   = 63
+	maxLabelPrefixLength = 253
+)
+
+var (
+	labelNamePattern   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// tokenizeSelector splits a label selector into identifiers and the
+// operator and punctuation tokens of the Kubernetes selector grammar.
+func tokenizeSelector(raw string) []string {
+	var tokens []string
+	for i := 0; i < len(raw); {
+		c := raw[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case strings.HasPrefix(raw[i:], "==") || strings.HasPrefix(raw[i:], "!="):
+			tokens = append(tokens, raw[i:i+2])
+			i += 2
+		case strings.IndexByte("=!(),<>", c) >= 0:
+			tokens = append(tokens, raw[i:i+1])
+			i++
+		default:
+			start := i
+			for i < len(raw) && strings.IndexByte(" \t\n=!(),<>", raw[i]) < 0 {
+				i++
+			}
+			tokens = append(tokens, raw[start:i])
+		}
+	}
+	return tokens
+}
+
+func isSelectorPunctuation(token string) bool {
+	switch token {
+	case "=", "==", "!=", "!", "(", ")", ",", "<", ">":
+		return true
+	}
+	return false
+}
+
+// validateLabelSelector checks raw against the Kubernetes label selector
+// syntax (equality, set-based, existence and numeric comparisons) and the
+// label key and value rules, without contacting the apiserver.
+func validateLabelSelector(raw string) error {
+	p := selectorParser{tokens: tokenizeSelector(raw)}
+	if len(p.tokens) == 0 {
+		return nil
+	}
+	for {
+		if err := p.requirement(); err != nil {
+			return err
+		}
+		switch token, ok := p.next(); {
+		case !ok:
+			return nil
+		case token != ",":
+			return fmt.Errorf("expected ',' between requirements, found %q", token)
+		}
+	}
+}
+
+type selectorParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *selectorParser) next() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, true
+}
+
+func (p *selectorParser) peek() (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *selectorParser) atRequirementEnd() bool {
+	token, ok := p.peek()
+	return !ok || token == ","
+}
+
+func (p *selectorParser) key() (string, error) {
+	token, ok := p.next()
+	if !ok || isSelectorPunctuation(token) || token == "in" || token == "notin" {
+		return "", fmt.Errorf("expected a label key, found %q", token)
+	}
+	return token, validateLabelKey(token)
+}
+
+func (p *selectorParser) requirement() error {
+	if token, _ := p.peek(); token == "!" {
+		p.pos++
+		if _, err := p.key(); err != nil {
+			return err
+		}
+		if !p.atRequirementEnd() {
+			return fmt.Errorf("unexpected %q after '!' requirement", p.tokens[p.pos])
+		}
+		return nil
+	}
+
+	if _, err := p.key(); err != nil {
+		return err
+	}
+	if p.atRequirementEnd() {
+		return nil
+	}
+
+	op, _ := p.next()
+	switch op {
+	case "=", "==", "!=":
+		if p.atRequirementEnd() {
+			return nil
+		}
+		value, _ := p.next()
+		if isSelectorPunctuation(value) {
+			return fmt.Errorf("expected a value after %q, found %q", op, value)
+		}
+		return validateLabelValue(value)
+	case "<", ">":
+		value, ok := p.next()
+		if !ok {
+			return fmt.Errorf("expected an integer after %q", op)
+		}
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("value %q for %q must be an integer", value, op)
+		}
+		return nil
+	case "in", "notin":
+		return p.valueSet(op)
+	default:
+		return fmt.Errorf("unknown operator %q", op)
+	}
+}
+
+// valueSet parses the parenthesized value list of in and notin. As in the
+// Kubernetes parser, missing values between commas or parentheses, as in
+// "()" or "(a,)", stand for the empty value.
+func (p *selectorParser) valueSet(op string) error {
+	if token, ok := p.next(); !ok || token != "(" {
+		return fmt.Errorf("expected '(' after %q", op)
+	}
+	for {
+		token, ok := p.next()
+		if !ok {
+			return fmt.Errorf("missing ')' after %q values", op)
+		}
+		switch token {
+		case ")":
+			return nil
+		case ",":
+			continue
+		}
+		if isSelectorPunctuation(token) {
+			return fmt.Errorf("unexpected %q in %q values", token, op)
+		}
+		if err := validateLabelValue(token); err != nil {
+			return err
+		}
+		if next, _ := p.peek(); next != "," && next != ")" {
+			return fmt.Errorf("expected ',' or ')' after %q, found %q", token, next)
+		}
+	}
+}
+
+// validateLabelKey checks a label key: an optional DNS subdomain prefix and
+// slash followed by a name of at most 63 characters.
+func validateLabelKey(key string) error {
+	name := key
+	if prefix, rest, ok := strings.Cut(key, "/"); ok {
+		if prefix == "" || len(prefix) > maxLabelPrefixLength || !labelPrefixPattern.MatchString(prefix) {
+			return fmt.Errorf("invalid label key prefix %q: must be a lowercase DNS subdomain", prefix)
+		}
+		name = rest
+	}
+	if name == "" || len(name) > maxLabelNameLength || !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid label key %q: names must be 63 characters or less, start and end with an alphanumeric character and contain only '-', '_', '.' or alphanumerics", key)
+	}
+	return nil
+}
+
+// validateLabelValue checks a label value, which may be empty.
+func validateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxLabelNameLength || !labelNamePattern.MatchString(value) {
+		return fmt.Errorf("invalid label value %q: values must be 63 characters or less, start and end with an alphanumeric character and contain only '-', '_', '.' or alphanumerics", value)
+	}
+	return nil
+}
+
+type selectorValidation struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// handleValidateSelector reports whether ?labelSelector= is a valid
+// Kubernetes label selector, so the UI can give feedback before applying it.
+func handleValidateSelector(w http.ResponseWriter, r *http.Request) {
+	response := selectorValidation{Valid: true}
+	if err := validateLabelSelector(r.URL.Query().Get("labelSelector")); err != nil {
+		response = selectorValidation{Error: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(response)
+}
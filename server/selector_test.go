@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestValidateLabelSelector(t *testing.T) {
+	valid := []string{
+		"",
+		"app",
+		"!app",
+		"app=web",
+		"app==web,tier!=db",
+		"app=",
+		"environment in (production, qa)",
+		"tier notin (frontend,backend),partition",
+		"app.kubernetes.io/name=grafana",
+		"replicas>2",
+		" app = web , !legacy ",
+	}
+	for _, selector := range valid {
+		if err := validateLabelSelector(selector); err != nil {
+			t.Errorf("expected %q to be valid, got %v", selector, err)
+		}
+	}
+
+	invalid := []string{
+		"app=web=x",
+		"app in (a,b",
+		"app notin a",
+		"app web",
+		"=web",
+		",app",
+		"app,",
+		"-app=web",
+		"app=web!",
+		"Example.com/app=web",
+		"app=" + strings.Repeat("a", 64),
+		"replicas>two",
+		"!app=web",
+	}
+	for _, selector := range invalid {
+		if err := validateLabelSelector(selector); err == nil {
+			t.Errorf("expected %q to be invalid", selector)
+		}
+	}
+}
+
+func TestHandleValidateSelector(t *testing.T) {
+	for selector, wantValid := range map[string]bool{"app in (web)": true, "app in web": false} {
+		rr := httptest.NewRecorder()
+		handleValidateSelector(rr, httptest.NewRequest(http.MethodGet, "/api/validate-selector?labelSelector="+url.QueryEscape(selector), nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		var payload selectorValidation
+		if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("invalid json from /api/validate-selector: %v", err)
+		}
+		if payload.Valid != wantValid || (payload.Error == "") != wantValid {
+			t.Fatalf("%q: expected valid=%v, got %+v", selector, wantValid, payload)
+		}
+	}
+}
+
+// TestValidateLabelSelectorMatchesUpstream covers selectors from the
+// Kubernetes labels parser tests (k8s.io/apimachinery/pkg/labels), so the
+// endpoint accepts exactly what the apiserver would.
+func TestValidateLabelSelectorMatchesUpstream(t *testing.T) {
+	cases := []struct {
+		selector string
+		valid    bool
+	}{
+		{"x=a,y=b,z=c", true},
+		{"x!=a,y=b", true},
+		{"x=", true},
+		{"x= ", true},
+		{"x=,z= ", true},
+		{"x= ,z= ", true},
+		{"!x", true},
+		{"x>1", true},
+		{"x>1,z<5", true},
+		{"x in (a)", true},
+		{"x in (a,b,c)", true},
+		{"x in (a, b)", true},
+		{"x notin (a)", true},
+		{"x in (a,)", true},
+		{"x in (,a)", true},
+		{"x in (a,,b)", true},
+		{"x in ()", true},
+		{"x notin ()", true},
+		{"x in (in)", true},
+		{"x=notin", true},
+		{"!x,y in (a)", true},
+		{"x=a||y=b", false},
+		{"x==a==b", false},
+		{"!x=a", false},
+		{"x<a", false},
+		{"x>1.5", false},
+		{"x in (a b)", false},
+		{"x in a", false},
+		{"x in (a", false},
+		{"x notin", false},
+		{"in=a", false},
+		{"notin in (a)", false},
+		{"!in", false},
+		{"x=a,", false},
+		{"x!y", false},
+		{"x in ((a))", false},
+	}
+	for _, tc := range cases {
+		if err := validateLabelSelector(tc.selector); (err == nil) != tc.valid {
+			t.Errorf("%q: expected valid=%v, got %v", tc.selector, tc.valid, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// identifyingHeaders reveal the server software or version and are removed
+// from every response, whichever handler or middleware set them.
+var identifyingHeaders = []string{"Server", "X-Powered-By"}
+
+// withServerHeader strips identifying headers from responses and, when value
+// is set (SERVER_HEADER), sends it as the Server header instead. net/http
+// sends no Server header of its own. It must be the outermost middleware so
+// it sees headers added by everything inside it.
+func withServerHeader(value string, next http.Handler) http.Handler {
+	value = strings.TrimSpace(value)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&serverHeaderWriter{ResponseWriter: w, value: value}, r)
+	})
+}
+
+type serverHeaderWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+// fixHeaders runs once, just before the headers are sent.
+func (w *serverHeaderWriter) fixHeaders() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	for _, name := range identifyingHeaders {
+		h.Del(name)
+	}
+	if w.value != "" {
+		h.Set("Server", w.value)
+	}
+}
+
+func (w *serverHeaderWriter) WriteHeader(code int) {
+	if code >= http.StatusOK {
+		w.fixHeaders()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *serverHeaderWriter) Write(p []byte) (int, error) {
+	w.fixHeaders()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *serverHeaderWriter) Flush() {
+	w.fixHeaders()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *serverHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
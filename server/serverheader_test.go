@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithServerHeader(t *testing.T) {
+	leaky := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Go/1.24")
+		w.Header().Set("X-Powered-By", "home-pager/dev")
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	rr := httptest.NewRecorder()
+	withServerHeader("", leaky).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rr.Header().Values("Server"); len(got) != 0 {
+		t.Errorf("expected no Server header by default, got %q", got)
+	}
+	if got := rr.Header().Get("X-Powered-By"); got != "" {
+		t.Errorf("expected X-Powered-By to be stripped, got %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	withServerHeader(" home ", leaky).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rr.Header().Get("Server"); got != "home" {
+		t.Errorf("expected SERVER_HEADER to replace the Server header, got %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	withServerHeader("home", http.HandlerFunc(handleNotFound)).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rr.Code != http.StatusNotFound || rr.Header().Get("Server") != "home" {
+		t.Errorf("expected the Server header on errors too, got %d %q", rr.Code, rr.Header().Get("Server"))
+	}
+}
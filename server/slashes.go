@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Trailing-slash handling modes for TRAILING_SLASH.
+const (
+	trailingSlashRedirect = "redirect"
+	trailingSlashRewrite  = "rewrite"
+	trailingSlashOff      = "off"
+)
+
+// parseTrailingSlashMode maps TRAILING_SLASH to a mode, defaulting to
+// redirect.
+func parseTrailingSlashMode(raw string) string {
+	switch mode := strings.ToLower(strings.TrimSpace(raw)); mode {
+	case "":
+		return trailingSlashRedirect
+	case trailingSlashRedirect, trailingSlashRewrite, trailingSlashOff:
+		return mode
+	default:
+		log.Printf("Warning: invalid TRAILING_SLASH %q; expected redirect, rewrite or off, using redirect", raw)
+		return trailingSlashRedirect
+	}
+}
+
+// canonicalAPIPath strips trailing slashes from an /api/ path, returning
+// false when the path is not an API path or is already canonical. The /api/
+// prefix itself is left alone since it is the catch-all for unknown routes.
+func canonicalAPIPath(p string) (string, bool) {
+	if !strings.HasPrefix(p, "/api/") || !strings.HasSuffix(p, "/") {
+		return "", false
+	}
+	canonical := strings.TrimRight(p, "/")
+	if canonical == "/api" {
+		return "", false
+	}
+	return canonical, true
+}
+
+// withTrailingSlash sends /api/ paths with a trailing slash to their
+// registered form, so /api/ingresses/ does not fall through to the /api/
+// catch-all. redirect answers with a 308, which keeps the method and body;
+// rewrite serves the canonical route directly. Static paths are untouched
+// because a trailing slash there names a directory.
+func withTrailingSlash(mode string, next http.Handler) http.Handler {
+	if mode == trailingSlashOff {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		canonical, ok := canonicalAPIPath(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if mode == trailingSlashRewrite {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = canonical
+			r2.URL.RawPath = ""
+			next.ServeHTTP(w, r2)
+			return
+		}
+
+		target := (&url.URL{Path: canonical, RawQuery: r.URL.RawQuery}).String()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTrailingSlashMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(root, "docs"), "index.html", "docs")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/ingresses", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ingresses " + r.URL.RawQuery))
+	})
+	mux.HandleFunc("/api/", handleNotFound)
+	mux.Handle("/", http.FileServer(http.Dir(root)))
+	return mux
+}
+
+func TestWithTrailingSlashRedirectsAPIPaths(t *testing.T) {
+	handler := withTrailingSlash(trailingSlashRedirect, newTrailingSlashMux(t))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses/?namespace=default", nil))
+
+	if rr.Code != http.StatusPermanentRedirect {
+		t.Fatalf("expected 308, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Location"); got != "/api/ingresses?namespace=default" {
+		t.Errorf("expected redirect to canonical path, got %q", got)
+	}
+}
+
+func TestWithTrailingSlashRewritesAPIPaths(t *testing.T) {
+	handler := withTrailingSlash(trailingSlashRewrite, newTrailingSlashMux(t))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses//?limit=5", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got := rr.Body.String(); got != "ingresses limit=5" {
+		t.Errorf("expected the ingresses handler to serve the request, got %q", got)
+	}
+}
+
+func TestWithTrailingSlashLeavesStaticPathsAlone(t *testing.T) {
+	for _, mode := range []string{trailingSlashRedirect, trailingSlashRewrite} {
+		handler := withTrailingSlash(mode, newTrailingSlashMux(t))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+		if rr.Code != http.StatusOK || rr.Body.String() != "docs" {
+			t.Errorf("%s: expected directory index, got %d %q", mode, rr.Code, rr.Body.String())
+		}
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("%s: expected /api/ to stay on the catch-all, got %d", mode, rr.Code)
+		}
+	}
+}
+
+func TestWithTrailingSlashOff(t *testing.T) {
+	handler := withTrailingSlash(parseTrailingSlashMode("off"), newTrailingSlashMux(t))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses/", nil))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected the catch-all 404 with normalization off, got %d", rr.Code)
+	}
+}
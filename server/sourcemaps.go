@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// sourceMapToken, when set, is required to download .map files. Without it
+// source maps are served like any other asset.
+var sourceMapToken string
+
+// hasSourceMapAccess accepts the token as a bearer token or as the password
+// of HTTP basic auth (any user name), and also admits requests carrying an
+// identity from a trusted auth proxy.
+func hasSourceMapAccess(r *http.Request) bool {
+	if requestIdentity(r.Context()) != "" {
+		return true
+	}
+	presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok {
+		presented = strings.TrimSpace(presented)
+	} else if _, password, basic := r.BasicAuth(); basic {
+		presented = password
+	} else {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(sourceMapToken)) == 1
+}
+
+// isSourceMapPath reports whether path names a source map, including its
+// precompressed variants such as app.js.map.br.
+func isSourceMapPath(path string) bool {
+	for _, encoding := range precompressedEncodings {
+		path = strings.TrimSuffix(path, encoding.suffix)
+	}
+	return strings.HasSuffix(path, ".map")
+}
+
+// withSourceMapAuth answers 404 for .map files unless the request is
+// authenticated, so debugging information is not public. Authorized
+// responses are kept out of shared caches.
+func withSourceMapAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sourceMapToken == "" || !isSourceMapPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !hasSourceMapAccess(r) {
+			localizedError(w, r, msgNotFound, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Cache-Control", "private, no-store")
+		next.ServeHTTP(w, r)
+	})
+}
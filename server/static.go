@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStaticDir          = "/app"
+	defaultStaticWriteTimeout = 60 * time.Second
+	staticIndexFile           = "/index.html"
+
+	// maxIndexBytes bounds how much of index.html is searched for the marker.
+	maxIndexBytes = 4 << 20
+)
+
+var (
+	staticFS http.FileSystem = newStaticFS([]string{defaultStaticDir})
+
+	// requireStaticAssets makes readiness depend on the UI being present.
+	requireStaticAssets bool
+
+	// staticIndexMarker, when set, must appear in index.html for the UI to
+	// count as present, catching truncated or partial asset mounts.
+	staticIndexMarker string
+)
+
+// layeredFS searches a list of file systems in order, so files in earlier
+// roots shadow files with the same name in later ones.
+type layeredFS []http.FileSystem
+
+func (l layeredFS) Open(name string) (http.File, error) {
+	for _, root := range l {
+		f, err := root.Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, fs.ErrNotExist
+}
+
+// parseStaticDirs splits a comma-separated list of static roots, falling back
+// to the default root when none are configured.
+func parseStaticDirs(raw string) []string {
+	var dirs []string
+	for _, part := range strings.Split(raw, ",") {
+		if dir := strings.TrimSpace(part); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return []string{defaultStaticDir}
+	}
+	return dirs
+}
+
+// layerFor returns the layer that serves name, or root itself when it is not
+// layered or no layer has name.
+func layerFor(root http.FileSystem, name string) http.FileSystem {
+	layers, ok := root.(layeredFS)
+	if !ok {
+		return root
+	}
+	for _, layer := range layers {
+		if f, err := layer.Open(name); err == nil {
+			f.Close()
+			return layer
+		}
+	}
+	return root
+}
+
+func newStaticFS(dirs []string) http.FileSystem {
+	roots := make(layeredFS, 0, len(dirs))
+	for _, dir := range dirs {
+		roots = append(roots, http.Dir(dir))
+	}
+	return roots
+}
+
+// loadStaticFS serves the UI from an S3-compatible bucket when
+// STATIC_S3_BUCKET is set, and from the STATIC_DIRS roots otherwise.
+func loadStaticFS() http.FileSystem {
+	if cfg, ok := loadS3Config(); ok {
+		log.Printf("Serving static assets from bucket %s at %s", cfg.bucket, cfg.endpoint)
+		return newS3FS(cfg, defaultHTTPTimeout)
+	}
+	return newStaticFS(parseStaticDirs(os.Getenv("STATIC_DIRS")))
+}
+
+// staticAssetsPresent reports whether the UI entry point can be served from
+// the static roots and, when staticIndexMarker is set, contains the marker.
+func staticAssetsPresent(root http.FileSystem) bool {
+	f, err := root.Open(staticIndexFile)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if staticIndexMarker == "" {
+		return true
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, maxIndexBytes))
+	return err == nil && bytes.Contains(data, []byte(staticIndexMarker))
+}
+
+// withWriteDeadline replaces the server-wide write timeout for the wrapped
+// handler, giving large static assets longer to reach slow clients.
+func withWriteDeadline(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Now().Add(timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("Warning: could not extend write deadline: %v", err)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// precompressedEncodings lists the precompressed asset variants that are
+// looked up next to a static file, in order of preference.
+var precompressedEncodings = []struct {
+	coding string
+	suffix string
+}{
+	{coding: "br", suffix: ".br"},
+	{coding: "gzip", suffix: ".gz"},
+}
+
+// withPrecompressedAssets serves a build-time compressed variant of a static
+// file (e.g. app.js.br) to clients that accept its encoding, falling back to
+// next, which serves the original and may compress it on the fly. Variants
+// are only taken from the layer that serves the original, so a stale
+// app.js.br in a base root cannot replace an app.js an overlay provides.
+func withPrecompressedAssets(root http.FileSystem, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := path.Clean("/" + r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/") {
+			name = path.Join(name, "index.html")
+		}
+
+		layer := layerFor(root, name)
+		original, err := layer.Open(name)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		info, err := original.Stat()
+		original.Close()
+		if err != nil || info.IsDir() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		acceptEncoding := r.Header.Get("Accept-Encoding")
+		for _, encoding := range precompressedEncodings {
+			if !acceptsEncoding(acceptEncoding, encoding.coding) {
+				continue
+			}
+			variant, err := layer.Open(name + encoding.suffix)
+			if err != nil {
+				continue
+			}
+			variantInfo, err := variant.Stat()
+			if err != nil || variantInfo.IsDir() {
+				variant.Close()
+				continue
+			}
+
+			if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			// withCompression, which wraps every route, already sends
+			// Vary: Accept-Encoding.
+			w.Header().Set("Content-Encoding", encoding.coding)
+			http.ServeContent(w, r, name, variantInfo.ModTime(), variant)
+			variant.Close()
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withLocalizedNotFound replaces the English 404 body written by
+// http.FileServer with a localized one.
+func withLocalizedNotFound(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nw := &notFoundWriter{ResponseWriter: w}
+		next.ServeHTTP(nw, r)
+		if nw.notFound {
+			localizedError(w, r, msgNotFound, http.StatusNotFound)
+		}
+	})
+}
+
+// notFoundWriter holds back a 404 and its body so the caller can replace them.
+type notFoundWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	notFound    bool
+}
+
+func (w *notFoundWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusNotFound {
+		w.notFound = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *notFoundWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.notFound {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *notFoundWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseStaticDirs(t *testing.T) {
+	if got := parseStaticDirs(""); len(got) != 1 || got[0] != defaultStaticDir {
+		t.Fatalf("expected default static dir, got %v", got)
+	}
+
+	got := parseStaticDirs("/overlay, /app ,")
+	if len(got) != 2 || got[0] != "/overlay" || got[1] != "/app" {
+		t.Fatalf("expected [/overlay /app], got %v", got)
+	}
+}
+
+func TestLayeredStaticFS(t *testing.T) {
+	overlay := t.TempDir()
+	base := t.TempDir()
+	writeTestFile(t, overlay, "css/styles.css", "overlay")
+	writeTestFile(t, base, "css/styles.css", "base")
+	writeTestFile(t, base, "js/app.js", "base-js")
+
+	handler := http.FileServer(newStaticFS([]string{overlay, base}))
+
+	cases := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/css/styles.css", http.StatusOK, "overlay"},
+		{"/js/app.js", http.StatusOK, "base-js"},
+		{"/missing.txt", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rr.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.code, rr.Code)
+		}
+		if tc.body != "" && rr.Body.String() != tc.body {
+			t.Fatalf("%s: expected body %q, got %q", tc.path, tc.body, rr.Body.String())
+		}
+	}
+}
+
+func TestWithWriteDeadlineOutlivesServerWriteTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, "asset")
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/api", slow)
+	mux.Handle("/static", withWriteDeadline(time.Second, slow))
+
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.WriteTimeout = 20 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/static")
+	if err != nil {
+		t.Fatalf("expected static request to succeed, got %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "asset" {
+		t.Fatalf("expected full static body, got %q (%v)", body, err)
+	}
+
+	resp, err = http.Get(srv.URL + "/api")
+	if err == nil {
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil && string(body) == "asset" {
+		t.Fatal("expected API request to be cut off by the server write timeout")
+	}
+}
+
+func TestReadinessRequiresStaticAssets(t *testing.T) {
+	kubernetesServiceHost = ""
+	kubernetesServicePort = ""
+
+	dir := t.TempDir()
+	prevFS := staticFS
+	staticFS = newStaticFS([]string{dir})
+	requireStaticAssets = true
+	defer func() {
+		staticFS = prevFS
+		requireStaticAssets = false
+	}()
+
+	if isReady() {
+		t.Fatal("expected not ready when index.html is missing")
+	}
+
+	writeTestFile(t, dir, "index.html", "<html></html>")
+	if !isReady() {
+		t.Fatal("expected ready once index.html is present")
+	}
+
+	requireStaticAssets = false
+	staticFS = newStaticFS([]string{t.TempDir()})
+	if !isReady() {
+		t.Fatal("expected missing UI to be ignored unless READY_REQUIRE_UI is set")
+	}
+}
+
+func TestStaticAssetsPresentChecksMarker(t *testing.T) {
+	dir := t.TempDir()
+	root := newStaticFS([]string{dir})
+	staticIndexMarker = `<div id="app">`
+	defer func() { staticIndexMarker = "" }()
+
+	writeTestFile(t, dir, "index.html", "<html><body>")
+	if staticAssetsPresent(root) {
+		t.Fatal("expected a truncated index.html without the marker to fail")
+	}
+
+	writeTestFile(t, dir, "index.html", `<html><body><div id="app"></div></body></html>`)
+	if !staticAssetsPresent(root) {
+		t.Fatal("expected index.html with the marker to pass")
+	}
+}
+
+func TestWithPrecompressedAssets(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "js/app.js", "plain-js")
+	writeTestFile(t, dir, "js/app.js.br", "brotli-js")
+	writeTestFile(t, dir, "js/app.js.gz", "gzip-js")
+	writeTestFile(t, dir, "index.html", "plain-html")
+	writeTestFile(t, dir, "index.html.gz", "gzip-html")
+	writeTestFile(t, dir, "orphan.js.br", "orphan")
+
+	root := newStaticFS([]string{dir})
+	handler := withPrecompressedAssets(root, http.FileServer(root))
+
+	cases := []struct {
+		path     string
+		accept   string
+		encoding string
+		body     string
+	}{
+		{"/js/app.js", "gzip, br", "br", "brotli-js"},
+		{"/js/app.js", "gzip", "gzip", "gzip-js"},
+		{"/js/app.js", "br;q=0, gzip", "gzip", "gzip-js"},
+		{"/js/app.js", "", "", "plain-js"},
+		{"/", "br, gzip", "gzip", "gzip-html"},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept-Encoding", tc.accept)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("Content-Encoding"); got != tc.encoding {
+			t.Errorf("%s (%q): expected encoding %q, got %q", tc.path, tc.accept, tc.encoding, got)
+		}
+		if rr.Body.String() != tc.body {
+			t.Errorf("%s (%q): expected body %q, got %q", tc.path, tc.accept, tc.body, rr.Body.String())
+		}
+		if tc.encoding != "" && !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/") {
+			t.Errorf("%s: expected original content type, got %q", tc.path, rr.Header().Get("Content-Type"))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/js/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	rr := httptest.NewRecorder()
+	withCompression(defaultGzipLevel, handler).ServeHTTP(rr, req)
+	if got := rr.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept-Encoding" {
+		t.Fatalf("expected a single Vary: Accept-Encoding, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/orphan.js", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when only a compressed variant exists, got %d", rr.Code)
+	}
+}
+
+func TestPrecompressedAssetsStayInTheOriginalLayer(t *testing.T) {
+	overlay := t.TempDir()
+	base := t.TempDir()
+	writeTestFile(t, overlay, "js/app.js", "overlay-js")
+	writeTestFile(t, base, "js/app.js", "base-js")
+	writeTestFile(t, base, "js/app.js.br", "stale-base-brotli")
+
+	root := newStaticFS([]string{overlay, base})
+	handler := withPrecompressedAssets(root, http.FileServer(root))
+
+	req := httptest.NewRequest(http.MethodGet, "/js/app.js", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if got := rr.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("expected the base layer's variant to be ignored, got encoding %q", got)
+	}
+	if rr.Body.String() != "overlay-js" {
+		t.Fatalf("expected the overlay file, got %q", rr.Body.String())
+	}
+}
+
+func TestWithSourceMapAuth(t *testing.T) {
+	sourceMapToken = "debug-token"
+	defer func() { sourceMapToken = "" }()
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "js/app.js", "plain-js")
+	writeTestFile(t, dir, "js/app.js.map", `{"version":3}`)
+	writeTestFile(t, dir, "js/app.js.map.gz", "gzip-map")
+	writeTestFile(t, dir, "js/app.js.map.br", "brotli-map")
+	root := newStaticFS([]string{dir})
+	handler := withSourceMapAuth(http.FileServer(root))
+
+	cases := []struct {
+		name string
+		path string
+		auth func(*http.Request)
+		code int
+	}{
+		{"asset without auth", "/js/app.js", func(*http.Request) {}, http.StatusOK},
+		{"map without auth", "/js/app.js.map", func(*http.Request) {}, http.StatusNotFound},
+		{"map with bearer", "/js/app.js.map", func(r *http.Request) { r.Header.Set("Authorization", "Bearer debug-token") }, http.StatusOK},
+		{"map with basic", "/js/app.js.map", func(r *http.Request) { r.SetBasicAuth("ops", "debug-token") }, http.StatusOK},
+		{"map with wrong token", "/js/app.js.map", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusNotFound},
+		{"gzip map without auth", "/js/app.js.map.gz", func(*http.Request) {}, http.StatusNotFound},
+		{"brotli map without auth", "/js/app.js.map.br", func(*http.Request) {}, http.StatusNotFound},
+		{"gzip map with bearer", "/js/app.js.map.gz", func(r *http.Request) { r.Header.Set("Authorization", "Bearer debug-token") }, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			tc.auth(req)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.code {
+				t.Fatalf("expected %d, got %d", tc.code, rr.Code)
+			}
+			if tc.code == http.StatusOK && strings.HasSuffix(tc.path, ".map") && rr.Header().Get("Cache-Control") != "private, no-store" {
+				t.Fatalf("expected authorized source maps to be uncacheable, got %q", rr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestWithLocalizedNotFound(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "index.html", "<html></html>")
+	handler := withLocalizedNotFound(http.FileServer(http.Dir(dir)))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing.js", nil)
+	req.Header.Set("Accept-Language", "de")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+	if got := strings.TrimSpace(rr.Body.String()); got != translations["de"][msgNotFound] {
+		t.Fatalf("expected a German 404 body, got %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "<html>") {
+		t.Fatalf("expected existing files to pass through, got %d %q", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// statsWindowSeconds is the longest window /api/stats reports on.
+const statsWindowSeconds = 15 * 60
+
+// statsLatencyBounds are the latency bucket upper bounds, in seconds, used to
+// estimate percentiles.
+var statsLatencyBounds = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// statsWindows are the windows reported by /api/stats, keyed by label.
+var statsWindows = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+}
+
+// rollingStats keeps per-second request counts for the last fifteen minutes
+// in a ring buffer, so recent rates can be served without Prometheus.
+type rollingStats struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	slots []statsSlot
+}
+
+type statsSlot struct {
+	second   int64
+	requests uint64
+	errors   uint64
+	latency  []uint64
+}
+
+func newRollingStats() *rollingStats {
+	return &rollingStats{now: time.Now, slots: make([]statsSlot, statsWindowSeconds)}
+}
+
+// record counts one request; 5xx responses count as errors.
+func (s *rollingStats) record(elapsed time.Duration, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	second := s.now().Unix()
+	slot := &s.slots[second%statsWindowSeconds]
+	if slot.second != second || slot.latency == nil {
+		*slot = statsSlot{second: second, latency: make([]uint64, len(statsLatencyBounds)+1)}
+	}
+
+	slot.requests++
+	if status >= http.StatusInternalServerError {
+		slot.errors++
+	}
+	bucket := len(statsLatencyBounds)
+	for i, bound := range statsLatencyBounds {
+		if elapsed.Seconds() <= bound {
+			bucket = i
+			break
+		}
+	}
+	slot.latency[bucket]++
+}
+
+// windowStats summarizes requests over one window. Latencies are estimated
+// from buckets and reported in milliseconds.
+type windowStats struct {
+	Requests          uint64  `json:"requests"`
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	ErrorRate         float64 `json:"errorRate"`
+	P50Ms             float64 `json:"p50Ms"`
+	P95Ms             float64 `json:"p95Ms"`
+}
+
+// window aggregates the slots from the last d, including the current second.
+func (s *rollingStats) window(d time.Duration) windowStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().Unix()
+	seconds := int64(d / time.Second)
+	latency := make([]uint64, len(statsLatencyBounds)+1)
+	var stats windowStats
+	var errors uint64
+	for _, slot := range s.slots {
+		if slot.latency == nil || slot.second > now || now-slot.second >= seconds {
+			continue
+		}
+		stats.Requests += slot.requests
+		errors += slot.errors
+		for i, count := range slot.latency {
+			latency[i] += count
+		}
+	}
+
+	if stats.Requests == 0 {
+		return stats
+	}
+	stats.RequestsPerSecond = float64(stats.Requests) / float64(seconds)
+	stats.ErrorRate = float64(errors) / float64(stats.Requests)
+	stats.P50Ms = bucketQuantile(latency, stats.Requests, 0.5) * 1000
+	stats.P95Ms = bucketQuantile(latency, stats.Requests, 0.95) * 1000
+	return stats
+}
+
+// bucketQuantile estimates quantile q by interpolating linearly inside the
+// bucket holding the target rank, as Prometheus' histogram_quantile does.
+func bucketQuantile(counts []uint64, total uint64, q float64) float64 {
+	rank := q * float64(total)
+	var cumulative uint64
+	for i, count := range counts {
+		previous := cumulative
+		cumulative += count
+		if float64(cumulative) < rank || count == 0 {
+			continue
+		}
+		if i == len(statsLatencyBounds) {
+			return statsLatencyBounds[len(statsLatencyBounds)-1]
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = statsLatencyBounds[i-1]
+		}
+		upper := statsLatencyBounds[i]
+		return lower + (upper-lower)*(rank-float64(previous))/float64(count)
+	}
+	return 0
+}
+
+type statsResponse struct {
+	Windows map[string]windowStats `json:"windows"`
+}
+
+// handleStats serves recent request rate, error rate and latency for the 1,
+// 5 and 15 minute windows.
+func (m *serverMetrics) handleStats(w http.ResponseWriter, _ *http.Request) {
+	response := statsResponse{Windows: make(map[string]windowStats, len(statsWindows))}
+	for _, window := range statsWindows {
+		response.Windows[window.label] = m.recent.window(window.duration)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRollingStatsWindows(t *testing.T) {
+	stats := newRollingStats()
+	now := time.Unix(1_700_000_000, 0)
+	stats.now = func() time.Time { return now }
+
+	// Ten minutes ago: one slow failure, outside the 1m and 5m windows.
+	now = now.Add(-10 * time.Minute)
+	stats.record(3*time.Second, http.StatusInternalServerError)
+	now = now.Add(10 * time.Minute)
+
+	for i := 0; i < 10; i++ {
+		stats.record(20*time.Millisecond, http.StatusOK)
+	}
+	stats.record(20*time.Millisecond, http.StatusBadGateway)
+
+	oneMinute := stats.window(time.Minute)
+	if oneMinute.Requests != 11 {
+		t.Fatalf("expected 11 requests in the last minute, got %d", oneMinute.Requests)
+	}
+	if math.Abs(oneMinute.RequestsPerSecond-11.0/60) > 1e-9 {
+		t.Fatalf("unexpected rate %v", oneMinute.RequestsPerSecond)
+	}
+	if math.Abs(oneMinute.ErrorRate-1.0/11) > 1e-9 {
+		t.Fatalf("unexpected error rate %v", oneMinute.ErrorRate)
+	}
+	if oneMinute.P50Ms <= 10 || oneMinute.P50Ms > 25 {
+		t.Fatalf("expected p50 within the 10-25ms bucket, got %v", oneMinute.P50Ms)
+	}
+
+	fifteen := stats.window(15 * time.Minute)
+	if fifteen.Requests != 12 || fifteen.P95Ms <= 25 {
+		t.Fatalf("expected the old slow request in the 15m window, got %+v", fifteen)
+	}
+
+	// Slots are reused once the ring wraps around.
+	now = now.Add(statsWindowSeconds * time.Second)
+	stats.record(time.Millisecond, http.StatusOK)
+	if got := stats.window(15 * time.Minute).Requests; got != 1 {
+		t.Fatalf("expected stale slots to be dropped, got %d requests", got)
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+	defer resetMetrics()
+
+	handler := metrics.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/ingresses", nil))
+
+	rr := httptest.NewRecorder()
+	metrics.handleStats(rr, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+
+	var body statsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	for _, label := range []string{"1m", "5m", "15m"} {
+		window, ok := body.Windows[label]
+		if !ok || window.Requests != 1 || window.ErrorRate != 1 {
+			t.Fatalf("unexpected %s window: %+v", label, window)
+		}
+	}
+}
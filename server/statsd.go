@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultStatsDInterval = 10 * time.Second
+	statsDPrefix          = "home_pager."
+)
+
+// statsDEmitter periodically pushes the same counters and gauges exposed on
+// /metrics to a StatsD server over UDP. Counters are sent as deltas since the
+// previous push, as StatsD expects.
+type statsDEmitter struct {
+	conn         net.Conn
+	metrics      *serverMetrics
+	lastRequests uint64
+	lastErrors   uint64
+}
+
+func newStatsDEmitter(addr string, metrics *serverMetrics) (*statsDEmitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsDEmitter{
+		conn:         conn,
+		metrics:      metrics,
+		lastRequests: metrics.requests.Load(),
+		lastErrors:   atomic.LoadUint64(&fetchErrors),
+	}, nil
+}
+
+// payload renders the metrics accumulated since the last call.
+func (e *statsDEmitter) payload() string {
+	requests := e.metrics.requests.Load()
+	errors := atomic.LoadUint64(&fetchErrors)
+	uptime := int64(time.Since(startTime).Seconds())
+
+	var b strings.Builder
+	b.WriteString(statsDPrefix + "requests_total:" + strconv.FormatUint(counterDelta(requests, e.lastRequests), 10) + "|c\n")
+	b.WriteString(statsDPrefix + "uptime:" + strconv.FormatInt(uptime, 10) + "|g\n")
+	b.WriteString(statsDPrefix + "fetch_errors:" + strconv.FormatUint(counterDelta(errors, e.lastErrors), 10) + "|c")
+
+	e.lastRequests = requests
+	e.lastErrors = errors
+	return b.String()
+}
+
+// counterDelta returns how much a counter grew since last. A counter below
+// last was reset, so everything it has counted since is new.
+func counterDelta(current, last uint64) uint64 {
+	if current < last {
+		return current
+	}
+	return current - last
+}
+
+func (e *statsDEmitter) run(ctx context.Context, interval time.Duration) {
+	defer e.conn.Close()
+
+	h := registerHeartbeat("statsd", interval)
+	defer h.stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = backgroundPool.do(ctx, func() {
+				if _, err := e.conn.Write([]byte(e.payload())); err != nil {
+					log.Printf("Error sending StatsD metrics: %v", err)
+				}
+			})
+			h.beat()
+		}
+	}
+}
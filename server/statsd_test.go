@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatsDEmitter(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	emitter, err := newStatsDEmitter(listener.LocalAddr().String(), metrics)
+	if err != nil {
+		t.Fatalf("newStatsDEmitter: %v", err)
+	}
+
+	metrics.requests.Add(5)
+	atomic.AddUint64(&fetchErrors, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go emitter.run(ctx, 10*time.Millisecond)
+
+	_ = listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	packet := string(buf[:n])
+	for _, want := range []string{
+		"home_pager.requests_total:5|c",
+		"home_pager.uptime:",
+		"home_pager.fetch_errors:2|c",
+	} {
+		if !strings.Contains(packet, want) {
+			t.Errorf("expected %q in packet %q", want, packet)
+		}
+	}
+
+	n, _, err = listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if packet := string(buf[:n]); !strings.Contains(packet, "home_pager.requests_total:0|c") {
+		t.Errorf("expected counters to be sent as deltas, got %q", packet)
+	}
+}
+
+func TestStatsDEmitterHandlesCounterReset(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	resetMetrics()
+	defer resetMetrics()
+
+	emitter := &statsDEmitter{metrics: metrics, lastRequests: 10, lastErrors: 4}
+	metrics.requests.Add(3)
+	atomic.AddUint64(&fetchErrors, 1)
+
+	packet := emitter.payload()
+	for _, want := range []string{"home_pager.requests_total:3|c", "home_pager.fetch_errors:1|c"} {
+		if !strings.Contains(packet, want) {
+			t.Errorf("expected %q after a counter reset, got %q", want, packet)
+		}
+	}
+	if emitter.lastRequests != 3 || emitter.lastErrors != 1 {
+		t.Errorf("expected the baseline to follow the reset counters, got %d and %d", emitter.lastRequests, emitter.lastErrors)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// statusToken, when set, is the bearer token required to view /status. It
+// defaults to METRICS_TOKEN since the page shows the same numbers.
+var statusToken string
+
+// lastFetchTime is the Unix time in nanoseconds of the last successful
+// ingress fetch, or zero before the first one.
+var lastFetchTime atomic.Int64
+
+// statusTemplate renders /status. It avoids inline styles and scripts so it
+// works under the default Content-Security-Policy.
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>home-pager status</title>
+</head>
+<body>
+<h1>home-pager status: {{.Status}}</h1>
+<table>
+<tr><th scope="row">Phase</th><td>{{.Phase}}</td></tr>
+<tr><th scope="row">Version</th><td>{{.Version}}</td></tr>
+<tr><th scope="row">Uptime</th><td>{{.Uptime}}</td></tr>
+<tr><th scope="row">Requests served</th><td>{{.Requests}}</td></tr>
+<tr><th scope="row">Fetch errors</th><td>{{.FetchErrors}}</td></tr>
+<tr><th scope="row">Last successful fetch</th><td>{{if .LastFetch.IsZero}}never{{else}}{{.LastFetch.Format "2006-01-02 15:04:05 MST"}} ({{.LastFetchAge}} ago){{end}}</td></tr>
+<tr><th scope="row">Cache TTL</th><td>{{if .CacheTTL}}{{.CacheTTL}}{{else}}disabled{{end}}</td></tr>
+<tr><th scope="row">Cached items</th><td>{{if .Cached}}{{.CachedItems}} ({{.CacheAge}} old){{else}}none{{end}}</td></tr>
+</table>
+<h2>Readiness</h2>
+<table>
+<tr><th>Check</th><th>Result</th></tr>
+{{range .Checks}}<tr><td>{{.Name}}</td><td>{{if .Skipped}}skipped{{else if .OK}}ok{{else}}failing: {{.Error}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type statusCheck struct {
+	Name string
+	readinessCheck
+}
+
+type statusPage struct {
+	Status       string
+	Phase        string
+	Version      string
+	Uptime       time.Duration
+	Requests     uint64
+	FetchErrors  uint64
+	LastFetch    time.Time
+	LastFetchAge time.Duration
+	CacheTTL     time.Duration
+	Cached       bool
+	CachedItems  int
+	CacheAge     time.Duration
+	Checks       []statusCheck
+}
+
+// cacheStatus reports how many items the shared cache entry holds and how
+// old they are. ok is false when nothing is cached.
+func (c *ingressCache) cacheStatus(now time.Time) (items int, age time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[""]
+	if !ok {
+		return 0, 0, false
+	}
+	list, _ := entry.result["items"].([]interface{})
+	return len(list), now.Sub(entry.fetchedAt).Round(time.Second), true
+}
+
+func (m *serverMetrics) buildStatusPage(now time.Time) statusPage {
+	page := statusPage{
+		Status:      "ready",
+		Version:     version,
+		Uptime:      now.Sub(startTime).Round(time.Second),
+		Requests:    m.requests.Load(),
+		FetchErrors: atomic.LoadUint64(&fetchErrors),
+		CacheTTL:    ingressesCache.ttl,
+	}
+	if nanos := lastFetchTime.Load(); nanos != 0 {
+		page.LastFetch = time.Unix(0, nanos).In(displayLocation)
+		page.LastFetchAge = now.Sub(page.LastFetch).Round(time.Second)
+	}
+	page.CachedItems, page.CacheAge, page.Cached = ingressesCache.cacheStatus(now)
+
+	phase, checks, ready := evaluateReadiness()
+	page.Phase = phase.String()
+	if !ready {
+		page.Status = "not ready"
+	}
+	for name, check := range checks {
+		page.Checks = append(page.Checks, statusCheck{Name: name, readinessCheck: check})
+	}
+	sort.Slice(page.Checks, func(i, j int) bool { return page.Checks[i].Name < page.Checks[j].Name })
+	return page
+}
+
+// handleStatus renders a human-readable summary of uptime, traffic, the
+// ingress cache and readiness.
+func (m *serverMetrics) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = statusTemplate.Execute(w, m.buildStatusPage(time.Now()))
+}
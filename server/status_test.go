@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleStatus(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	ingressesCache.reset()
+	defer ingressesCache.reset()
+	ingressesCache.store("", map[string]interface{}{"items": []interface{}{testIngress("default", "app", "app.example.com")}}, time.Now())
+	lastFetchTime.Store(time.Now().UnixNano())
+	defer lastFetchTime.Store(0)
+
+	rr := httptest.NewRecorder()
+	metrics.handleStatus(rr, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Fatalf("expected html, got %q", got)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"<h1>home-pager status: ready</h1>", "Uptime", "<td>1 (", "<td>firstFetch</td><td>skipped</td>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in status page:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "never") {
+		t.Error("expected the last fetch time to be shown")
+	}
+}
+
+func TestHandleStatusUsesDisplayTimezone(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	location, err := loadDisplayLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("loading Asia/Tokyo: %v", err)
+	}
+	prev := displayLocation
+	displayLocation = location
+	defer func() { displayLocation = prev }()
+	lastFetchTime.Store(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).UnixNano())
+	defer lastFetchTime.Store(0)
+
+	rr := httptest.NewRecorder()
+	metrics.handleStatus(rr, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if body := rr.Body.String(); !strings.Contains(body, "2024-03-01 21:00:00 JST") {
+		t.Errorf("expected the last fetch in Tokyo time:\n%s", body)
+	}
+
+	if _, err := loadDisplayLocation("Mars/Olympus_Mons"); err == nil {
+		t.Error("expected an unknown timezone to be rejected")
+	}
+}
+
+func TestHandleStatusRequiresToken(t *testing.T) {
+	metrics := newServerMetrics(defaultLatencyBuckets)
+	statusToken = "secret"
+	defer func() { statusToken = "" }()
+
+	h := requireBearerToken(&statusToken, metrics.handleStatus)
+	rr := httptest.NewRecorder()
+	h(rr, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr = httptest.NewRecorder()
+	h(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 with the token, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	streamBackoffBase   = time.Second
+	streamBackoffMax    = 30 * time.Second
+	streamWatchDuration = 5 * time.Minute
+	streamHeartbeat     = 30 * time.Second
+)
+
+// streamRetryAfter is the Retry-After, in seconds, sent with a 503 when
+// MAX_STREAMS is reached.
+const streamRetryAfter = "30"
+
+var (
+	// maxStreams caps concurrent /api/ingresses/stream connections; zero
+	// allows any number.
+	maxStreams int64
+
+	activeStreams   atomic.Int64
+	rejectedStreams atomic.Uint64
+)
+
+// acquireStream reserves a stream slot, reporting false when maxStreams are
+// already open. A successful call must be paired with releaseStream.
+func acquireStream() bool {
+	if n := activeStreams.Add(1); maxStreams > 0 && n > maxStreams {
+		activeStreams.Add(-1)
+		rejectedStreams.Add(1)
+		return false
+	}
+	return true
+}
+
+func releaseStream() {
+	activeStreams.Add(-1)
+}
+
+func writeStreamMetrics(w io.Writer) {
+	_, _ = io.WriteString(w, "# HELP home_pager_streams_active Open /api/ingresses/stream connections.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_streams_active gauge\n")
+	_, _ = io.WriteString(w, "home_pager_streams_active "+strconv.FormatInt(activeStreams.Load(), 10)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_streams_rejected_total Stream connections refused because MAX_STREAMS were open.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_streams_rejected_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_streams_rejected_total "+strconv.FormatUint(rejectedStreams.Load(), 10)+"\n")
+}
+
+// ingressStream writes server-sent events to a single client.
+type ingressStream struct {
+	mu sync.Mutex
+	w  io.Writer
+	rc *http.ResponseController
+
+	// visible holds the keys of the ingresses the client was last sent as
+	// visible. Only run's goroutine uses it.
+	visible map[string]bool
+}
+
+func (s *ingressStream) send(event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := io.WriteString(s.w, "event: "+event+"\ndata: "+string(data)+"\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *ingressStream) comment(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := io.WriteString(s.w, ": "+text+"\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// backoffDelay returns an exponentially increasing delay for the given retry
+// attempt, capped at streamBackoffMax, with jitter across its upper half.
+func backoffDelay(attempt int) time.Duration {
+	delay := streamBackoffMax
+	if attempt < 16 {
+		delay = min(streamBackoffBase<<attempt, streamBackoffMax)
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int64N(int64(half)+1))
+}
+
+// kubernetesWatchClient returns a client sharing the Kubernetes transport but
+// without an overall timeout, which would otherwise cut long-lived watches.
+func kubernetesWatchClient() *http.Client {
+	client := *httpClient
+	client.Timeout = 0
+	return &client
+}
+
+func handleIngressStream(timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if watchUnavailable(w, r) {
+			return
+		}
+		if !acquireStream() {
+			w.Header().Set("Retry-After", streamRetryAfter)
+			localizedError(w, r, msgTooManyStreams, http.StatusServiceUnavailable)
+			return
+		}
+		defer releaseStream()
+
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("Warning: could not clear write deadline for stream: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		stream := &ingressStream{w: w, rc: rc}
+		stream.run(r.Context(), timeout)
+	}
+}
+
+// run sends a snapshot of the visible ingresses and then forwards watch
+// events until the client disconnects. When the watch fails, it reconnects
+// with exponential backoff and relists so that missed events are not lost.
+// A watch that closes without delivering any event is resumed after the same
+// backoff, so an apiserver or proxy that ends watches at once is not hammered.
+func (s *ingressStream) run(ctx context.Context, timeout time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.heartbeat(ctx, cancel)
+
+	attempt := 0
+	for ctx.Err() == nil {
+		resourceVersion, err := s.sendSnapshot(ctx, timeout)
+		if err == nil {
+			attempt = 0
+			if !kubeAPIConfigured() {
+				<-ctx.Done()
+				return
+			}
+		}
+		idle := 0
+		for err == nil {
+			var delivered bool
+			resourceVersion, delivered, err = s.forwardWatch(ctx, resourceVersion)
+			if err != nil || delivered {
+				idle = 0
+				continue
+			}
+			if !sleepUntil(ctx, time.Now().Add(backoffDelay(idle))) {
+				return
+			}
+			idle++
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Ingress stream interrupted: %v", err)
+		if s.comment("reconnecting") != nil {
+			return
+		}
+
+		timer := time.NewTimer(backoffDelay(attempt))
+		attempt++
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *ingressStream) heartbeat(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.comment("heartbeat") != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (s *ingressStream) sendSnapshot(ctx context.Context, timeout time.Duration) (string, error) {
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := fetchIngresses(fetchCtx)
+	if err != nil {
+		return "", err
+	}
+
+	snapshot := filterIngresses(result)
+	metadata, _ := result["metadata"].(map[string]interface{})
+	if err := s.send("snapshot", snapshot); err != nil {
+		return "", err
+	}
+	s.visible = visibleIngressKeys(snapshot)
+	return stringField(metadata, "resourceVersion"), nil
+}
+
+// forwardWatch streams watch events from resourceVersion to the client and
+// returns the latest resourceVersion seen and whether any event, bookmarks
+// included, arrived. A nil error means the apiserver closed the watch
+// normally and it can be resumed.
+func (s *ingressStream) forwardWatch(ctx context.Context, resourceVersion string) (string, bool, error) {
+	watchCtx, cancel := context.WithTimeout(ctx, streamWatchDuration+time.Minute)
+	defer cancel()
+
+	req, err := newKubernetesRequest(watchCtx, listedResource.path(), url.Values{
+		"watch":               {"1"},
+		"resourceVersion":     {resourceVersion},
+		"allowWatchBookmarks": {"true"},
+		"timeoutSeconds":      {strconv.Itoa(int(streamWatchDuration.Seconds()))},
+	})
+	if err != nil {
+		return resourceVersion, false, err
+	}
+
+	resp, err := kubernetesWatchClient().Do(req)
+	if err != nil {
+		return resourceVersion, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		return resourceVersion, false, errResourceVersionGone
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxIngressesBodyBytes))
+		return resourceVersion, false, &kubernetesAPIError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+	}
+
+	delivered := false
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	for {
+		var event watchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return resourceVersion, delivered, nil
+			}
+			return resourceVersion, delivered, err
+		}
+		delivered = true
+
+		switch event.Type {
+		case "ERROR":
+			if watchStatusCode(event.Object) == http.StatusGone {
+				return resourceVersion, delivered, errResourceVersionGone
+			}
+			return resourceVersion, delivered, errors.New("kubernetes watch error: " + stringField(event.Object, "message"))
+		case "BOOKMARK":
+		default:
+			key := objectKey(event.Object)
+			visible := event.Type != "DELETED" && isVisibleIngress(event.Object)
+			eventType := visibilityEventType(s.visible[key], visible)
+			if visible {
+				s.visible[key] = true
+			} else {
+				delete(s.visible, key)
+			}
+			if eventType == "" {
+				break
+			}
+			if err := s.send(eventType, event.Object); err != nil {
+				return resourceVersion, delivered, err
+			}
+		}
+
+		if rv := objectResourceVersion(event.Object); rv != "" {
+			resourceVersion = rv
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		got := backoffDelay(attempt)
+		if got < want/2 || got > want {
+			t.Fatalf("attempt %d: expected delay in [%v, %v], got %v", attempt, want/2, want, got)
+		}
+	}
+	if got := backoffDelay(100); got > streamBackoffMax {
+		t.Fatalf("expected delay capped at %v, got %v", streamBackoffMax, got)
+	}
+}
+
+func TestIngressStreamReconnects(t *testing.T) {
+	prevBase := streamBackoffBase
+	streamBackoffBase = time.Millisecond
+	defer func() { streamBackoffBase = prevBase }()
+
+	var lists, watches int32
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "1" {
+			if atomic.AddInt32(&watches, 1) == 1 {
+				_ = json.NewEncoder(w).Encode(watchEvent{Type: "ADDED", Object: watchObject("app", "11")})
+				_ = json.NewEncoder(w).Encode(watchEvent{Type: "ERROR", Object: map[string]interface{}{"code": 410}})
+				return
+			}
+			<-r.Context().Done()
+			return
+		}
+		atomic.AddInt32(&lists, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"metadata": map[string]interface{}{"resourceVersion": "10"},
+			"items":    []interface{}{},
+		})
+	}))
+
+	srv := httptest.NewServer(handleIngressStream(time.Second))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected event stream content type, got %q", ct)
+	}
+
+	var lines []string
+	snapshots := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lines = append(lines, line)
+		if line == "event: snapshot" {
+			snapshots++
+			if snapshots == 2 {
+				break
+			}
+		}
+	}
+
+	got := strings.Join(lines, "\n")
+	for _, want := range []string{"event: snapshot", "event: added", ": reconnecting"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %q in stream:\n%s", want, got)
+		}
+	}
+	if snapshots != 2 || atomic.LoadInt32(&lists) < 2 {
+		t.Fatalf("expected a relist after reconnect, got %d snapshots and %d lists", snapshots, lists)
+	}
+}
+
+func TestIngressStreamReportsVisibilityChanges(t *testing.T) {
+	setFlags(t, func(f *featureFlags) { f.hiddenHostPatterns = parseHostPatterns("*.internal.local") })
+	hidden := func(name, resourceVersion string) map[string]interface{} {
+		item := testIngress("default", name, name+".internal.local")
+		item["metadata"].(map[string]interface{})["resourceVersion"] = resourceVersion
+		return item
+	}
+
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") != "1" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"metadata": map[string]interface{}{"resourceVersion": "10"},
+				"items":    []interface{}{hidden("app", "9")},
+			})
+			return
+		}
+		if r.URL.Query().Get("resourceVersion") != "10" {
+			<-r.Context().Done()
+			return
+		}
+		enc := json.NewEncoder(w)
+		_ = enc.Encode(watchEvent{Type: "MODIFIED", Object: hidden("other", "11")})
+		_ = enc.Encode(watchEvent{Type: "MODIFIED", Object: watchObject("app", "12")})
+		_ = enc.Encode(watchEvent{Type: "MODIFIED", Object: watchObject("app", "13")})
+		_ = enc.Encode(watchEvent{Type: "MODIFIED", Object: hidden("app", "14")})
+	}))
+
+	srv := httptest.NewServer(handleIngressStream(time.Second))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, event)
+			if event == "deleted" {
+				break
+			}
+		}
+	}
+
+	want := "snapshot,added,modified,deleted"
+	if got := strings.Join(events, ","); got != want {
+		t.Fatalf("expected events %s, got %s", want, got)
+	}
+}
+
+func TestIngressStreamBacksOffEmptyWatches(t *testing.T) {
+	prevBase := streamBackoffBase
+	streamBackoffBase = 100 * time.Millisecond
+	defer func() { streamBackoffBase = prevBase }()
+
+	var watches int32
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "1" {
+			// The watch closes at once without any event.
+			atomic.AddInt32(&watches, 1)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"metadata": map[string]interface{}{"resourceVersion": "10"},
+			"items":    []interface{}{},
+		})
+	}))
+
+	srv := httptest.NewServer(handleIngressStream(time.Second))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	<-ctx.Done()
+	resp.Body.Close()
+
+	if got := atomic.LoadInt32(&watches); got < 1 || got > 4 {
+		t.Fatalf("expected a few backed-off watches, got %d", got)
+	}
+}
+
+func TestIngressStreamLimit(t *testing.T) {
+	maxStreams = 1
+	defer func() { maxStreams = 0 }()
+
+	srv := httptest.NewServer(handleIngressStream(time.Second))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	first, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request failed: %v", err)
+	}
+	if !bufio.NewScanner(first.Body).Scan() {
+		t.Fatal("expected the first stream to start")
+	}
+
+	second, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = second.Body.Close()
+	if second.StatusCode != http.StatusServiceUnavailable || second.Header.Get("Retry-After") != streamRetryAfter {
+		t.Fatalf("expected 503 with Retry-After over the limit, got %d %q", second.StatusCode, second.Header.Get("Retry-After"))
+	}
+	if activeStreams.Load() != 1 || rejectedStreams.Load() == 0 {
+		t.Fatalf("expected 1 active stream and a rejection, got %d and %d", activeStreams.Load(), rejectedStreams.Load())
+	}
+
+	cancel()
+	_ = first.Body.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for activeStreams.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the closed stream to free its slot")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	legacyAnnotationPrefix = "homepage.link/"
+	linkAnnotationPrefix   = annotationPrefix + "link."
+	orderAnnotation        = annotationPrefix + "order"
+	urlAnnotation          = annotationPrefix + "url"
+
+	// defaultOrder places ingresses without an order annotation after all
+	// explicitly ordered ones.
+	defaultOrder = math.MaxInt32
+)
+
+// Response formats accepted by the format query parameter of /api/ingresses.
+const (
+	formatRaw       = "raw"
+	formatSummary   = "summary"
+	formatTable     = "table"
+	formatBookmarks = "bookmarks"
+)
+
+// parseFormat normalizes the format query parameter, defaulting to the raw
+// Kubernetes list. It reports false for unknown formats.
+func parseFormat(raw string) (string, bool) {
+	switch format := strings.ToLower(strings.TrimSpace(raw)); format {
+	case "", formatRaw:
+		return formatRaw, true
+	case formatSummary, formatTable, formatBookmarks:
+		return format, true
+	default:
+		return "", false
+	}
+}
+
+// rawDeprecationWarning is sent in the Warning header of raw-format responses
+// when DEPRECATE_RAW is set.
+const rawDeprecationWarning = `299 - "The raw ingress format is deprecated; use ?format=summary"`
+
+// setRawDeprecationHeaders points clients of the raw format at the summary
+// format, using the Deprecation header and a Link to its replacement.
+func setRawDeprecationHeaders(w http.ResponseWriter) {
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Warning", rawDeprecationWarning)
+	w.Header().Set("Link", `</api/ingresses?format=summary>; rel="successor-version"`)
+}
+
+// ingressSummary is the dashboard-oriented view of an ingress returned by
+// /api/ingresses?format=summary. Its JSON keys are part of the public API and
+// deliberately independent of the Kubernetes object layout.
+type ingressSummary struct {
+	Namespace        string            `json:"namespace"`
+	Name             string            `json:"name"`
+	Title            string            `json:"title"`
+	Description      string            `json:"description,omitempty"`
+	Icon             string            `json:"icon,omitempty"`
+	Hosts            []string          `json:"hosts"`
+	URL              string            `json:"url,omitempty"`
+	URLs             []string          `json:"urls,omitempty"`
+	IngressClassName string            `json:"ingressClassName,omitempty"`
+	TLS              bool              `json:"tls"`
+	Visibility       string            `json:"visibility,omitempty"`
+	Links            []summaryLink     `json:"links,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	Backends         []backendRef      `json:"backends,omitempty"`
+	Namespaces       []string          `json:"namespaces,omitempty"`
+	Order            *int              `json:"order,omitempty"`
+	IsFavorite       bool              `json:"isFavorite,omitempty"`
+	Health           string            `json:"health,omitempty"`
+	Source           string            `json:"source"`
+}
+
+func (s ingressSummary) sortOrder() int {
+	if s.Order == nil {
+		return defaultOrder
+	}
+	return *s.Order
+}
+
+// backendRef is the routing target of an ingress path, or of the default
+// backend when host and path are empty. Exactly one of Service or Resource is
+// set, mirroring the two backend forms of networking.k8s.io/v1.
+type backendRef struct {
+	Host     string       `json:"host,omitempty"`
+	Path     string       `json:"path,omitempty"`
+	PathType string       `json:"pathType,omitempty"`
+	Service  *serviceRef  `json:"service,omitempty"`
+	Resource *resourceRef `json:"resource,omitempty"`
+}
+
+type serviceRef struct {
+	Name string `json:"name"`
+	Port string `json:"port,omitempty"`
+}
+
+type resourceRef struct {
+	APIGroup string `json:"apiGroup,omitempty"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+}
+
+type summaryLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// summaryResponse is the envelope of the summary format.
+type summaryResponse struct {
+	ResourceVersion string           `json:"resourceVersion,omitempty"`
+	Count           int              `json:"count"`
+	Ingresses       []ingressSummary `json:"ingresses"`
+	Truncated       bool             `json:"truncated,omitempty"`
+	Warnings        []string         `json:"warnings,omitempty"`
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	value, _ := m[key].(string)
+	return value
+}
+
+func annotationValue(item map[string]interface{}, key string) string {
+	return strings.TrimSpace(stringField(ingressAnnotations(item), key))
+}
+
+// summarizeIngresses converts the items of an ingress list response into
+// dashboard summaries, merged with extra summaries from other sources.
+func summarizeIngresses(ctx context.Context, result map[string]interface{}, extra []ingressSummary) summaryResponse {
+	items, _ := result["items"].([]interface{})
+
+	summaries := make([]ingressSummary, 0, len(items))
+	for _, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		summaries = append(summaries, summarizeIngress(itemMap))
+	}
+	summaries = append(summaries, extra...)
+	if currentFlags().dedupeHosts {
+		summaries = dedupeSummariesByHost(summaries)
+	}
+	markFavorites(ctx, summaries)
+	markHealth(summaries)
+	sortSummaries(summaries)
+
+	metadata, _ := result["metadata"].(map[string]interface{})
+	return summaryResponse{
+		ResourceVersion: stringField(metadata, "resourceVersion"),
+		Count:           len(summaries),
+		Ingresses:       summaries,
+		Truncated:       result["truncated"] == true,
+		Warnings:        resultWarnings(result),
+	}
+}
+
+func summarizeIngress(item map[string]interface{}) ingressSummary {
+	metadata, _ := item["metadata"].(map[string]interface{})
+	spec, _ := item["spec"].(map[string]interface{})
+	tls, _ := spec["tls"].([]interface{})
+
+	summary := ingressSummary{
+		Namespace:        stringField(metadata, "namespace"),
+		Name:             stringField(metadata, "name"),
+		Title:            annotationValue(item, legacyAnnotationPrefix+"name"),
+		Description:      annotationValue(item, legacyAnnotationPrefix+"description"),
+		Icon:             annotationValue(item, legacyAnnotationPrefix+"icon"),
+		Hosts:            ingressHosts(item),
+		IngressClassName: stringField(spec, "ingressClassName"),
+		TLS:              len(tls) > 0,
+		Visibility:       ingressVisibility(item),
+		Links:            ingressLinks(item),
+		Tags:             ingressTags(item),
+		Backends:         ingressBackends(item),
+		Order:            ingressOrder(item),
+		Source:           sourceIngress,
+	}
+	if summary.Title == "" {
+		summary.Title = summary.Name
+	}
+	if summary.Hosts == nil {
+		summary.Hosts = []string{}
+	}
+
+	tlsHosts := ingressTLSHosts(item)
+	for _, host := range summary.Hosts {
+		summary.URLs = append(summary.URLs, hostURL(host, tlsHosts))
+	}
+	if len(summary.URLs) > 0 {
+		summary.URL = summary.URLs[0]
+	}
+	if override := annotationValue(item, urlAnnotation); override != "" && isValidLinkURL(override) {
+		summary.URL = override
+	}
+	return summary
+}
+
+// ingressTLSHosts returns the lowercased hosts listed under spec.tls.
+func ingressTLSHosts(item map[string]interface{}) []string {
+	spec, _ := item["spec"].(map[string]interface{})
+	entries, _ := spec["tls"].([]interface{})
+
+	var hosts []string
+	for _, entry := range entries {
+		entryMap, _ := entry.(map[string]interface{})
+		entryHosts, _ := entryMap["hosts"].([]interface{})
+		for _, host := range entryHosts {
+			if h, ok := host.(string); ok && h != "" {
+				hosts = append(hosts, strings.ToLower(h))
+			}
+		}
+	}
+	return hosts
+}
+
+// hostURL builds the link for a host, using https when the host is covered by
+// the ingress TLS configuration (including wildcard entries) or FORCE_HTTPS
+// is set.
+func hostURL(host string, tlsHosts []string) string {
+	if currentFlags().forceHTTPS || matchesAnyHostPattern(host, tlsHosts) {
+		return "https://" + host
+	}
+	return "http://" + host
+}
+
+// ingressBackends lists the default backend followed by the backend of every
+// rule path. With dedupePaths, a host, path and path type repeated across
+// rules is listed once, at its first occurrence.
+func ingressBackends(item map[string]interface{}) []backendRef {
+	spec, _ := item["spec"].(map[string]interface{})
+	seen := make(map[[3]string]bool)
+	dedupePaths := currentFlags().dedupePaths
+
+	var backends []backendRef
+	if backend, ok := spec["defaultBackend"].(map[string]interface{}); ok {
+		if ref, ok := parseBackend(backend); ok {
+			backends = append(backends, ref)
+		}
+	}
+
+	rules, _ := spec["rules"].([]interface{})
+	for _, rule := range rules {
+		ruleMap, _ := rule.(map[string]interface{})
+		httpRule, _ := ruleMap["http"].(map[string]interface{})
+		paths, _ := httpRule["paths"].([]interface{})
+		for _, p := range paths {
+			pathMap, _ := p.(map[string]interface{})
+			backend, _ := pathMap["backend"].(map[string]interface{})
+			ref, ok := parseBackend(backend)
+			if !ok {
+				continue
+			}
+			ref.Host = stringField(ruleMap, "host")
+			ref.Path = stringField(pathMap, "path")
+			ref.PathType = stringField(pathMap, "pathType")
+			key := [3]string{strings.ToLower(ref.Host), ref.Path, ref.PathType}
+			if dedupePaths && seen[key] {
+				continue
+			}
+			seen[key] = true
+			backends = append(backends, ref)
+		}
+	}
+	return backends
+}
+
+func parseBackend(backend map[string]interface{}) (backendRef, bool) {
+	if service, ok := backend["service"].(map[string]interface{}); ok {
+		port, _ := service["port"].(map[string]interface{})
+		portValue := stringField(port, "name")
+		if number, ok := port["number"]; ok {
+			portValue = formatJSONNumber(number)
+		}
+		return backendRef{Service: &serviceRef{Name: stringField(service, "name"), Port: portValue}}, true
+	}
+
+	if resource, ok := backend["resource"].(map[string]interface{}); ok {
+		return backendRef{Resource: &resourceRef{
+			APIGroup: stringField(resource, "apiGroup"),
+			Kind:     stringField(resource, "kind"),
+			Name:     stringField(resource, "name"),
+		}}, true
+	}
+
+	return backendRef{}, false
+}
+
+// jsonInt converts a decoded JSON number, whether float64 or json.Number, to
+// an int.
+func jsonInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), true
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n), true
+		}
+		if f, err := v.Float64(); err == nil {
+			return int(f), true
+		}
+	}
+	return 0, false
+}
+
+// formatJSONNumber renders a decoded JSON number without a fractional part or
+// exponent where possible.
+func formatJSONNumber(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+// ingressLinks collects home-pager.io/link.<label> annotations into secondary
+// links, skipping any whose value is not an absolute http(s) URL.
+func ingressLinks(item map[string]interface{}) []summaryLink {
+	var links []summaryLink
+	for key, value := range ingressAnnotations(item) {
+		label, ok := strings.CutPrefix(key, linkAnnotationPrefix)
+		if !ok || label == "" {
+			continue
+		}
+		raw, _ := value.(string)
+		raw = strings.TrimSpace(raw)
+		if !isValidLinkURL(raw) {
+			continue
+		}
+		links = append(links, summaryLink{Label: label, URL: raw})
+	}
+
+	sort.Slice(links, func(i, j int) bool { return links[i].Label < links[j].Label })
+	return links
+}
+
+// ingressOrder parses the home-pager.io/order annotation, returning nil when
+// it is absent or not an integer.
+func ingressOrder(item map[string]interface{}) *int {
+	raw := annotationValue(item, orderAnnotation)
+	if raw == "" {
+		return nil
+	}
+	order, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil
+	}
+	return &order
+}
+
+// sortSummaries orders summaries by their order annotation, then namespace
+// and name.
+func sortSummaries(summaries []ingressSummary) {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.IsFavorite != b.IsFavorite {
+			return a.IsFavorite
+		}
+		if a.sortOrder() != b.sortOrder() {
+			return a.sortOrder() < b.sortOrder()
+		}
+		return summaryIdentityLess(a, b)
+	})
+}
+
+// summaryIdentityLess orders summaries by namespace and name, breaking the
+// remaining ties by title, source and URL. Every comparison ends here so the
+// order never depends on how the apiserver or a Go map happened to order the
+// input, and replicas return tiles in the same order.
+func summaryIdentityLess(a, b ingressSummary) bool {
+	switch {
+	case a.Namespace != b.Namespace:
+		return a.Namespace < b.Namespace
+	case a.Name != b.Name:
+		return a.Name < b.Name
+	case a.Title != b.Title:
+		return a.Title < b.Title
+	case a.Source != b.Source:
+		return a.Source < b.Source
+	default:
+		return a.URL < b.URL
+	}
+}
+
+func isValidLinkURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// dedupeSummariesByHost merges summaries whose first host is the same,
+// case-insensitively. Summaries are ordered by namespace and name first so the
+// entry that supplies title, icon and other fields is chosen deterministically;
+// the merged entry lists every contributing namespace.
+func dedupeSummariesByHost(summaries []ingressSummary) []ingressSummary {
+	sorted := make([]ingressSummary, len(summaries))
+	copy(sorted, summaries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return summaryIdentityLess(sorted[i], sorted[j])
+	})
+
+	merged := make([]ingressSummary, 0, len(sorted))
+	byHost := make(map[string]int)
+	for _, summary := range sorted {
+		if len(summary.Hosts) == 0 {
+			merged = append(merged, summary)
+			continue
+		}
+
+		key := strings.ToLower(summary.Hosts[0])
+		idx, ok := byHost[key]
+		if !ok {
+			summary.Namespaces = []string{summary.Namespace}
+			byHost[key] = len(merged)
+			merged = append(merged, summary)
+			continue
+		}
+
+		existing := &merged[idx]
+		existing.Hosts = appendUnique(existing.Hosts, summary.Hosts...)
+		existing.URLs = appendUnique(existing.URLs, summary.URLs...)
+		existing.Namespaces = appendUnique(existing.Namespaces, summary.Namespace)
+	}
+	return merged
+}
+
+func appendUnique(values []string, additions ...string) []string {
+	for _, addition := range additions {
+		found := false
+		for _, value := range values {
+			if strings.EqualFold(value, addition) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, addition)
+		}
+	}
+	return values
+}
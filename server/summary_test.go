@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeIngress(t *testing.T) {
+	item := testIngress("media", "jellyfin", "jellyfin.example.com")
+	item["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{
+		legacyAnnotationPrefix + "name": "Jellyfin",
+		legacyAnnotationPrefix + "icon": "🎬",
+		linkAnnotationPrefix + "docs":   "https://jellyfin.org/docs",
+		linkAnnotationPrefix + "admin":  "https://jellyfin.example.com/admin",
+		linkAnnotationPrefix + "broken": "not a url",
+		linkAnnotationPrefix + "script": "javascript:alert(1)",
+	}
+	item["spec"].(map[string]interface{})["ingressClassName"] = "nginx"
+	item["spec"].(map[string]interface{})["tls"] = []interface{}{map[string]interface{}{"hosts": []interface{}{"jellyfin.example.com"}}}
+
+	summary := summarizeIngress(item)
+	if summary.Namespace != "media" || summary.Name != "jellyfin" || summary.Title != "Jellyfin" {
+		t.Fatalf("unexpected identity fields: %+v", summary)
+	}
+	if summary.IngressClassName != "nginx" || !summary.TLS {
+		t.Fatalf("unexpected class/tls fields: %+v", summary)
+	}
+	if len(summary.Hosts) != 1 || summary.Hosts[0] != "jellyfin.example.com" {
+		t.Fatalf("unexpected hosts: %v", summary.Hosts)
+	}
+
+	want := []summaryLink{
+		{Label: "admin", URL: "https://jellyfin.example.com/admin"},
+		{Label: "docs", URL: "https://jellyfin.org/docs"},
+	}
+	if len(summary.Links) != len(want) {
+		t.Fatalf("expected links %v, got %v", want, summary.Links)
+	}
+	for i := range want {
+		if summary.Links[i] != want[i] {
+			t.Fatalf("expected links %v, got %v", want, summary.Links)
+		}
+	}
+}
+
+func TestSummarizeIngressURLs(t *testing.T) {
+	item := testIngress("default", "app", "app.example.com", "Plain.example.org", "wild.apps.example.com")
+	item["spec"].(map[string]interface{})["tls"] = []interface{}{
+		map[string]interface{}{"hosts": []interface{}{"app.example.com"}},
+		map[string]interface{}{"hosts": []interface{}{"*.apps.example.com"}},
+	}
+
+	summary := summarizeIngress(item)
+	want := []string{"https://app.example.com", "http://Plain.example.org", "https://wild.apps.example.com"}
+	if strings.Join(summary.URLs, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected urls %v, got %v", want, summary.URLs)
+	}
+	if summary.URL != want[0] {
+		t.Fatalf("expected primary url %q, got %q", want[0], summary.URL)
+	}
+
+	setFlags(t, func(f *featureFlags) { f.forceHTTPS = true })
+	if got := summarizeIngress(item).URLs[1]; got != "https://Plain.example.org" {
+		t.Fatalf("expected FORCE_HTTPS to upgrade plain host, got %q", got)
+	}
+}
+
+func TestSummarizeIngressBackends(t *testing.T) {
+	item := testIngress("default", "app")
+	spec := item["spec"].(map[string]interface{})
+	spec["defaultBackend"] = map[string]interface{}{
+		"resource": map[string]interface{}{"apiGroup": "k8s.example.com", "kind": "StorageBucket", "name": "static-assets"},
+	}
+	spec["rules"] = []interface{}{
+		map[string]interface{}{
+			"host": "app.example.com",
+			"http": map[string]interface{}{
+				"paths": []interface{}{
+					map[string]interface{}{
+						"path":     "/",
+						"pathType": "Prefix",
+						"backend":  map[string]interface{}{"service": map[string]interface{}{"name": "web", "port": map[string]interface{}{"number": float64(8080)}}},
+					},
+					map[string]interface{}{
+						"path":     "/api",
+						"pathType": "Exact",
+						"backend":  map[string]interface{}{"service": map[string]interface{}{"name": "api", "port": map[string]interface{}{"name": "http"}}},
+					},
+				},
+			},
+		},
+	}
+
+	backends := summarizeIngress(item).Backends
+	if len(backends) != 3 {
+		t.Fatalf("expected 3 backends, got %d: %+v", len(backends), backends)
+	}
+	if backends[0].Resource == nil || backends[0].Resource.Kind != "StorageBucket" || backends[0].Host != "" {
+		t.Fatalf("expected default resource backend first, got %+v", backends[0])
+	}
+	if b := backends[1]; b.Host != "app.example.com" || b.Path != "/" || b.PathType != "Prefix" || b.Service == nil || b.Service.Name != "web" || b.Service.Port != "8080" {
+		t.Fatalf("unexpected numbered service backend: %+v", b)
+	}
+	if b := backends[2]; b.PathType != "Exact" || b.Service == nil || b.Service.Name != "api" || b.Service.Port != "http" {
+		t.Fatalf("unexpected named-port service backend: %+v", b)
+	}
+}
+
+func TestSummarizeIngressDedupesPaths(t *testing.T) {
+	service := func(name string) map[string]interface{} {
+		return map[string]interface{}{"service": map[string]interface{}{"name": name, "port": map[string]interface{}{"number": float64(80)}}}
+	}
+	path := func(p, pathType, backend string) interface{} {
+		return map[string]interface{}{"path": p, "pathType": pathType, "backend": service(backend)}
+	}
+	item := testIngress("default", "app")
+	item["spec"].(map[string]interface{})["rules"] = []interface{}{
+		map[string]interface{}{
+			"host": "app.example.com",
+			"http": map[string]interface{}{"paths": []interface{}{
+				path("/", "Prefix", "web"),
+				path("/api", "Prefix", "api"),
+				path("/", "Prefix", "web-duplicate"),
+			}},
+		},
+		map[string]interface{}{
+			"host": "APP.example.com",
+			"http": map[string]interface{}{"paths": []interface{}{
+				path("/api", "Prefix", "api-duplicate"),
+				path("/api", "Exact", "api-exact"),
+			}},
+		},
+	}
+
+	if n := len(summarizeIngress(item).Backends); n != 5 {
+		t.Fatalf("expected all 5 paths by default, got %d", n)
+	}
+
+	setFlags(t, func(f *featureFlags) { f.dedupePaths = true })
+	var got []string
+	for _, b := range summarizeIngress(item).Backends {
+		got = append(got, b.Path+" "+b.PathType+" "+b.Service.Name)
+	}
+	want := []string{"/ Prefix web", "/api Prefix api", "/api Exact api-exact"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected first occurrences with DEDUPE_PATHS=true %v, got %v", want, got)
+	}
+}
+
+func TestSummarizeIngressDefaults(t *testing.T) {
+	summary := summarizeIngress(testIngress("default", "app"))
+	if summary.Title != "app" {
+		t.Fatalf("expected title to default to name, got %q", summary.Title)
+	}
+	if summary.Hosts == nil || summary.Links != nil || summary.URL != "" {
+		t.Fatalf("expected empty hosts, no links and no url, got %+v", summary)
+	}
+}
+
+func TestDedupeSummariesByHost(t *testing.T) {
+	summaries := []ingressSummary{
+		{Namespace: "staging", Name: "app", Title: "Staging App", Hosts: []string{"app.example.com"}},
+		{Namespace: "prod", Name: "app", Title: "Prod App", Hosts: []string{"APP.example.com", "www.example.com"}},
+		{Namespace: "prod", Name: "other", Title: "Other", Hosts: []string{"other.example.com"}},
+		{Namespace: "default", Name: "hostless", Title: "Hostless", Hosts: []string{}},
+	}
+
+	got := dedupeSummariesByHost(summaries)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 summaries after dedupe, got %d: %+v", len(got), got)
+	}
+
+	app := got[1]
+	if app.Title != "Prod App" {
+		t.Fatalf("expected prod namespace to take precedence, got %q", app.Title)
+	}
+	if len(app.Namespaces) != 2 || app.Namespaces[0] != "prod" || app.Namespaces[1] != "staging" {
+		t.Fatalf("expected contributing namespaces [prod staging], got %v", app.Namespaces)
+	}
+	if len(app.Hosts) != 2 {
+		t.Fatalf("expected merged hosts, got %v", app.Hosts)
+	}
+
+	if got[0].Name != "hostless" || got[0].Namespaces != nil {
+		t.Fatalf("expected hostless summary untouched, got %+v", got[0])
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	cases := map[string]string{"": formatRaw, "raw": formatRaw, "Summary": formatSummary}
+	for raw, want := range cases {
+		if got, ok := parseFormat(raw); !ok || got != want {
+			t.Errorf("parseFormat(%q) = %q, %v; want %q", raw, got, ok, want)
+		}
+	}
+	if _, ok := parseFormat("xml"); ok {
+		t.Error("expected unknown format to be rejected")
+	}
+}
+
+func TestSummarizeIngressesOrdering(t *testing.T) {
+	withOrder := func(name, order string) map[string]interface{} {
+		item := testIngress("default", name)
+		if order != "" {
+			item["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{orderAnnotation: order}
+		}
+		return item
+	}
+
+	result := map[string]interface{}{
+		"items": []interface{}{
+			withOrder("zeta", ""),
+			withOrder("alpha", ""),
+			withOrder("second", "20"),
+			withOrder("first", "10"),
+			withOrder("tied-b", "20"),
+			withOrder("invalid", "soon"),
+		},
+	}
+
+	var got []string
+	for _, summary := range summarizeIngresses(context.Background(), result, nil).Ingresses {
+		got = append(got, summary.Name)
+	}
+
+	want := []string{"first", "second", "tied-b", "alpha", "invalid", "zeta"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected order %v, got %v", want, got)
+	}
+}
+
+func TestSummarizeIngressesBreaksTiesByNamespaceAndName(t *testing.T) {
+	titled := func(namespace, name, title string) map[string]interface{} {
+		item := testIngress(namespace, name)
+		item["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{legacyAnnotationPrefix + "name": title}
+		return item
+	}
+	result := map[string]interface{}{
+		"items": []interface{}{
+			titled("media", "jellyfin", "Alpha"),
+			titled("books", "kavita", "Zulu"),
+			titled("books", "calibre", "Mike"),
+		},
+	}
+
+	var got []string
+	for _, summary := range summarizeIngresses(context.Background(), result, nil).Ingresses {
+		got = append(got, summary.Namespace+"/"+summary.Name)
+	}
+	want := []string{"books/calibre", "books/kavita", "media/jellyfin"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected order %v, got %v", want, got)
+	}
+}
+
+func TestSummarizeIngressesOrderIsDeterministic(t *testing.T) {
+	titled := func(namespace, name, title string) map[string]interface{} {
+		item := testIngress(namespace, name, name+"."+namespace+".example.com")
+		item["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{legacyAnnotationPrefix + "name": title}
+		return item
+	}
+	items := []interface{}{
+		titled("media", "jellyfin", "Media"),
+		titled("books", "calibre", "Media"),
+		titled("books", "kavita", "media"),
+		titled("default", "app", "App"),
+		titled("default", "app", "app"),
+		testIngress("default", "untitled"),
+	}
+	extra := []ingressSummary{
+		{Namespace: "default", Name: "app", Title: "App", Source: sourceHomepageEntry, URL: "https://app.example.com"},
+	}
+
+	order := func(items []interface{}, extra []ingressSummary) string {
+		var names []string
+		for _, summary := range summarizeIngresses(context.Background(), map[string]interface{}{"items": items}, extra).Ingresses {
+			names = append(names, summary.Namespace+"/"+summary.Name+"/"+summary.Title+"/"+summary.Source)
+		}
+		return strings.Join(names, ",")
+	}
+
+	reversed := make([]interface{}, len(items))
+	for i, item := range items {
+		reversed[len(items)-1-i] = item
+	}
+
+	first := order(items, extra)
+	for i := 0; i < 5; i++ {
+		if got := order(reversed, extra); got != first {
+			t.Fatalf("expected identical ordering for reordered input:\n%s\n%s", first, got)
+		}
+		if got := order(items, extra); got != first {
+			t.Fatalf("expected identical ordering across calls:\n%s\n%s", first, got)
+		}
+	}
+}
+
+func TestHandleIngressesFormats(t *testing.T) {
+	kubernetesServiceHost = ""
+	kubernetesServicePort = ""
+	h := handleIngresses(time.Second)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses?format=summary", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 for summary format, got %d", rr.Code)
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid json for summary format: %v", err)
+	}
+	if string(payload["ingresses"]) != "[]" || string(payload["count"]) != "0" {
+		t.Fatalf("expected empty ingresses and zero count, got %s", rr.Body.String())
+	}
+	if _, ok := payload["items"]; ok {
+		t.Fatal("expected summary envelope not to use the Kubernetes items key")
+	}
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses?format=xml", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown format, got %d", rr.Code)
+	}
+}
+
+func TestHandleIngressesRawDeprecation(t *testing.T) {
+	kubernetesServiceHost = ""
+	kubernetesServicePort = ""
+	setFlags(t, func(f *featureFlags) { f.deprecateRaw = true })
+	h := handleIngresses(time.Second)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected the raw format to keep working, got %d", rr.Code)
+	}
+	if rr.Header().Get("Deprecation") != "true" || !strings.Contains(rr.Header().Get("Warning"), "format=summary") {
+		t.Fatalf("expected deprecation headers, got %v", rr.Header())
+	}
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses?format=summary", nil))
+	if rr.Header().Get("Deprecation") != "" {
+		t.Fatal("expected no deprecation header on the summary format")
+	}
+}
+
+func TestSummarizeIngressURLOverride(t *testing.T) {
+	item := testIngress("default", "app", "app.internal")
+	metadata := item["metadata"].(map[string]interface{})
+
+	metadata["annotations"] = map[string]interface{}{urlAnnotation: " https://app.example.com/dashboard "}
+	summary := summarizeIngress(item)
+	if summary.URL != "https://app.example.com/dashboard" {
+		t.Fatalf("expected the annotation to override url, got %q", summary.URL)
+	}
+	if len(summary.URLs) != 1 || summary.URLs[0] != "http://app.internal" {
+		t.Fatalf("expected per-host urls to be kept, got %v", summary.URLs)
+	}
+
+	for _, invalid := range []string{"/dashboard", "javascript:alert(1)", "app.example.com"} {
+		metadata["annotations"] = map[string]interface{}{urlAnnotation: invalid}
+		if got := summarizeIngress(item).URL; got != "http://app.internal" {
+			t.Fatalf("expected invalid override %q to be ignored, got %q", invalid, got)
+		}
+	}
+}
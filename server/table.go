@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// writeSummaryTable renders summaries as a fixed-width text table for
+// /api/ingresses?format=table, which is easier to scan from a terminal than
+// JSON.
+func writeSummaryTable(w io.Writer, response summaryResponse) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = io.WriteString(tw, "NAMESPACE\tNAME\tHOST\tCLASS\tTLS\n")
+	for _, summary := range response.Ingresses {
+		_, _ = io.WriteString(tw, strings.Join([]string{
+			tableCell(summary.Namespace),
+			tableCell(summary.Name),
+			tableCell(strings.Join(summary.Hosts, ",")),
+			tableCell(summary.IngressClassName),
+			strconv.FormatBool(summary.TLS),
+		}, "\t")+"\n")
+	}
+	if response.Truncated {
+		_, _ = io.WriteString(tw, "# truncated: MAX_PAGES reached\n")
+	}
+	for _, warning := range response.Warnings {
+		_, _ = io.WriteString(tw, "# warning: "+tableCell(warning)+"\n")
+	}
+	return tw.Flush()
+}
+
+// tableCell keeps one value on one line and in one column, showing empty
+// values as "-".
+func tableCell(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteSummaryTable(t *testing.T) {
+	app := testIngress("default", "app", "app.example.com", "www.example.com")
+	app["spec"].(map[string]interface{})["ingressClassName"] = "nginx"
+	app["spec"].(map[string]interface{})["tls"] = []interface{}{map[string]interface{}{"hosts": []interface{}{"app.example.com"}}}
+	response := summarizeIngresses(context.Background(), map[string]interface{}{
+		"items": []interface{}{app, testIngress("monitoring", "grafana")},
+	}, nil)
+
+	var b strings.Builder
+	if err := writeSummaryTable(&b, response); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "NAMESPACE   NAME     HOST                             CLASS  TLS\n" +
+		"default     app      app.example.com,www.example.com  nginx  true\n" +
+		"monitoring  grafana  -                                -      false\n"
+	if b.String() != want {
+		t.Fatalf("unexpected table:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestHandleIngressesTableFormat(t *testing.T) {
+	ingressesCache.reset()
+	withTestKubernetesAPI(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []interface{}{testIngress("default", "app", "app.example.com")},
+		})
+	}))
+
+	rr := httptest.NewRecorder()
+	handleIngresses(time.Second).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/ingresses?format=table", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Fatalf("expected text/plain, got %q", got)
+	}
+	if !strings.HasPrefix(rr.Body.String(), "NAMESPACE") || !strings.Contains(rr.Body.String(), "app.example.com") {
+		t.Fatalf("unexpected body %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+)
+
+const tagAnnotationPrefix = annotationPrefix + "tag."
+
+// tagFilter is one ?tag= query parameter. "team=payments" matches ingresses
+// whose team tag equals payments; a bare "team" matches any ingress that has
+// the tag at all.
+type tagFilter struct {
+	name     string
+	value    string
+	anyValue bool
+}
+
+// ingressTags collects home-pager.io/tag.<name> annotations into a map of tag
+// name to value.
+func ingressTags(item map[string]interface{}) map[string]string {
+	var tags map[string]string
+	for key, value := range ingressAnnotations(item) {
+		name, ok := strings.CutPrefix(key, tagAnnotationPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		raw, _ := value.(string)
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		tags[name] = strings.TrimSpace(raw)
+	}
+	return tags
+}
+
+// parseTagFilters parses repeated ?tag=name=value parameters. It reports false
+// when a parameter has no tag name.
+func parseTagFilters(raw []string) ([]tagFilter, bool) {
+	filters := make([]tagFilter, 0, len(raw))
+	for _, entry := range raw {
+		name, value, hasValue := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, false
+		}
+		filters = append(filters, tagFilter{name: name, value: strings.TrimSpace(value), anyValue: !hasValue})
+	}
+	return filters, true
+}
+
+// matchesTagFilters reports whether tags satisfy every filter.
+func matchesTagFilters(tags map[string]string, filters []tagFilter) bool {
+	for _, filter := range filters {
+		value, ok := tags[filter.name]
+		if !ok || (!filter.anyValue && value != filter.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterByTags returns a copy of result keeping only the items whose tags
+// satisfy every filter.
+func filterByTags(result map[string]interface{}, filters []tagFilter) map[string]interface{} {
+	if len(filters) == 0 {
+		return result
+	}
+
+	filtered := make(map[string]interface{}, len(result))
+	for key, value := range result {
+		filtered[key] = value
+	}
+
+	items, ok := result["items"].([]interface{})
+	if !ok {
+		return filtered
+	}
+
+	kept := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		itemMap, _ := item.(map[string]interface{})
+		if matchesTagFilters(ingressTags(itemMap), filters) {
+			kept = append(kept, item)
+		}
+	}
+	filtered["items"] = kept
+	return filtered
+}
+
+// filterSummariesByTags applies the same filters to summaries that do not come
+// from an ingress list, such as HomepageEntry tiles.
+func filterSummariesByTags(summaries []ingressSummary, filters []tagFilter) []ingressSummary {
+	if len(filters) == 0 {
+		return summaries
+	}
+
+	kept := make([]ingressSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		if matchesTagFilters(summary.Tags, filters) {
+			kept = append(kept, summary)
+		}
+	}
+	return kept
+}
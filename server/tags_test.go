@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+)
+
+func taggedIngress(name string, tags map[string]string) map[string]interface{} {
+	item := testIngress("default", name, name+".example.com")
+	annotations := make(map[string]interface{}, len(tags))
+	for key, value := range tags {
+		annotations[tagAnnotationPrefix+key] = value
+	}
+	item["metadata"].(map[string]interface{})["annotations"] = annotations
+	return item
+}
+
+func TestIngressTags(t *testing.T) {
+	item := taggedIngress("app", map[string]string{"team": " payments ", "tier": "gold"})
+	item["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})[tagAnnotationPrefix] = "ignored"
+
+	tags := ingressTags(item)
+	if len(tags) != 2 || tags["team"] != "payments" || tags["tier"] != "gold" {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+	if got := ingressTags(testIngress("default", "plain")); got != nil {
+		t.Fatalf("expected no tags, got %v", got)
+	}
+}
+
+func TestParseTagFilters(t *testing.T) {
+	filters, ok := parseTagFilters([]string{"team=payments", "tier", "env="})
+	if !ok || len(filters) != 3 {
+		t.Fatalf("expected three filters, got %v (%v)", filters, ok)
+	}
+	if filters[0] != (tagFilter{name: "team", value: "payments"}) {
+		t.Fatalf("unexpected first filter %+v", filters[0])
+	}
+	if !filters[1].anyValue || filters[2].anyValue {
+		t.Fatalf("unexpected anyValue flags %+v", filters)
+	}
+	if _, ok := parseTagFilters([]string{"=payments"}); ok {
+		t.Fatal("expected a filter without a name to be rejected")
+	}
+}
+
+func TestFilterByTags(t *testing.T) {
+	result := map[string]interface{}{
+		"resourceVersion": "1",
+		"items": []interface{}{
+			taggedIngress("pay", map[string]string{"team": "payments", "tier": "gold"}),
+			taggedIngress("web", map[string]string{"team": "web"}),
+			testIngress("default", "untagged", "untagged.example.com"),
+		},
+	}
+
+	cases := []struct {
+		raw  []string
+		want []string
+	}{
+		{[]string{"team=payments"}, []string{"pay"}},
+		{[]string{"team"}, []string{"pay", "web"}},
+		{[]string{"team", "tier=gold"}, []string{"pay"}},
+		{[]string{"team=ops"}, nil},
+	}
+	for _, tc := range cases {
+		filters, _ := parseTagFilters(tc.raw)
+		items := filterByTags(result, filters)["items"].([]interface{})
+		if len(items) != len(tc.want) {
+			t.Fatalf("%v: expected %v, got %d items", tc.raw, tc.want, len(items))
+		}
+		for i, item := range items {
+			if name := stringField(item.(map[string]interface{})["metadata"].(map[string]interface{}), "name"); name != tc.want[i] {
+				t.Fatalf("%v: expected %v, got %s at %d", tc.raw, tc.want, name, i)
+			}
+		}
+	}
+
+	if got := filterByTags(result, nil); len(got["items"].([]interface{})) != 3 {
+		t.Fatal("expected no filters to keep every item")
+	}
+}
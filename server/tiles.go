@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sourceTilesFile = "tilesFile"
+
+	defaultTilesPollInterval = 10 * time.Second
+)
+
+var (
+	// tilesFile is the TILES_FILE path; empty disables file-based tiles.
+	tilesFile string
+
+	tilesMu   sync.RWMutex
+	fileTiles []fileTile
+)
+
+// tileEntry is one tile in TILES_FILE. Show opts the tile in when
+// OPT_IN_ONLY is set, like the home-pager.io/show annotation on ingresses.
+type tileEntry struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Icon        string `json:"icon"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Show        bool   `json:"show"`
+}
+
+// fileTile is a loaded tile and whether it is opted in.
+type fileTile struct {
+	ingressSummary
+	show bool
+}
+
+// loadTiles reads a JSON array of tiles for services outside the cluster.
+// Every entry needs a title and an absolute http(s) URL, so a typo fails the
+// load rather than producing a broken tile. The category becomes the
+// "category" tag, which tag filters and the bookmarks export use.
+func loadTiles(path string) ([]fileTile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []tileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	tiles := make([]fileTile, 0, len(entries))
+	for i, entry := range entries {
+		title := strings.TrimSpace(entry.Title)
+		rawURL := strings.TrimSpace(entry.URL)
+		if title == "" {
+			return nil, fmt.Errorf("tile %d has no title", i)
+		}
+		if !isValidLinkURL(rawURL) {
+			return nil, fmt.Errorf("tile %q: url %q is not an absolute http(s) URL", title, rawURL)
+		}
+		parsed, _ := url.Parse(rawURL)
+
+		tile := ingressSummary{
+			Name:        title,
+			Title:       title,
+			Description: strings.TrimSpace(entry.Description),
+			Icon:        strings.TrimSpace(entry.Icon),
+			Hosts:       []string{parsed.Hostname()},
+			URL:         rawURL,
+			URLs:        []string{rawURL},
+			TLS:         parsed.Scheme == "https",
+			Visibility:  hostsVisibility([]string{parsed.Hostname()}),
+			Source:      sourceTilesFile,
+		}
+		if category := strings.TrimSpace(entry.Category); category != "" {
+			tile.Tags = map[string]string{bookmarkCategoryTag: category}
+		}
+		tiles = append(tiles, fileTile{ingressSummary: tile, show: entry.Show})
+	}
+	return tiles, nil
+}
+
+// reloadTiles replaces the file tiles from path. On failure the previous
+// tiles stay in place.
+func reloadTiles(path string) error {
+	tiles, err := loadTiles(path)
+	recordConfigReload(err)
+	if err != nil {
+		return err
+	}
+
+	tilesMu.Lock()
+	fileTiles = tiles
+	tilesMu.Unlock()
+	return nil
+}
+
+// currentFileTiles returns a copy of the file tiles that pass OPT_IN_ONLY
+// and HIDDEN_HOSTS, since summaries are modified in place when favorites and
+// health are marked.
+func currentFileTiles() []ingressSummary {
+	flags := currentFlags()
+	tilesMu.RLock()
+	defer tilesMu.RUnlock()
+
+	var tiles []ingressSummary
+	for _, tile := range fileTiles {
+		if flags.optInOnly && !tile.show {
+			continue
+		}
+		if hostsHidden(tile.Hosts, flags.hiddenHostPatterns) {
+			continue
+		}
+		tiles = append(tiles, tile.ingressSummary)
+	}
+	return tiles
+}
+
+// watchTilesFile reloads path whenever its modification time or size
+// changes, until ctx is cancelled. Polling follows symlinks, so it also sees
+// the atomic symlink swap Kubernetes uses to update mounted ConfigMaps.
+func watchTilesFile(ctx context.Context, path string, interval time.Duration) {
+	last := tilesFileVersion(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		version := tilesFileVersion(path)
+		if version == last {
+			continue
+		}
+		last = version
+		if err := reloadTiles(path); err != nil {
+			log.Printf("Error reloading TILES_FILE: %v; keeping previous tiles", err)
+			continue
+		}
+		log.Printf("Reloaded TILES_FILE")
+	}
+}
+
+// tilesFileVersion identifies the file's content cheaply, or is empty when
+// the file cannot be read.
+func tilesFileVersion(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "tiles.json", `[
+		{"title": "GitHub", "url": "https://github.com", "icon": "🐙", "category": "Dev"},
+		{"title": "Router", "url": "http://192.168.1.1", "description": "Admin UI"}
+	]`)
+
+	tiles, err := loadTiles(filepath.Join(dir, "tiles.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tiles) != 2 {
+		t.Fatalf("expected 2 tiles, got %+v", tiles)
+	}
+	github := tiles[0]
+	if github.Title != "GitHub" || github.URL != "https://github.com" || !github.TLS || github.Tags["category"] != "Dev" || github.Source != sourceTilesFile {
+		t.Fatalf("unexpected tile %+v", github)
+	}
+	if router := tiles[1]; router.Hosts[0] != "192.168.1.1" || router.Visibility != "internal" || router.Tags != nil {
+		t.Fatalf("unexpected tile %+v", router)
+	}
+
+	for name, content := range map[string]string{
+		"no-title.json": `[{"url": "https://example.com"}]`,
+		"bad-url.json":  `[{"title": "X", "url": "javascript:alert(1)"}]`,
+		"bad-json.json": `{"title": "X"}`,
+	} {
+		writeTestFile(t, dir, name, content)
+		if _, err := loadTiles(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestFileTilesFilters(t *testing.T) {
+	defer func() {
+		tilesMu.Lock()
+		fileTiles = nil
+		tilesMu.Unlock()
+	}()
+	dir := t.TempDir()
+	writeTestFile(t, dir, "tiles.json", `[
+		{"title": "Wiki", "url": "https://wiki.example.com", "show": true},
+		{"title": "NAS", "url": "https://nas.internal.local", "show": true},
+		{"title": "Blog", "url": "https://blog.example.com"}
+	]`)
+	if err := reloadTiles(filepath.Join(dir, "tiles.json")); err != nil {
+		t.Fatal(err)
+	}
+	if tiles := currentFileTiles(); len(tiles) != 3 {
+		t.Fatalf("expected every tile without filters, got %+v", tiles)
+	}
+
+	setFlags(t, func(f *featureFlags) {
+		f.optInOnly = true
+		f.hiddenHostPatterns = parseHostPatterns("*.internal.local")
+	})
+	if tiles := currentFileTiles(); len(tiles) != 1 || tiles[0].Title != "Wiki" {
+		t.Fatalf("expected only the opted-in, unhidden tile, got %+v", tiles)
+	}
+}
+
+func TestWatchTilesFileReloadsOnChange(t *testing.T) {
+	defer func() {
+		tilesMu.Lock()
+		fileTiles = nil
+		tilesMu.Unlock()
+	}()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tiles.json")
+	writeTestFile(t, dir, "tiles.json", `[{"title": "NAS", "url": "https://nas.example.com"}]`)
+	if err := reloadTiles(path); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchTilesFile(ctx, path, 10*time.Millisecond)
+
+	extra := fetchExtraSummaries(ctx)
+	if len(extra) != 1 || extra[0].Title != "NAS" {
+		t.Fatalf("expected the NAS tile among extra summaries, got %+v", extra)
+	}
+
+	// Keep the previous tiles when the file becomes invalid.
+	writeTestFile(t, dir, "tiles.json", `[{"title": "Broken"}]`)
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if tiles := currentFileTiles(); len(tiles) != 1 || tiles[0].Title != "NAS" {
+		t.Fatalf("expected an invalid file to keep the previous tiles, got %+v", tiles)
+	}
+
+	writeTestFile(t, dir, "tiles.json", `[{"title": "NAS", "url": "https://nas.example.com"}, {"title": "Wiki", "url": "https://wiki.example.com"}]`)
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(currentFileTiles()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the changed file to be reloaded, got %+v", currentFileTiles())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var titles []string
+	for _, tile := range currentFileTiles() {
+		titles = append(titles, tile.Title)
+	}
+	if got := strings.Join(titles, ","); got != "NAS,Wiki" {
+		t.Fatalf("unexpected tiles %s", got)
+	}
+}
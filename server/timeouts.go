@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 10 * time.Second
+	defaultWriteTimeout      = 15 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+)
+
+// serverTimeouts bounds how long a single connection may take to send its
+// request and receive its response, so slow or stalled clients cannot hold
+// connections open indefinitely.
+type serverTimeouts struct {
+	readHeader time.Duration
+	read       time.Duration
+	write      time.Duration
+	idle       time.Duration
+}
+
+func loadServerTimeouts() serverTimeouts {
+	return serverTimeouts{
+		readHeader: getEnvDuration("READ_HEADER_TIMEOUT", defaultReadHeaderTimeout),
+		read:       getEnvDuration("READ_TIMEOUT", defaultReadTimeout),
+		write:      getEnvDuration("WRITE_TIMEOUT", defaultWriteTimeout),
+		idle:       getEnvDuration("IDLE_TIMEOUT", defaultIdleTimeout),
+	}
+}
+
+// apply sets the per-connection deadlines on server. The read timeout covers
+// the headers and the whole body, so it is never shorter than the header
+// timeout.
+func (t serverTimeouts) apply(server *http.Server) {
+	server.ReadHeaderTimeout = t.readHeader
+	server.ReadTimeout = max(t.read, t.readHeader)
+	server.WriteTimeout = t.write
+	server.IdleTimeout = t.idle
+}
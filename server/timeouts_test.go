@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func startTimeoutTestServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(handler)
+	serverTimeouts{
+		readHeader: 100 * time.Millisecond,
+		read:       200 * time.Millisecond,
+		write:      time.Second,
+		idle:       time.Second,
+	}.apply(srv.Config)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv.Listener.Addr().String()
+}
+
+func TestServerTimeoutsCloseSlowHeaders(t *testing.T) {
+	addr := startTimeoutTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Start a request but never finish its headers.
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	start := time.Now()
+	if _, err := io.ReadAll(conn); err != nil {
+		t.Fatalf("expected the server to close the connection, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the connection to be closed after the header timeout, took %v", elapsed)
+	}
+}
+
+func TestServerTimeoutsAbortSlowBodies(t *testing.T) {
+	readErr := make(chan error, 1)
+	addr := startTimeoutTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		readErr <- err
+	}))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Declare a body and then trickle only part of it.
+	if _, err := io.WriteString(conn, "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 100\r\n\r\npartial"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Fatal("expected reading the incomplete body to fail")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the read timeout to abort the slow body")
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	// The release image is built FROM scratch and has no zoneinfo files.
+	_ "time/tzdata"
+)
+
+// displayLocation is the zone server-rendered pages show timestamps in. JSON
+// responses keep RFC 3339 timestamps, which carry their own offset.
+var displayLocation = time.Local
+
+// loadDisplayLocation resolves DISPLAY_TIMEZONE, an IANA zone name such as
+// Europe/London. An empty name keeps the process's local zone.
+func loadDisplayLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
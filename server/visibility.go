@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+)
+
+const (
+	visibilityAnnotation = annotationPrefix + "visibility"
+
+	visibilityPublic   = "public"
+	visibilityInternal = "internal"
+)
+
+// internalHostSuffixes are domains that only resolve on a private network.
+var internalHostSuffixes = []string{".local", ".lan", ".internal", ".home.arpa", ".localdomain"}
+
+// parseVisibility normalizes an annotation value, reporting false for
+// anything other than public or internal.
+func parseVisibility(raw string) (string, bool) {
+	switch value := strings.ToLower(strings.TrimSpace(raw)); value {
+	case visibilityPublic, visibilityInternal:
+		return value, true
+	case "external":
+		return visibilityPublic, true
+	default:
+		return "", false
+	}
+}
+
+// ingressVisibility reports whether an ingress is reachable from the
+// internet. The home-pager.io/visibility annotation wins; otherwise the
+// ingress class decides when it is listed in INTERNAL_INGRESS_CLASSES or
+// PUBLIC_INGRESS_CLASSES, and failing that the hosts do.
+func ingressVisibility(item map[string]interface{}) string {
+	if visibility, ok := parseVisibility(annotationValue(item, visibilityAnnotation)); ok {
+		return visibility
+	}
+	flags := currentFlags()
+	class := ingressClassName(item)
+	switch {
+	case flags.internalIngressClasses[class]:
+		return visibilityInternal
+	case flags.publicIngressClasses[class]:
+		return visibilityPublic
+	}
+	return hostsVisibility(ingressHosts(item))
+}
+
+// hostsVisibility guesses visibility from hostnames: internal when every host
+// is on a private-only domain or is a private IP address.
+func hostsVisibility(hosts []string) string {
+	if len(hosts) == 0 {
+		return visibilityInternal
+	}
+	for _, host := range hosts {
+		if !isInternalHost(host) {
+			return visibilityPublic
+		}
+	}
+	return visibilityInternal
+}
+
+func isInternalHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast()
+	}
+	if !strings.Contains(host, ".") || host == "localhost" {
+		return true
+	}
+	for _, suffix := range internalHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestIngressVisibility(t *testing.T) {
+	setFlags(t, func(f *featureFlags) {
+		f.internalIngressClasses = parseNameSet("nginx-internal")
+		f.publicIngressClasses = parseNameSet("nginx-public")
+	})
+
+	annotated := func(host, visibility string) map[string]interface{} {
+		item := testIngress("default", "app", host)
+		item["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{visibilityAnnotation: visibility}
+		return item
+	}
+
+	cases := []struct {
+		name string
+		item map[string]interface{}
+		want string
+	}{
+		{"annotation wins over host", annotated("nas.home.arpa", "Public"), visibilityPublic},
+		{"annotation internal", annotated("app.example.com", "internal"), visibilityInternal},
+		{"invalid annotation falls back", annotated("app.example.com", "sometimes"), visibilityPublic},
+		{"internal class", classedIngress("default", "app", "nginx-internal"), visibilityInternal},
+		{"public class", classedIngress("default", "router", "nginx-public"), visibilityPublic},
+		{"private domain", testIngress("default", "nas", "nas.lan", "nas.local"), visibilityInternal},
+		{"private address", testIngress("default", "nas", "192.168.1.10"), visibilityInternal},
+		{"mixed hosts", testIngress("default", "app", "app.lan", "app.example.com"), visibilityPublic},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ingressVisibility(tc.item); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	if got := summarizeIngress(testIngress("default", "nas", "nas.lan")).Visibility; got != visibilityInternal {
+		t.Fatalf("expected the summary to carry visibility, got %q", got)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultWorkerPoolSize = 4
+
+// workerPool bounds how many background tasks (cache prewarming and
+// revalidation, health check probes, StatsD flushes) run at once so a small node is not swamped.
+// Fetches a request is waiting on do not use it.
+type workerPool struct {
+	slots     chan struct{}
+	busy      atomic.Int64
+	waiting   atomic.Int64
+	saturated atomic.Uint64
+	completed atomic.Uint64
+}
+
+// backgroundPool is the pool every background task submits to; main sizes it
+// from WORKER_POOL_SIZE.
+var backgroundPool = newWorkerPool(defaultWorkerPoolSize)
+
+func newWorkerPool(size int) *workerPool {
+	if size <= 0 {
+		size = defaultWorkerPoolSize
+	}
+	return &workerPool{slots: make(chan struct{}, size)}
+}
+
+func (p *workerPool) size() int {
+	return cap(p.slots)
+}
+
+// do runs fn once a worker slot is free, or returns the context error if ctx
+// is done first.
+func (p *workerPool) do(ctx context.Context, fn func()) error {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		p.saturated.Add(1)
+		p.waiting.Add(1)
+		select {
+		case p.slots <- struct{}{}:
+			p.waiting.Add(-1)
+		case <-ctx.Done():
+			p.waiting.Add(-1)
+			return ctx.Err()
+		}
+	}
+
+	p.busy.Add(1)
+	defer func() {
+		p.busy.Add(-1)
+		p.completed.Add(1)
+		<-p.slots
+	}()
+	fn()
+	return nil
+}
+
+func (p *workerPool) write(w io.Writer) {
+	_, _ = io.WriteString(w, "# HELP home_pager_worker_pool_size Maximum concurrent background tasks.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_worker_pool_size gauge\n")
+	_, _ = io.WriteString(w, "home_pager_worker_pool_size "+strconv.Itoa(p.size())+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_worker_pool_busy Background tasks currently running.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_worker_pool_busy gauge\n")
+	_, _ = io.WriteString(w, "home_pager_worker_pool_busy "+strconv.FormatInt(p.busy.Load(), 10)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_worker_pool_waiting Background tasks waiting for a free worker.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_worker_pool_waiting gauge\n")
+	_, _ = io.WriteString(w, "home_pager_worker_pool_waiting "+strconv.FormatInt(p.waiting.Load(), 10)+"\n")
+	_, _ = io.WriteString(w, "# HELP home_pager_worker_pool_saturated_total Tasks that found every worker busy.\n")
+	_, _ = io.WriteString(w, "# TYPE home_pager_worker_pool_saturated_total counter\n")
+	_, _ = io.WriteString(w, "home_pager_worker_pool_saturated_total "+strconv.FormatUint(p.saturated.Load(), 10)+"\n")
+}
+
+const defaultWorkerShutdownTimeout = 5 * time.Second
+
+// workerGroup tracks long-running background goroutines by name so shutdown
+// can wait for them to exit, and name the ones that do not.
+type workerGroup struct {
+	mu      sync.Mutex
+	running map[string]int
+	done    chan struct{}
+}
+
+var backgroundWorkers = &workerGroup{}
+
+// start runs fn in a new goroutine, tracked under name until it returns.
+func (g *workerGroup) start(name string, fn func()) {
+	g.mu.Lock()
+	if g.running == nil {
+		g.running = make(map[string]int)
+	}
+	g.running[name]++
+	g.mu.Unlock()
+
+	go func() {
+		defer g.finish(name)
+		fn()
+	}()
+}
+
+func (g *workerGroup) finish(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running[name]--
+	if g.running[name] == 0 {
+		delete(g.running, name)
+	}
+	if len(g.running) == 0 && g.done != nil {
+		close(g.done)
+		g.done = nil
+	}
+}
+
+// wait blocks until every worker has returned or timeout elapses, returning
+// the sorted names of workers still running. Callers cancel the workers'
+// context first; wait only bounds how long shutdown lingers on them.
+func (g *workerGroup) wait(timeout time.Duration) []string {
+	g.mu.Lock()
+	if len(g.running) == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	if g.done == nil {
+		g.done = make(chan struct{})
+	}
+	done := g.done
+	g.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	lingering := make([]string, 0, len(g.running))
+	for name := range g.running {
+		lingering = append(lingering, name)
+	}
+	sort.Strings(lingering)
+	return lingering
+}
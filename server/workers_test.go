@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolBoundsConcurrency(t *testing.T) {
+	pool := newWorkerPool(2)
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = pool.do(context.Background(), func() {
+				started <- struct{}{}
+				<-release
+			})
+		}()
+	}
+
+	<-started
+	<-started
+	deadline := time.Now().Add(time.Second)
+	for pool.waiting.Load() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := pool.busy.Load(); got != 2 {
+		t.Fatalf("expected 2 busy workers, got %d", got)
+	}
+	if got := pool.waiting.Load(); got != 1 {
+		t.Fatalf("expected 1 waiting task, got %d", got)
+	}
+
+	close(release)
+	wg.Wait()
+	if got := pool.saturated.Load(); got != 1 {
+		t.Fatalf("expected 1 saturation, got %d", got)
+	}
+
+	var b strings.Builder
+	pool.write(&b)
+	for _, want := range []string{"home_pager_worker_pool_size 2", "home_pager_worker_pool_busy 0", "home_pager_worker_pool_saturated_total 1"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected %q in %q", want, b.String())
+		}
+	}
+}
+
+func TestWorkerPoolHonorsContext(t *testing.T) {
+	pool := newWorkerPool(1)
+	release := make(chan struct{})
+	go func() {
+		_ = pool.do(context.Background(), func() { <-release })
+	}()
+	defer close(release)
+	for pool.busy.Load() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ran := false
+	err := pool.do(ctx, func() { ran = true })
+	if !errors.Is(err, context.DeadlineExceeded) || ran {
+		t.Fatalf("expected deadline error without running, got %v (ran=%v)", err, ran)
+	}
+}
+
+func TestWorkerGroupWaitNamesLingeringWorkers(t *testing.T) {
+	group := &workerGroup{}
+	if lingering := group.wait(time.Millisecond); lingering != nil {
+		t.Fatalf("expected no workers, got %v", lingering)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	group.start("watch", func() { <-ctx.Done() })
+	group.start("stuck", func() { <-release })
+	cancel()
+
+	start := time.Now()
+	lingering := group.wait(50 * time.Millisecond)
+	if len(lingering) != 1 || lingering[0] != "stuck" {
+		t.Fatalf("expected only the stuck worker to linger, got %v", lingering)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected wait to give up after its timeout, took %v", elapsed)
+	}
+
+	group = &workerGroup{}
+	group.start("quick", func() {})
+	if lingering := group.wait(time.Second); lingering != nil {
+		t.Fatalf("expected every worker to stop, got %v", lingering)
+	}
+}